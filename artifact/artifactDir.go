@@ -1,46 +1,29 @@
 package artifact
 
 import (
-	"Builder/spinner"
+	"Builder/utils"
 	"fmt"
 	"os"
 	"strconv"
-	"time"
 )
 
-func ArtifactDir() {
-	var dirPath string
-	// if os.Getenv("BUILDER_COMMAND") == "true" {
-	// 	path, _ := os.Getwd()
-	// 	if strings.Contains(path, "workspace") {
-	// 		dirPath = strings.TrimRight(path, "\\workspace")
-	// 	} else if strings.Contains(path, "workspace") && strings.Contains(path, "temp") {
-	// 		dirPath = strings.TrimRight(path, "\\temp")
-	// 	}
-	// } else {
+// ArtifactDir creates the timestamped artifact dir inside the parent dir
+func ArtifactDir(bc *utils.BuildContext) error {
+	dirPath := bc.ParentDir
+	dirName := utils.GetName(bc)
 
-	dirPath = os.Getenv("BUILDER_PARENT_DIR")
-	dirName := os.Getenv("BUILDER_DIR_NAME")
-	// }
-	startTime := os.Getenv("BUILD_START_TIME")
-
-	parsedStartTime, _ := time.Parse(time.RFC850, startTime)
-	timeBuildStarted := parsedStartTime.Unix()
+	timeBuildStarted := bc.StartTime.Unix()
 	artifactStamp := dirName + "_artifact_" + strconv.FormatInt(timeBuildStarted, 10)
-	os.Setenv("BUILDER_ARTIFACT_STAMP", artifactStamp)
+	bc.ArtifactStamp = artifactStamp
 	artifactDir := dirPath + "/" + artifactStamp
 
 	err := os.Mkdir(artifactDir, 0755)
 	//should return nil once directory is made, if not, throw err
 	if err != nil {
-		spinner.LogMessage("failed to make artifact directory", "fatal")
+		return fmt.Errorf("failed to make artifact directory: %w", err)
 	}
 
-	//check workspace env exists, if not, create it
-	val, present := os.LookupEnv("BUILDER_ARTIFACT_DIR")
-	if !present {
-		os.Setenv("BUILDER_ARTIFACT_DIR", artifactDir)
-	} else {
-		fmt.Println("BUILDER_ARTIFACT_DIR", val)
-	}
+	bc.ArtifactDir = artifactDir
+
+	return nil
 }
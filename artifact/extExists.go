@@ -1,7 +1,6 @@
 package artifact
 
 import (
-	"Builder/spinner"
 	"Builder/utils"
 	"fmt"
 	"os"
@@ -10,21 +9,17 @@ import (
 )
 
 // find file with extension and return file name
-func ExtExistsFunction(dirPath string, ext string) (bool, string) {
+func ExtExistsFunction(bc *utils.BuildContext, dirPath string, ext string) (bool, string, error) {
 	found := false
 	d, err := os.Open(dirPath)
 	if err != nil {
-		fmt.Println(err)
-		spinner.LogMessage("could not find dirpath "+dirPath+": "+err.Error(), "fatal")
-		os.Exit(1)
+		return false, "", fmt.Errorf("could not find dirpath %s: %w", dirPath, err)
 	}
 	defer d.Close()
 
 	files, err := d.Readdir(-1)
 	if err != nil {
-		fmt.Println(err)
-		spinner.LogMessage("could not read directory: "+err.Error(), "fatal")
-		os.Exit(1)
+		return false, "", fmt.Errorf("could not read directory: %w", err)
 	}
 	var fileName string
 
@@ -36,7 +31,7 @@ func ExtExistsFunction(dirPath string, ext string) (bool, string) {
 					found = true
 				}
 			} else {
-				if file.Mode()&0111 != 0 && file.Name() == strings.TrimSuffix(utils.GetName(), ".git") {
+				if file.Mode()&0111 != 0 && file.Name() == strings.TrimSuffix(utils.GetName(bc), ".git") {
 					fileName = file.Name()
 					found = true
 				}
@@ -44,5 +39,5 @@ func ExtExistsFunction(dirPath string, ext string) (bool, string) {
 
 		}
 	}
-	return found, fileName
+	return found, fileName, nil
 }
@@ -1,30 +1,29 @@
 package artifact
 
 import (
+	"Builder/utils"
 	"fmt"
 	"os"
 	"strconv"
 	"strings"
-	"time"
 )
 
 // rename artifact with Unix timestamp
-func NameArtifact(fullPath string, extName string) string {
+func NameArtifact(bc *utils.BuildContext, fullPath string, extName string) (string, error) {
 	//seperate extName by last ".", return that ext (jar, exe, etc)
 	newExtName := extName[strings.LastIndex(extName, ".")+1:]
 
 	//trim off ".jar", ".exe", etc to add timestamp
 	res := strings.Split(extName, "."+newExtName)
-	parsedStartTime, _ := time.Parse(time.RFC850, os.Getenv("BUILD_START_TIME"))
-	timeBuildStarted := parsedStartTime.Unix()
+	timeBuildStarted := bc.StartTime.Unix()
 
 	//join it all back together
 	artifactName := res[0] + "_" + strconv.FormatInt(timeBuildStarted, 10) + "." + newExtName
 
 	err := os.Rename(fullPath+extName, fullPath+artifactName)
 	if err != nil {
-		fmt.Println(err)
+		return "", fmt.Errorf("could not rename artifact %s: %w", extName, err)
 	}
 
-	return artifactName
+	return artifactName, nil
 }
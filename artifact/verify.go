@@ -0,0 +1,237 @@
+package artifact
+
+import (
+	"Builder/utils"
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Verify results
+const (
+	VerifyMatch    = "match"
+	VerifyMismatch = "mismatch"
+	VerifyMissing  = "missing"
+)
+
+// VerifyResult is the outcome of checking one recorded artifact file
+type VerifyResult struct {
+	Path     string                  `json:"path"`
+	Result   string                  `json:"result"`
+	Expected utils.ArtifactChecksum  `json:"expected"`
+	Actual   *utils.ArtifactChecksum `json:"actual,omitempty"`
+}
+
+// ErrNoChecksums is returned for metadata written before checksums were recorded as a list
+var ErrNoChecksums = errors.New("metadata has no artifact checksum list, it was created by an older Builder (schema version < 2)")
+
+// ParseChecksums returns the ArtifactChecksums recorded in a metadata.json
+func ParseChecksums(metadataJSON []byte) ([]utils.ArtifactChecksum, error) {
+	var metadata map[string]json.RawMessage
+	if err := json.Unmarshal(metadataJSON, &metadata); err != nil {
+		return nil, fmt.Errorf("could not parse metadata: %w", err)
+	}
+
+	var checksums []utils.ArtifactChecksum
+	if err := json.Unmarshal(metadata["ArtifactChecksums"], &checksums); err != nil {
+		return nil, ErrNoChecksums
+	}
+
+	return checksums, nil
+}
+
+// Verify recomputes the checksums of the artifacts at location, an artifact dir or a
+// compressed artifact dir, and compares them to expected.  Artifacts inside any .tar.gz or
+// .zip in an artifact dir are checked too.  If expected is nil the checksums are read from
+// the metadata.json at location.
+func Verify(location string, expected []utils.ArtifactChecksum) ([]VerifyResult, error) {
+	info, err := os.Stat(location)
+	if err != nil {
+		return nil, fmt.Errorf("could not find %s: %w", location, err)
+	}
+
+	actual := map[string]utils.ArtifactChecksum{}
+	var metadataJSON []byte
+
+	if info.IsDir() {
+		metadataJSON, err = dirChecksums(location, actual)
+	} else {
+		metadataJSON, err = archiveChecksums(location, actual)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if expected == nil {
+		if metadataJSON == nil {
+			return nil, fmt.Errorf("could not find metadata.json in %s", location)
+		}
+
+		expected, err = ParseChecksums(metadataJSON)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var results []VerifyResult
+	for _, want := range expected {
+		result := VerifyResult{Path: want.Path, Expected: want}
+
+		got, found := actual[want.Path]
+		switch {
+		case !found:
+			result.Result = VerifyMissing
+		case got.Size == want.Size && got.SHA256 == want.SHA256 && (want.SHA512 == "" || got.SHA512 == want.SHA512):
+			result.Result = VerifyMatch
+			result.Actual = &got
+		default:
+			result.Result = VerifyMismatch
+			result.Actual = &got
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// dirChecksums adds the checksums of the files in dir, and of the files inside the archives
+// in dir, to checksums.  It returns the contents of dir's metadata.json if there is one.
+func dirChecksums(dir string, checksums map[string]utils.ArtifactChecksum) ([]byte, error) {
+	var metadataJSON []byte
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+
+		if relPath == "metadata.json" {
+			metadataJSON, err = os.ReadFile(path)
+			return err
+		}
+
+		checksum, err := utils.FileChecksum(path)
+		if err != nil {
+			return err
+		}
+		checksum.Path = relPath
+		checksums[relPath] = checksum
+
+		if isArchive(path) {
+			if _, err := archiveChecksums(path, checksums); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not read artifacts in %s: %w", dir, err)
+	}
+
+	return metadataJSON, nil
+}
+
+// archiveChecksums adds the checksums of the files inside a .tar.gz or .zip to checksums,
+// without overwriting files found outside the archive.  It returns the contents of the
+// archive's metadata.json if there is one.
+func archiveChecksums(path string, checksums map[string]utils.ArtifactChecksum) ([]byte, error) {
+	var metadataJSON []byte
+
+	addEntry := func(name string, r io.Reader) error {
+		name = strings.TrimPrefix(filepath.ToSlash(name), "./")
+		if name == "metadata.json" {
+			var err error
+			metadataJSON, err = io.ReadAll(r)
+			return err
+		}
+
+		if _, found := checksums[name]; found {
+			return nil
+		}
+
+		checksum, err := utils.ReaderChecksum(name, r)
+		if err != nil {
+			return err
+		}
+		checksums[name] = checksum
+
+		return nil
+	}
+
+	switch {
+	case strings.HasSuffix(path, ".zip"):
+		zipReader, err := zip.OpenReader(path)
+		if err != nil {
+			return nil, fmt.Errorf("could not open %s: %w", path, err)
+		}
+		defer zipReader.Close()
+
+		for _, file := range zipReader.File {
+			if file.FileInfo().IsDir() {
+				continue
+			}
+
+			entry, err := file.Open()
+			if err != nil {
+				return nil, fmt.Errorf("could not read %s in %s: %w", file.Name, path, err)
+			}
+			err = addEntry(file.Name, entry)
+			entry.Close()
+			if err != nil {
+				return nil, err
+			}
+		}
+	case strings.HasSuffix(path, ".tar.gz") || strings.HasSuffix(path, ".tgz"):
+		archiveFile, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("could not open %s: %w", path, err)
+		}
+		defer archiveFile.Close()
+
+		gzipReader, err := gzip.NewReader(archiveFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read %s: %w", path, err)
+		}
+		defer gzipReader.Close()
+
+		tarReader := tar.NewReader(gzipReader)
+		for {
+			header, err := tarReader.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("could not read %s: %w", path, err)
+			}
+			if header.Typeflag != tar.TypeReg {
+				continue
+			}
+
+			if err := addEntry(header.Name, tarReader); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("%s is not an artifact dir, .tar.gz or .zip", path)
+	}
+
+	return metadataJSON, nil
+}
+
+func isArchive(path string) bool {
+	return strings.HasSuffix(path, ".zip") || strings.HasSuffix(path, ".tar.gz") || strings.HasSuffix(path, ".tgz")
+}
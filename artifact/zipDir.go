@@ -1,7 +1,7 @@
 package artifact
 
 import (
-	"Builder/spinner"
+	"Builder/utils"
 	"archive/tar"
 	"archive/zip"
 	"compress/gzip"
@@ -11,10 +11,9 @@ import (
 	"runtime"
 )
 
-// Npm creates zip from files passed in as arg
-func ZipArtifactDir() {
-	// parentDir := os.Getenv("BUILDER_PARENT_DIR")
-	artifactDir := os.Getenv("BUILDER_ARTIFACT_DIR")
+// ZipArtifactDir creates a zip (windows) or tar.gz of the artifact dir next to it
+func ZipArtifactDir(bc *utils.BuildContext) error {
+	artifactDir := bc.ArtifactDir
 
 	if runtime.GOOS == "windows" {
 		artifactZip := artifactDir + ".zip"
@@ -22,7 +21,7 @@ func ZipArtifactDir() {
 		// CreateZip temp dir.
 		outFile, err := os.Create(artifactZip)
 		if err != nil {
-			spinner.LogMessage("failed to create artifact directory: "+err.Error(), "fatal")
+			return fmt.Errorf("failed to create artifact zip: %w", err)
 		}
 
 		defer outFile.Close()
@@ -31,11 +30,13 @@ func ZipArtifactDir() {
 		w := zip.NewWriter(outFile)
 
 		// Add files from artifact dir to the artifact zip.
-		addFilesZip(w, artifactDir+"/", "")
+		if err := addFilesZip(w, artifactDir+"/", ""); err != nil {
+			return err
+		}
 
 		err = w.Close()
 		if err != nil {
-			spinner.LogMessage("failed to create artifact directory: "+err.Error(), "fatal")
+			return fmt.Errorf("failed to create artifact zip: %w", err)
 		}
 	} else {
 
@@ -43,7 +44,7 @@ func ZipArtifactDir() {
 
 		outFile, err := os.Create(artifactTar)
 		if err != nil {
-			spinner.LogMessage("failed to create artifact directory: "+err.Error(), "fatal")
+			return fmt.Errorf("failed to create artifact tar: %w", err)
 		}
 
 		defer outFile.Close()
@@ -54,65 +55,72 @@ func ZipArtifactDir() {
 		defer tw.Close()
 
 		// Add files from artifact dir to the artifact tar.gz.
-		addFilesTar(tw, artifactDir+"/", "")
+		if err := addFilesTar(tw, artifactDir+"/", ""); err != nil {
+			return err
+		}
 
 		err = tw.Close()
 		if err != nil {
-			spinner.LogMessage("failed to create artifact: "+err.Error(), "fatal")
+			return fmt.Errorf("failed to create artifact tar: %w", err)
 		}
 	}
 
+	return nil
 }
 
 // recursively add files
-func addFilesZip(w *zip.Writer, basePath, baseInZip string) {
+func addFilesZip(w *zip.Writer, basePath, baseInZip string) error {
 	// Open the Directory
 	files, err := ioutil.ReadDir(basePath)
 	if err != nil {
-		spinner.LogMessage("failed to read zip directory: "+err.Error(), "fatal")
+		return fmt.Errorf("failed to read zip directory: %w", err)
 	}
 
 	for _, file := range files {
 		if !file.IsDir() {
 			dat, err := ioutil.ReadFile(basePath + file.Name())
 			if err != nil {
-				fmt.Println(err)
+				return fmt.Errorf("failed to read %s: %w", file.Name(), err)
 			}
 
 			// Add some files to the archive.
 			f, err := w.Create(baseInZip + file.Name())
 			if err != nil {
-				spinner.LogMessage("failed to create zip: "+err.Error(), "error")
+				return fmt.Errorf("failed to create zip: %w", err)
 			}
 			_, err = f.Write(dat)
 			if err != nil {
-				spinner.LogMessage("failed to add files to zip: "+err.Error(), "error")
+				return fmt.Errorf("failed to add files to zip: %w", err)
 			}
 		} else if file.IsDir() {
 			// Recurse
 			newBase := basePath + file.Name() + "/"
-			addFilesZip(w, newBase, baseInZip+file.Name()+"/")
+			if err := addFilesZip(w, newBase, baseInZip+file.Name()+"/"); err != nil {
+				return err
+			}
 		}
 	}
+
+	return nil
 }
 
-func addFilesTar(w *tar.Writer, basePath, baseInZip string) {
+func addFilesTar(w *tar.Writer, basePath, baseInZip string) error {
 	// Open the Directory
 	files, err := ioutil.ReadDir(basePath)
 	if err != nil {
-		fmt.Println(err)
+		return fmt.Errorf("failed to read tar directory: %w", err)
 	}
 
 	for _, file := range files {
 		if !file.IsDir() {
 			dat, err := ioutil.ReadFile(basePath + file.Name())
 			if err != nil {
-				fmt.Println(err)
+				return fmt.Errorf("failed to read %s: %w", file.Name(), err)
 			}
 
 			header, err := tar.FileInfoHeader(file, file.Name())
 			if err != nil {
-				fmt.Println(err)
+				return fmt.Errorf("failed to create tar header: %w", err)
 			}
 
 			header.Name = baseInZip + file.Name()
@@ -120,16 +128,20 @@ func addFilesTar(w *tar.Writer, basePath, baseInZip string) {
 			// Add some files to the archive.
 			err = w.WriteHeader(header)
 			if err != nil {
-				fmt.Println(err)
+				return fmt.Errorf("failed to write tar header: %w", err)
 			}
 			_, err = w.Write(dat)
 			if err != nil {
-				fmt.Println(err)
+				return fmt.Errorf("failed to add files to tar: %w", err)
 			}
 		} else if file.IsDir() {
 			// Recurse
 			newBase := basePath + file.Name() + "/"
-			addFilesTar(w, newBase, baseInZip+file.Name()+"/")
+			if err := addFilesTar(w, newBase, baseInZip+file.Name()+"/"); err != nil {
+				return err
+			}
 		}
 	}
+
+	return nil
 }
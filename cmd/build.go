@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"Builder/directory"
+	"Builder/spinner"
+	"Builder/utils"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// finishing is locked by whichever of the build and the signal handler ends the build first,
+// it is never unlocked since ending the build exits Builder
+var finishing sync.Mutex
+
+// cancelWait is how long a cancelled build gets to stop and clean up before Builder exits
+// without it
+const cancelWait = 10 * time.Second
+
+// startBuild opens the build's record in the build history and makes sure the build gets
+// cancelled if Builder is interrupted
+func startBuild(bc *utils.BuildContext) {
+	exitOnError(bc, utils.OpenBuildRecord(bc))
+
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		<-signals
+
+		// a second interrupt exits without waiting for the clean up
+		go func() {
+			<-signals
+			os.Exit(130)
+		}()
+
+		// kill the running build command, the build itself stops when it sees the error and
+		// exitOnError cleans it up.  bc belongs to the build, it isn't touched from here on.
+		bc.Cancel()
+
+		// a build that is ending by now is left to exit on its own
+		time.Sleep(cancelWait)
+		finishing.Lock()
+		spinner.Spinner.Stop()
+		fmt.Fprintln(os.Stderr, "Build did not stop after being cancelled, exiting without cleaning up 🛑")
+		os.Exit(130)
+	}()
+}
+
+// cancelBuild ends a cancelled build once the build has stopped.  It stops the spinner, removes the half done build
+// dirs, records the cancelled build in the build history and exits.
+func cancelBuild(bc *utils.BuildContext) {
+	spinner.Spinner.Stop()
+	bc.CloseLogger()
+
+	if err := directory.CleanupCancelledBuild(bc); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not clean up cancelled build: "+err.Error())
+	}
+
+	if recordErr := utils.FinishBuildRecord(bc, utils.StatusCancelled, utils.ErrCancelled); recordErr != nil {
+		fmt.Fprintln(os.Stderr, "Could not record cancelled build: "+recordErr.Error())
+	}
+
+	fmt.Fprintln(os.Stderr, "Build Cancelled 🛑")
+	if bc.LogsDir != "" {
+		fmt.Fprintln(os.Stderr, "Build logs are in "+bc.LogsDir)
+	}
+
+	os.Exit(130)
+}
+
+// finishBuild marks the build as succeeded in the build history
+func finishBuild(bc *utils.BuildContext) {
+	finishing.Lock()
+	if bc.Cancelled() {
+		cancelBuild(bc)
+	}
+
+	bc.Step = "record"
+	if err := utils.FinishBuildRecord(bc, utils.StatusSucceeded, nil); err != nil {
+		failBuild(bc, err)
+	}
+}
+
+// exitOnError ends a failed build.  It stops the spinner, records the failed build in
+// the build history, tells the user what went wrong and exits with the failing command's exit
+// code.  bc is nil if the build failed before its context could be created.
+func exitOnError(bc *utils.BuildContext, err error) {
+	if err == nil {
+		return
+	}
+
+	finishing.Lock()
+	if bc != nil && bc.Cancelled() {
+		cancelBuild(bc)
+	}
+
+	failBuild(bc, err)
+}
+
+// failBuild does the work of exitOnError once the build is being ended.  The parent dir of a
+// build that failed before it was renamed gets renamed, so the logs stay with the failed build.
+func failBuild(bc *utils.BuildContext, err error) {
+	spinner.Spinner.Stop()
+
+	if bc != nil {
+		bc.CloseLogger()
+
+		if cleanupErr := directory.CleanupFailedBuild(bc); cleanupErr != nil {
+			fmt.Fprintln(os.Stderr, "Could not clean up failed build: "+cleanupErr.Error())
+		}
+
+		if recordErr := utils.FinishBuildRecord(bc, utils.StatusFailed, err); recordErr != nil {
+			fmt.Fprintln(os.Stderr, "Could not record failed build: "+recordErr.Error())
+		}
+	}
+
+	message := err.Error()
+	if bc != nil {
+		message = bc.Redact(message)
+	}
+	fmt.Fprintln(os.Stderr, "Build Failed 💥: "+message)
+	if bc != nil && bc.LogsDir != "" {
+		fmt.Fprintln(os.Stderr, "Build logs are in "+bc.LogsDir)
+	}
+
+	os.Exit(utils.ExitCode(err))
+}
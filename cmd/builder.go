@@ -10,46 +10,79 @@ import (
 )
 
 func Builder() {
-	os.Setenv("BUILDER_COMMAND", "true")
+	bc, err := utils.NewBuildContext("builder", os.Args[1:])
+	exitOnError(bc, err)
+
 	path, _ := os.Getwd()
 
 	//checks if yaml file exists in path
-	if _, err := os.Stat(path + "/" + "builder.yaml"); err == nil {
-		// Start loading spinner
-		spinner.Spinner.Start()
+	if _, err := os.Stat(path + "/" + "builder.yaml"); err != nil {
+		utils.PrintHelp()
+	}
 
-		//parse builder.yaml
-		yaml.YamlParser(path + "/" + "builder.yaml")
+	dryRun(bc)
+
+	startBuild(bc)
+	exitOnError(bc, builderBuild(bc, path))
+	finishBuild(bc)
+}
 
-		// Create directories
-		directory.MakeDirs()
-		spinner.LogMessage("Directories successfully created.", "info")
+func builderBuild(bc *utils.BuildContext, path string) error {
+	// Start loading spinner
+	spinner.Spinner.Start()
 
-		// clone files from current dir into hidden
-		currentDir, _ := os.Getwd()
-		hiddenDir := os.Getenv("BUILDER_HIDDEN_DIR")
-		utils.CloneRepoFiles(currentDir, hiddenDir)
-		spinner.LogMessage("Files copied to hidden dir successfully.", "info")
+	//parse builder.yaml
+	bc.Step = "config"
+	if err := yaml.YamlParser(path+"/"+"builder.yaml", &bc.Config); err != nil {
+		return err
+	}
+
+	// Create directories
+	bc.Step = "directories"
+	if err := directory.MakeDirs(bc); err != nil {
+		return err
+	}
+	spinner.LogMessage("Directories successfully created.", "info")
 
-		//creates a new artifact
-		derive.ProjectType()
+	// clone files from current dir into hidden
+	bc.Step = "copy"
+	if err := utils.CloneRepoFiles(bc, path, bc.HiddenDir); err != nil {
+		return err
+	}
+	spinner.LogMessage("Files copied to hidden dir successfully.", "info")
 
-		//Get build metadata (deprecated, func moved inside compiler)
-		spinner.LogMessage("Metadata created successfully.", "info")
+	// read the secrets so they're redacted from the build log and ${...} can use them
+	bc.Step = "secrets"
+	if err := utils.LoadSecrets(bc); err != nil {
+		return err
+	}
 
-		// Store build metadata to hidden builder dir
-		utils.StoreBuildMetadataLocally()
+	// fill in the ${...} variables of the builder.yaml
+	bc.Step = "config"
+	if err := utils.InterpolateConfig(bc, path); err != nil {
+		return err
+	}
 
-		//Check for Dockerfile, then build image
-		utils.Docker()
+	//creates a new artifact
+	if err := derive.ProjectType(bc); err != nil {
+		return err
+	}
 
-		//makes hidden dir read-only
-		utils.MakeHidden()
-		spinner.LogMessage("Hidden Dir is now read-only.", "info")
+	//Get build metadata (deprecated, func moved inside compiler)
+	spinner.LogMessage("Metadata created successfully.", "info")
 
-		// Stop loading spinner
-		spinner.Spinner.Stop()
-	} else {
-		utils.Help()
+	//Check for Dockerfile, then build image
+	bc.Step = "docker"
+	if err := utils.Docker(bc); err != nil {
+		return err
 	}
+
+	//makes hidden dir read-only
+	utils.MakeHidden(bc)
+	spinner.LogMessage("Hidden Dir is now read-only.", "info")
+
+	// Stop loading spinner
+	spinner.Spinner.Stop()
+
+	return nil
 }
@@ -11,55 +11,97 @@ import (
 )
 
 func Config() {
+	bc, err := utils.NewBuildContext("config", os.Args[2:])
+	exitOnError(bc, err)
+
+	dryRun(bc)
+
+	startBuild(bc)
+	exitOnError(bc, configBuild(bc))
+	finishBuild(bc)
+}
+
+func configBuild(bc *utils.BuildContext) error {
 	//check args normally,
-	utils.CheckArgs()
+	bc.Step = "checkargs"
+	if err := utils.CheckArgs(bc); err != nil {
+		return err
+	}
 
 	// Start loading spinner
 	spinner.Spinner.Start()
 
 	//clone repo into temp dir to pull builder.yaml info
-	utils.CloneRepo("./tempRepo")
+	bc.Step = "clone"
+	if err := utils.CloneRepo(bc, "./tempRepo"); err != nil {
+		return err
+	}
 
-	//set yaml info as env vars
-	yaml.YamlParser("./tempRepo/builder.yaml")
+	//parse yaml info into the build config
+	bc.Step = "config"
+	if err := yaml.YamlParser("./tempRepo/builder.yaml", &bc.Config); err != nil {
+		return err
+	}
 
 	// clone repo into folder named after project name and keep track of its path
-	projectName := utils.GetName()
-	if os.Getenv("BUILDER_DIR_PATH") != "" { // User wants this repo built elsewhere
-		utils.CloneRepo(os.Getenv("BUILDER_DIR_PATH") + "/" + projectName)
-		os.Setenv("BUILDER_REPO_DIR", os.Getenv("BUILDER_DIR_PATH")+"/"+projectName)
+	projectName := utils.GetName(bc)
+	if bc.Config.ProjectPath != "" { // User wants this repo built elsewhere
+		bc.RepoDir = bc.Config.ProjectPath + "/" + projectName
 	} else {
-		utils.CloneRepo("./" + projectName)
-		os.Setenv("BUILDER_REPO_DIR", "./"+projectName)
+		bc.RepoDir = "./" + projectName
+	}
+	bc.Step = "clone"
+	if err := utils.CloneRepo(bc, bc.RepoDir); err != nil {
+		return err
 	}
 	spinner.LogMessage("Repo cloned successfully.", "info")
 
 	// make dirs
-	directory.MakeDirs()
+	bc.Step = "directories"
+	if err := directory.MakeDirs(bc); err != nil {
+		return err
+	}
 	spinner.LogMessage("Directories successfully created.", "info")
 
 	// copy repo files we just cloned to hidden dir
-	repoDir := os.Getenv("BUILDER_REPO_DIR")
-	hiddenDir := os.Getenv("BUILDER_HIDDEN_DIR")
-	utils.CloneRepoFiles(repoDir, hiddenDir)
+	bc.Step = "copy"
+	if err := utils.CloneRepoFiles(bc, bc.RepoDir, bc.HiddenDir); err != nil {
+		return err
+	}
+
+	// read the secrets so they're redacted from the build log and ${...} can use them
+	bc.Step = "secrets"
+	if err := utils.LoadSecrets(bc); err != nil {
+		return err
+	}
+
+	// fill in the ${...} variables of the builder.yaml
+	bc.Step = "config"
+	if err := utils.InterpolateConfig(bc, bc.RepoDir); err != nil {
+		return err
+	}
 
 	// compile logic to derive project type
-	derive.ProjectType()
+	if err := derive.ProjectType(bc); err != nil {
+		return err
+	}
 
 	//Get build metadata (deprecated, func moved inside compiler)
 	// utils.Metadata()
 	spinner.LogMessage("Metadata created successfully.", "info")
 
-	// Store build metadata to hidden builder dir
-	utils.StoreBuildMetadataLocally()
-
 	//Check for Dockerfile, then build image
-	utils.Docker()
+	bc.Step = "docker"
+	if err := utils.Docker(bc); err != nil {
+		return err
+	}
 
 	//makes hidden dir read-only
-	utils.MakeHidden()
+	utils.MakeHidden(bc)
 	spinner.LogMessage("Hidden Dir is now read-only.", "info")
 
 	// Stop loading spinner
 	spinner.Spinner.Stop()
+
+	return nil
 }
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"Builder/yaml"
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+// IsConfigFileCommand reports whether builder config was given a config file subcommand
+// rather than a repo to build
+func IsConfigFileCommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+
+	switch args[0] {
+	case "get", "set", "unset", "list":
+		return true
+	}
+
+	return false
+}
+
+// ConfigFile reads and edits the user (~/.builder/config.yaml) and system
+// (/etc/builder/config.yaml) config files
+func ConfigFile() {
+	exitOnCommandError(configFile(os.Args[2:]))
+}
+
+func configFile(args []string) error {
+	subcommand := args[0]
+	asJSON := false
+	name := yaml.UserConfig
+
+	var values []string
+	for _, arg := range args[1:] {
+		switch arg {
+		case "--json":
+			asJSON = true
+		case "--system":
+			name = yaml.SystemConfig
+		default:
+			values = append(values, arg)
+		}
+	}
+
+	switch subcommand {
+	case "list":
+		return listConfig(asJSON)
+	case "get":
+		if len(values) != 1 {
+			return fmt.Errorf("usage: builder config get <key>")
+		}
+		return getConfig(values[0], asJSON)
+	case "set":
+		if len(values) != 2 {
+			return fmt.Errorf("usage: builder config set <key> <value> [--system]")
+		}
+		if values[1] == "" {
+			return fmt.Errorf("no value provided for %s, use builder config unset to remove it", values[0])
+		}
+		return setConfig(name, values[0], values[1])
+	case "unset":
+		if len(values) != 1 {
+			return fmt.Errorf("usage: builder config unset <key> [--system]")
+		}
+		return setConfig(name, values[0], "")
+	}
+
+	return fmt.Errorf("unknown config command %s", subcommand)
+}
+
+func listConfig(asJSON bool) error {
+	settings, err := yaml.GlobalSettings()
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		if settings == nil {
+			settings = []yaml.ConfigSetting{}
+		}
+		return printJSON(settings)
+	}
+
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "KEY\tVALUE\tSOURCE")
+	for _, setting := range settings {
+		source := setting.Source
+		if setting.Path != "" {
+			source += " (" + setting.Path + ")"
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\n", setting.Key, setting.Value, source)
+	}
+
+	return table.Flush()
+}
+
+func getConfig(key string, asJSON bool) error {
+	setting, ok, err := yaml.GlobalSetting(key)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%s is not set", key)
+	}
+
+	if asJSON {
+		return printJSON(setting)
+	}
+
+	fmt.Println(setting.Value)
+
+	return nil
+}
+
+func setConfig(name, key, value string) error {
+	path, err := yaml.SetGlobalSetting(name, key, value, validateChecks())
+	if err != nil {
+		return err
+	}
+
+	if value == "" {
+		fmt.Printf("Removed %s from %s\n", key, path)
+	} else {
+		fmt.Printf("Set %s in %s\n", key, path)
+	}
+
+	return nil
+}
@@ -0,0 +1,224 @@
+package cmd
+
+import (
+	"Builder/history"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// date formats accepted by the --since and --until flags
+var dateFormats = []string{"2006-01-02", "2006-01-02T15:04", time.RFC3339}
+
+// fields printed first by builder show, in order.  Other fields are printed after them.
+var showFields = []string{
+	"BuildID", "Status", "ProjectName", "ProjectType", "GitURL", "BranchName", "GitHash", "MasterGitHash",
+	"UserName", "HomeDir", "IP", "StartTime", "EndTime", "ArtifactName", "ArtifactLocation",
+	"ArtifactChecksums", "LogsLocation", "Step", "ExitCode", "Error", "LogTail",
+}
+
+// History lists the builds in the build history
+func History() {
+	exitOnCommandError(listHistory(os.Args[2:]))
+}
+
+// Show prints everything recorded about one build
+func Show() {
+	exitOnCommandError(showBuild(os.Args[2:]))
+}
+
+func listHistory(args []string) error {
+	var query history.Query
+	asJSON := false
+
+	for i := 0; i < len(args); i++ {
+		flag := args[i]
+		if flag == "--json" {
+			asJSON = true
+			continue
+		}
+
+		if i+1 >= len(args) {
+			return fmt.Errorf("no value provided for %s", flag)
+		}
+		value := args[i+1]
+		i++
+
+		switch flag {
+		case "--project", "-p":
+			query.ProjectName = value
+		case "--branch", "-b":
+			query.BranchName = value
+		case "--user", "-u":
+			query.UserName = value
+		case "--status", "-s":
+			query.Status = value
+		case "--since":
+			since, err := parseDate(value)
+			if err != nil {
+				return err
+			}
+			query.Since = since
+		case "--until":
+			until, err := parseDate(value)
+			if err != nil {
+				return err
+			}
+			// a plain date includes the whole day
+			if len(value) == len("2006-01-02") {
+				until = until.Add(24*time.Hour - time.Nanosecond)
+			}
+			query.Until = until
+		default:
+			return fmt.Errorf("unknown history flag %s", flag)
+		}
+	}
+
+	store, err := historyStore()
+	if err != nil {
+		return err
+	}
+	builds := store.Query(query)
+
+	if asJSON {
+		return printJSON(builds)
+	}
+
+	if len(builds) == 0 {
+		fmt.Println("No builds to display")
+		return nil
+	}
+
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "BUILD ID\tSTATUS\tPROJECT\tBRANCH\tUSER\tSTARTED\tGIT HASH")
+	for _, build := range builds {
+		started := build.Get("StartTime")
+		if startTime := build.StartTime(); !startTime.IsZero() {
+			started = startTime.Local().Format("2006-01-02 15:04:05")
+		}
+
+		// builds recorded before GitHash was added only have the default branch's hash
+		gitHash := build.Get("GitHash")
+		if gitHash == "" {
+			gitHash = build.Get("MasterGitHash")
+		}
+		if len(gitHash) > 7 {
+			gitHash = gitHash[:7]
+		}
+
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", build.ID(), build.Status(), build.Get("ProjectName"),
+			build.Get("BranchName"), build.Get("UserName"), started, gitHash)
+	}
+
+	return table.Flush()
+}
+
+func showBuild(args []string) error {
+	var buildID string
+	asJSON := false
+
+	for _, arg := range args {
+		if arg == "--json" {
+			asJSON = true
+		} else if buildID == "" {
+			buildID = arg
+		}
+	}
+
+	if buildID == "" {
+		return errors.New("no BuildID provided.  Usage: builder show <buildID> [--json]")
+	}
+
+	store, err := historyStore()
+	if err != nil {
+		return err
+	}
+
+	build, found := store.Lookup(buildID)
+	if !found {
+		return fmt.Errorf("no build found with BuildID %s", buildID)
+	}
+
+	if asJSON {
+		return printJSON(build)
+	}
+
+	printed := map[string]bool{}
+	for _, field := range showFields {
+		printed[field] = true
+		if _, ok := build[field]; ok {
+			printField(field, build[field])
+		}
+	}
+
+	var otherFields []string
+	for field := range build {
+		if !printed[field] {
+			otherFields = append(otherFields, field)
+		}
+	}
+	sort.Strings(otherFields)
+
+	for _, field := range otherFields {
+		printField(field, build[field])
+	}
+
+	return nil
+}
+
+// printField prints one field of builder show.  Lists and objects are printed as indented JSON.
+func printField(field string, value interface{}) {
+	switch value.(type) {
+	case []interface{}, map[string]interface{}:
+		valueJSON, _ := json.MarshalIndent(value, "  ", "  ")
+		fmt.Printf("%s:\n  %s\n", field, valueJSON)
+	case nil:
+		fmt.Printf("%s:\n", field)
+	default:
+		fmt.Printf("%s: %v\n", field, value)
+	}
+}
+
+func printJSON(value interface{}) error {
+	valueJSON, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(string(valueJSON))
+
+	return nil
+}
+
+func parseDate(value string) (time.Time, error) {
+	for _, format := range dateFormats {
+		if date, err := time.ParseInLocation(format, value, time.Local); err == nil {
+			return date, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("could not parse date %s, use YYYY-MM-DD", value)
+}
+
+func historyStore() (*history.Store, error) {
+	store, err := history.OpenDefault()
+	if err != nil {
+		return nil, fmt.Errorf("could not open build history: %w", err)
+	}
+
+	return store, nil
+}
+
+// exitOnCommandError prints err and exits for commands that don't run a build
+func exitOnCommandError(err error) {
+	if err == nil {
+		return
+	}
+
+	fmt.Fprintln(os.Stderr, "Error: "+err.Error())
+	os.Exit(1)
+}
@@ -5,49 +5,101 @@ import (
 	"Builder/directory"
 	"Builder/spinner"
 	"Builder/utils"
+	"Builder/yaml"
+	"errors"
 
 	"os"
 )
 
 func Init() {
+	bc, err := utils.NewBuildContext("init", os.Args[2:])
+	exitOnError(bc, err)
+
+	dryRun(bc)
+
+	startBuild(bc)
+	exitOnError(bc, initBuild(bc))
+	finishBuild(bc)
+}
+
+// errProfileWithoutYaml is returned when --profile is given to a build that doesn't read a
+// builder.yaml
+var errProfileWithoutYaml = errors.New("--profile needs a builder.yaml, use builder config or builder")
+
+func initBuild(bc *utils.BuildContext) error {
 	//check argument syntax, exit if incorrect
-	utils.CheckArgs()
+	bc.Step = "checkargs"
+	if err := utils.CheckArgs(bc); err != nil {
+		return err
+	}
+	if bc.Config.Profile != "" {
+		return errProfileWithoutYaml
+	}
+
+	//init builds don't read the builder.yaml, only the global config files
+	bc.Step = "config"
+	if err := yaml.LoadGlobalConfig(&bc.Config); err != nil {
+		return err
+	}
 
 	// Start loading spinner
 	spinner.Spinner.Start()
 
 	// clone repo into folder named after project name and keep track of its path
-	projectName := utils.GetName()
-	os.Setenv("BUILDER_REPO_DIR", "./"+projectName)
-	utils.CloneRepo("./" + projectName)
+	projectName := utils.GetName(bc)
+	bc.RepoDir = "./" + projectName
+	bc.Step = "clone"
+	if err := utils.CloneRepo(bc, bc.RepoDir); err != nil {
+		return err
+	}
 	spinner.LogMessage("Repo cloned successfully.", "info")
 
 	// make dirs
-	directory.MakeDirs()
+	bc.Step = "directories"
+	if err := directory.MakeDirs(bc); err != nil {
+		return err
+	}
 	spinner.LogMessage("Directories successfully created.", "info")
 
 	// copy repo files we just cloned to hidden dir
-	repoDir := os.Getenv("BUILDER_REPO_DIR")
-	hiddenDir := os.Getenv("BUILDER_HIDDEN_DIR")
-	utils.CloneRepoFiles(repoDir, hiddenDir)
+	bc.Step = "copy"
+	if err := utils.CloneRepoFiles(bc, bc.RepoDir, bc.HiddenDir); err != nil {
+		return err
+	}
+
+	// read the secrets so they're redacted from the build log and ${...} can use them
+	bc.Step = "secrets"
+	if err := utils.LoadSecrets(bc); err != nil {
+		return err
+	}
+
+	// fill in the ${...} variables of the builder.yaml
+	bc.Step = "config"
+	if err := utils.InterpolateConfig(bc, bc.RepoDir); err != nil {
+		return err
+	}
 
 	// compile logic to derive project type
-	derive.ProjectType()
+	if err := derive.ProjectType(bc); err != nil {
+		return err
+	}
 
 	//Get build metadata (deprecated, func moved inside compiler)
 	// utils.Metadata()
 	spinner.LogMessage("Metadata created successfully.", "info")
 
-	// Store build metadata to hidden builder dir
-	utils.StoreBuildMetadataLocally()
-
 	//Check for Dockerfile, then build image
-	utils.Docker()
+	bc.Step = "docker"
+	if err := utils.Docker(bc); err != nil {
+		return err
+	}
 
 	//makes hidden dir read-only
-	utils.MakeHidden()
+	utils.MakeHidden(bc)
 	spinner.LogMessage("Hidden Dir is now read-only.", "info")
 
 	// Stop loading spinner
 	spinner.Spinner.Stop()
+
+	return nil
 }
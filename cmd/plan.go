@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"Builder/compile"
+	"Builder/derive"
+	"Builder/directory"
+	"Builder/utils"
+	"Builder/yaml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Plan prints what a build would do without cloning into the builds dir or running anything
+func Plan() {
+	args := os.Args[2:]
+
+	// a repo is planned like builder config if it has a builder.yaml, otherwise like builder init
+	command := "builder"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command = "config"
+	}
+
+	bc, err := utils.NewBuildContext(command, args)
+	exitOnCommandError(err)
+
+	exitOnCommandError(printPlan(bc, hasFlag(args, "--json"), true))
+}
+
+// dryRun prints the plan of the build bc would run and exits, if --dry-run was given
+func dryRun(bc *utils.BuildContext) {
+	if !bc.Flags.DryRun {
+		return
+	}
+
+	exitOnCommandError(printPlan(bc, hasFlag(os.Args, "--json"), false))
+	os.Exit(0)
+}
+
+// printPlan works out the plan of the build and prints it.  pickCommand plans a repo like
+// builder config if it has a builder.yaml and like builder init if it doesn't.
+func printPlan(bc *utils.BuildContext, asJSON bool, pickCommand bool) error {
+	plan, err := planBuild(bc, pickCommand)
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		return printJSON(plan)
+	}
+
+	printBuildPlan(plan)
+
+	return nil
+}
+
+// planBuild reads the project the way the build would and works out its plan.  init and
+// config builds clone the repo into a temp dir, which is removed afterwards.
+func planBuild(bc *utils.BuildContext, pickCommand bool) (*compile.BuildPlan, error) {
+	srcDir, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	if !bc.IsBuilderCommand() {
+		if err := utils.CheckArgs(bc); err != nil {
+			return nil, err
+		}
+
+		tempDir, err := os.MkdirTemp("", "builder-plan-")
+		if err != nil {
+			return nil, fmt.Errorf("could not create temp dir: %w", err)
+		}
+		defer os.RemoveAll(tempDir)
+
+		if err := utils.CloneRepo(bc, tempDir); err != nil {
+			return nil, err
+		}
+		srcDir = tempDir
+	}
+
+	yamlPath := filepath.Join(srcDir, "builder.yaml")
+	_, statErr := os.Stat(yamlPath)
+	if pickCommand && !bc.IsBuilderCommand() && statErr != nil {
+		bc.Command = "init"
+	}
+
+	if (bc.Command == "init" || statErr != nil) && bc.Config.Profile != "" {
+		return nil, errProfileWithoutYaml
+	}
+
+	//init builds don't read the builder.yaml, config builds need one
+	if bc.Command == "config" || (bc.IsBuilderCommand() && statErr == nil) {
+		if err := yaml.YamlParser(yamlPath, &bc.Config); err != nil {
+			return nil, err
+		}
+	} else if err := yaml.LoadGlobalConfig(&bc.Config); err != nil {
+		return nil, err
+	}
+
+	if err := utils.InterpolateConfig(bc, srcDir); err != nil {
+		return nil, err
+	}
+
+	directory.PlanDirs(bc)
+
+	// the hidden dir doesn't exist yet, detect and plan from the source it would be copied from
+	hiddenDir := bc.HiddenDir
+	bc.HiddenDir = srcDir
+
+	compiler, buildFile, err := derive.Detect(bc, srcDir)
+	if err != nil {
+		return nil, err
+	}
+
+	plan, err := compile.PlanBuild(bc, compiler, buildFile, directory.FinishedParentDirPath(bc))
+	if err != nil {
+		return nil, err
+	}
+
+	plan.Dirs.Hidden = hiddenDir
+	plan.BuildFile = hiddenDir + strings.TrimPrefix(buildFile, srcDir)
+	bc.HiddenDir = hiddenDir
+
+	return plan, nil
+}
+
+func printBuildPlan(plan *compile.BuildPlan) {
+	fmt.Printf("Plan for %s (%s project, builder %s)\n", plan.ProjectName, plan.ProjectType, plan.Command)
+	fmt.Println("Build file:  " + plan.BuildFile)
+	if plan.Timeout != "" {
+		fmt.Println("Timeout:     " + plan.Timeout)
+	}
+
+	fmt.Println("\nDirectories:")
+	fmt.Println("  parent     " + plan.Dirs.Parent)
+	fmt.Println("  hidden     " + plan.Dirs.Hidden)
+	fmt.Println("  workspace  " + plan.Dirs.Workspace)
+	fmt.Println("  logs       " + plan.Dirs.Logs)
+	fmt.Println("  build      " + plan.Dirs.Build)
+	fmt.Println("  the parent dir is renamed to " + plan.Dirs.FinishedParent + " once the project is built")
+
+	fmt.Println("\nCommands:")
+	for _, command := range plan.Commands {
+		stage := command.Stage
+		if command.Name != "" {
+			stage += " " + command.Name
+		}
+		fmt.Printf("  [%s] %s\n", stage, command.Command)
+		fmt.Println("      in " + command.Dir)
+		if len(command.Env) > 0 {
+			fmt.Println("      env " + strings.Join(command.Env, " "))
+		}
+		if command.Timeout != "" {
+			fmt.Println("      timeout " + command.Timeout)
+		}
+		if command.ContinueOnError {
+			fmt.Println("      continues on error")
+		}
+	}
+
+	if len(plan.Env) > 0 {
+		fmt.Println("\nEnvironment of the build commands:")
+		for _, env := range plan.Env {
+			fmt.Println("  " + env)
+		}
+	}
+
+	fmt.Println("\nArtifacts (patterns are relative to the build dir):")
+	for _, artifact := range plan.Artifacts {
+		fmt.Println("  " + artifact)
+	}
+
+	fmt.Println("\nOutputs:")
+	for _, output := range plan.Outputs {
+		fmt.Println("  " + output)
+	}
+
+	if len(plan.Notes) > 0 {
+		fmt.Println("\nNotes:")
+		for _, note := range plan.Notes {
+			fmt.Println("  " + note)
+		}
+	}
+}
+
+// hasFlag reports whether flag is in args
+func hasFlag(args []string, flag string) bool {
+	for _, arg := range args {
+		if arg == flag {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"Builder/secrets"
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Secret stores, lists and removes the secrets in the encrypted secrets file
+// (~/.builder/secrets.enc) that builder.yaml secrets can read with store:
+func Secret() {
+	exitOnCommandError(secret(os.Args[2:]))
+}
+
+func secret(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: builder secret set <name> [<value>] | list | rm <name>")
+	}
+
+	store, err := secrets.Open()
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "list":
+		names := store.Names()
+		if hasFlag(args, "--json") {
+			return printJSON(names)
+		}
+		for _, name := range names {
+			fmt.Println(name)
+		}
+		return nil
+	case "set":
+		if len(args) < 2 || len(args) > 3 {
+			return fmt.Errorf("usage: builder secret set <name> [<value>]")
+		}
+
+		var value string
+		if len(args) == 3 {
+			value = args[2]
+		} else {
+			// read from stdin so the value stays out of the shell history
+			fmt.Fprint(os.Stderr, "Value of "+args[1]+": ")
+			line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+			if err != nil && line == "" {
+				return fmt.Errorf("could not read the value of %s: %w", args[1], err)
+			}
+			value = strings.TrimRight(line, "\r\n")
+		}
+		if value == "" {
+			return fmt.Errorf("no value provided for %s", args[1])
+		}
+
+		if err := store.Set(args[1], value); err != nil {
+			return err
+		}
+		fmt.Printf("Stored %s in %s\n", args[1], store.Path())
+		return nil
+	case "rm":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: builder secret rm <name>")
+		}
+
+		removed, err := store.Delete(args[1])
+		if err != nil {
+			return err
+		}
+		if !removed {
+			return fmt.Errorf("%s is not in %s", args[1], store.Path())
+		}
+		fmt.Printf("Removed %s from %s\n", args[1], store.Path())
+		return nil
+	}
+
+	return fmt.Errorf("unknown secret command %s", args[0])
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"Builder/compile"
+	"Builder/utils"
+	"Builder/yaml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// Validate checks a builder.yaml for unknown keys, values of the wrong type and keys that
+// don't work together
+func Validate() {
+	exitOnCommandError(validateBuilderYaml(os.Args[2:]))
+}
+
+func validateBuilderYaml(args []string) error {
+	yamlPath := "builder.yaml"
+	asJSON := false
+
+	for _, arg := range args {
+		switch arg {
+		case "--json":
+			asJSON = true
+		case "--schema":
+			_, err := os.Stdout.Write(yaml.Schema)
+			return err
+		default:
+			yamlPath = arg
+		}
+	}
+
+	// a dir is checked for its builder.yaml
+	if info, err := os.Stat(yamlPath); err == nil && info.IsDir() {
+		yamlPath = filepath.Join(yamlPath, "builder.yaml")
+	}
+
+	source, err := os.ReadFile(yamlPath)
+	if err != nil {
+		return fmt.Errorf("could not read builder yaml: %w", err)
+	}
+
+	problems := yaml.Validate(source, validateChecks())
+
+	if asJSON {
+		if problems == nil {
+			problems = []yaml.Problem{}
+		}
+		if err := printJSON(problems); err != nil {
+			return err
+		}
+	} else {
+		// file:line: key: message, like compiler errors
+		for _, problem := range problems {
+			location := yamlPath
+			if problem.Line > 0 {
+				location += ":" + strconv.Itoa(problem.Line)
+			}
+			problem.Line = 0
+			fmt.Println(location + ": " + problem.String())
+		}
+		if len(problems) == 0 {
+			fmt.Println(yamlPath + " is valid")
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%d problem(s) found in %s", len(problems), yamlPath)
+	}
+
+	return nil
+}
+
+// validateChecks checks the values the yaml package can't check on its own
+func validateChecks() yaml.Checks {
+	return yaml.Checks{
+		ProjectType: func(projectType string) error {
+			if _, ok := compile.Lookup(projectType); !ok {
+				return fmt.Errorf("unknown project type %s", projectType)
+			}
+			return nil
+		},
+		Timeout: func(timeout string) error {
+			_, err := utils.ParseTimeout(timeout)
+			return err
+		},
+	}
+}
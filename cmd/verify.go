@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"Builder/artifact"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Verify recomputes the checksums of a build's artifacts and compares them to its metadata
+func Verify() {
+	exitOnCommandError(verifyArtifacts(os.Args[2:]))
+}
+
+func verifyArtifacts(args []string) error {
+	var target string
+	asJSON := false
+
+	for _, arg := range args {
+		if arg == "--json" {
+			asJSON = true
+		} else if target == "" {
+			target = arg
+		}
+	}
+
+	if target == "" {
+		return errors.New("nothing to verify.  Usage: builder verify <artifact dir | archive | buildID> [--json]")
+	}
+
+	var results []artifact.VerifyResult
+	if _, err := os.Stat(target); err == nil {
+		// artifact dir or compressed artifact dir, checked against the metadata.json it holds
+		results, err = artifact.Verify(target, nil)
+		if err != nil {
+			return err
+		}
+	} else {
+		// BuildID, checked against the checksums in the build history
+		store, err := historyStore()
+		if err != nil {
+			return err
+		}
+
+		build, found := store.Lookup(target)
+		if !found {
+			return fmt.Errorf("%s is not an artifact dir, archive or BuildID", target)
+		}
+
+		buildJSON, err := json.Marshal(build)
+		if err != nil {
+			return err
+		}
+		expected, err := artifact.ParseChecksums(buildJSON)
+		if err != nil {
+			return err
+		}
+
+		results, err = artifact.Verify(build.Get("ArtifactLocation"), expected)
+		if err != nil {
+			return err
+		}
+	}
+
+	failed := 0
+	for _, result := range results {
+		if result.Result != artifact.VerifyMatch {
+			failed++
+		}
+	}
+
+	if asJSON {
+		if err := printJSON(results); err != nil {
+			return err
+		}
+	} else {
+		printVerifyResults(results)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d artifact(s) failed verification", failed, len(results))
+	}
+
+	return nil
+}
+
+func printVerifyResults(results []artifact.VerifyResult) {
+	counts := map[string]int{}
+
+	for _, result := range results {
+		counts[result.Result]++
+
+		switch result.Result {
+		case artifact.VerifyMatch:
+			fmt.Println("OK        " + result.Path)
+		case artifact.VerifyMissing:
+			fmt.Println("MISSING   " + result.Path)
+		default:
+			fmt.Println("MISMATCH  " + result.Path)
+			if result.Actual.Size != result.Expected.Size {
+				fmt.Printf("          size    expected %d, got %d\n", result.Expected.Size, result.Actual.Size)
+			}
+			if result.Actual.SHA256 != result.Expected.SHA256 {
+				fmt.Printf("          sha256  expected %s, got %s\n", result.Expected.SHA256, result.Actual.SHA256)
+			}
+		}
+	}
+
+	fmt.Printf("%d matched, %d mismatched, %d missing\n", counts[artifact.VerifyMatch], counts[artifact.VerifyMismatch], counts[artifact.VerifyMissing])
+}
@@ -1,246 +1,130 @@
 package compile
 
 import (
-	"Builder/artifact"
-	"Builder/directory"
-	"Builder/spinner"
 	"Builder/utils"
-	"Builder/utils/log"
-	"Builder/yaml"
-	"bufio"
+	"errors"
 	"fmt"
-	"os"
 	"os/exec"
 	"path/filepath"
-	"runtime"
 	"strings"
-	"time"
 
-	cp "github.com/otiai10/copy"
+	"github.com/manifoldco/promptui"
 )
 
-func CSharp(filePath string) {
-	fmt.Println("C# filePath: " + filePath)
-	//Set default project type env for builder.yaml creation
-	projectType := os.Getenv("BUILDER_PROJECT_TYPE")
-	if projectType == "" {
-		os.Setenv("BUILDER_PROJECT_TYPE", "c#")
-	}
-
-	//Set up local logger
-	localPath, _ := os.LookupEnv("BUILDER_LOGS_DIR")
-	locallogger, closeLocalLogger = log.NewLogger("logs", localPath)
-
-	//define dir path for command to run in
-	var fullPath string
-	configPath := os.Getenv("BUILDER_DIR_PATH")
-	//if user defined path in builder.yaml, full path is included already, else add curren dir + local path
-	if os.Getenv("BUILDER_COMMAND") == "true" {
-		// ex: C:/Users/Name/Projects/helloworld_19293/workspace/dir
-		fullPath = filePath
-	} else if configPath != "" {
-		// ex: C:/Users/Name/Projects/helloworld_19293/workspace/dir
-		fullPath = filePath
-	} else {
-		path, _ := os.Getwd()
-		//combine local path to newly created tempWorkspace,
-		//gets rid of "." in path name
-		// ex: C:/Users/Name/Projects + /helloworld_19293/workspace/dir
-		fullPath = path + filePath[strings.Index(filePath, ".")+1:]
-		os.Setenv("BUILDER_DIR_PATH", path)
-	}
+type cSharpCompiler struct{}
 
-	//install dependencies/build,
-	// if yaml build type exists install accordingly, if buildCmd exists,
-	buildTool := strings.ToLower(os.Getenv("BUILDER_BUILD_TOOL"))
-	buildCmd := os.Getenv("BUILDER_BUILD_COMMAND")
-
-	var cmd *exec.Cmd
-	if buildCmd != "" {
-		//user specified cmd
-		buildCmdArray := strings.Fields(buildCmd)
-		cmd = exec.Command(buildCmdArray[0], buildCmdArray[1:]...)
-		cmd.Dir = fullPath // or whatever directory it's in
-	} else if buildTool == "dotnet" {
-		cmd = exec.Command("dotnet", "build", fullPath)
-		cmd.Dir = fullPath // or whatever directory it's in
-		os.Setenv("BUILDER_BUILD_COMMAND", "dotnet build "+fullPath)
-	} else {
-		//default
-		cmd = exec.Command("dotnet", "build", fullPath)
-		// cmd.Dir = fullPath // or whatever directory it's in
-		os.Setenv("BUILDER_BUILD_TOOL", "dotnet")
-		os.Setenv("BUILDER_BUILD_COMMAND", "dotnet build "+fullPath)
-		os.Setenv("BUILDER_BUILD_FILE", fullPath[strings.LastIndex(fullPath, "/")+1:])
-	}
+func init() {
+	Register(80, cSharpCompiler{}, "csharp")
+}
 
-	//run cmd, check for err, log cmd
-	spinner.LogMessage("running command: "+cmd.String(), "info")
+func (cSharpCompiler) ProjectType() string {
+	return "c#"
+}
 
-	stdout, pipeErr := cmd.StdoutPipe()
-	if pipeErr != nil {
-		spinner.LogMessage(pipeErr.Error(), "fatal")
+// Detect derives c# projects by the extension of the project or solution file
+func (cSharpCompiler) Detect(bc *utils.BuildContext, dir string) (string, error) {
+	if bc.Config.ProjectType != "" && bc.Config.BuildFile != "" {
+		return detectBuildFile(bc, dir)
 	}
 
-	cmd.Stderr = cmd.Stdout
-
-	// Make a new channel which will be used to ensure we get all output
-	done := make(chan struct{})
-
-	scanner := bufio.NewScanner(stdout)
-
-	// Use the scanner to scan the output line by line and log it
-	// It's running in a goroutine so that it doesn't block
-	go func() {
-		// Read line by line and process it
-		for scanner.Scan() {
-			line := scanner.Text()
-			spinner.Spinner.Stop()
-			locallogger.Info(line)
-			spinner.Spinner.Start()
+	for _, ext := range []string{".csproj", ".sln"} {
+		filePath, err := findExt(dir, ext)
+		if err != nil || filePath != "" {
+			return filePath, err
 		}
-
-		// We're all done, unblock the channel
-		done <- struct{}{}
-
-	}()
-
-	os.Setenv("BUILD_START_TIME", time.Now().Format(time.RFC850))
-
-	if err := cmd.Start(); err != nil {
-		spinner.LogMessage(err.Error(), "fatal")
 	}
 
-	// Wait for all output to be processed
-	<-done
-
-	// Wait for cmd to finish
-	if err := cmd.Wait(); err != nil {
-		spinner.LogMessage(err.Error(), "fatal")
-	}
-
-	os.Setenv("BUILD_END_TIME", time.Now().Format(time.RFC850))
-
-	// Close log file
-	closeLocalLogger()
-
-	// Update parent dir name to include start time and send back new full path
-	fullPath = directory.UpdateParentDirName(fullPath)
-
-	yaml.CreateBuilderYaml(fullPath)
-
-	packageCSharpArtifact(fullPath)
-
-	spinner.LogMessage("csharp project compiled successfully.", "info")
+	return "", nil
 }
 
-func packageCSharpArtifact(fullPath string) {
-	archiveExt := ""
-
-	if runtime.GOOS == "windows" {
-		archiveExt = ".zip"
-	} else {
-		archiveExt = ".tar.gz"
+// Build runs dotnet build on the project.  For a solution the user is prompted for the project to build.
+func (c cSharpCompiler) Build(bc *utils.BuildContext, buildFile string) error {
+	plan, err := c.Plan(bc, buildFile)
+	if err != nil {
+		return err
 	}
 
-	artifact.ArtifactDir()
-	artifactDir := os.Getenv("BUILDER_ARTIFACT_DIR")
-	outputPath := os.Getenv("BUILDER_OUTPUT_PATH")
-
-	//find artifact by extension
-	artifactsArray, _ := WalkMatch(fullPath, "*.dll")
-	os.Setenv("BUILDER_ARTIFACT_NAMES", strings.Join([]string(artifactsArray), ","))
-
-	var artifactNames []string
+	if err := utils.CopyDir(bc); err != nil {
+		return err
+	}
+	bc.BuildDir = plan.BuildDir
+	projectFile := bc.BuildDir + "/" + filepath.Base(buildFile)
 
-	//copy artifact(s), then remove artifact(s) from workspace
-	for i := 0; i < len(artifactsArray); i++ {
-		artifactNames = append(artifactNames, filepath.Base(artifactsArray[i]))
+	//if it's .sln, it will find all the project path in the solution(repo)
+	if filepath.Ext(projectFile) == ".sln" {
+		listOfProjects, err := exec.Command("dotnet", "sln", projectFile, "list").Output()
 
-		err := cp.Copy(artifactsArray[i], artifactDir+"/"+artifactNames[i])
 		if err != nil {
-			spinner.LogMessage(err.Error(), "warn")
+			return fmt.Errorf("dotnet sln failed: %w", err)
 		}
 
-		// If outputpath provided also cp artifacts to that location
-		if outputPath != "" {
-			// Check if outputPath exists.  If not, create it
-			if _, err := os.Stat(outputPath); os.IsNotExist(err) {
-				if err := os.Mkdir(outputPath, 0755); err != nil {
-					spinner.LogMessage("Could not create output path", "fatal")
-				}
-			}
-
-			err := cp.Copy(artifactsArray[i], outputPath+"/"+artifactNames[i])
-			if err != nil {
-				spinner.LogMessage(err.Error(), "warn")
-			}
-
-			spinner.LogMessage("Artifact(s) copied to output path provided", "info")
+		stringifyListOfProjects := string(listOfProjects)
+		listOfProjectsArray := strings.Split(stringifyListOfProjects, "\n")[2:]
+		//if there's more than 5 projects in solution(repo), user will be asked to use builder config instead
+		if len(listOfProjectsArray) > 5 {
+			return errors.New("there is more than 5 projects in this solution, please use Builder Config and specify the path of your file you wish to compile in the builder.yml")
 		}
 
-		errRemove := os.Remove(artifactsArray[i])
-		if errRemove != nil {
-			spinner.LogMessage(errRemove.Error(), "warn")
+		// < 5 projects in solution(repo), user will be prompt to choose a project path.
+		selectedPath, err := selectPathToCompileFrom(listOfProjectsArray)
+		if err != nil {
+			return err
 		}
+		projectFile = bc.BuildDir + "/" + selectedPath
+		bc.BuildDir = filepath.Dir(projectFile)
+		bc.Config.BuildFile = filepath.Base(projectFile)
 	}
 
-	os.Setenv("BUILDER_ARTIFACT_NAMES", strings.Join([]string(artifactNames), ","))
+	return runBuildCommand(bc, c)
+}
 
-	//create metadata, then copy contents to zip dir
-	utils.Metadata(artifactDir)
+func (cSharpCompiler) Plan(bc *utils.BuildContext, buildFile string) (CompilerPlan, error) {
+	if bc.Config.BuildTool == "" {
+		bc.Config.BuildTool = "dotnet"
+	}
 
-	if os.Getenv("ARTIFACT_ZIP_ENABLED") == "true" {
-		//zip artifact
-		artifact.ZipArtifactDir()
+	plan := CompilerPlan{BuildDir: workspaceBuildPath(bc, buildFile), Artifacts: []string{"**/*.dll"}}
 
-		//remove uncompressed artifacts
-		for i := 0; i < len(artifactsArray); i++ {
-			err := os.Remove(artifactDir + "/" + filepath.Base(artifactsArray[i]))
-			if err != nil {
-				spinner.LogMessage(err.Error(), "warn")
-			}
-		}
+	if filepath.Ext(buildFile) == ".sln" {
+		plan.Notes = append(plan.Notes, "the project to build in the solution is picked when the build runs")
+	} else if bc.Config.BuildFile == "" {
+		bc.Config.BuildFile = filepath.Base(buildFile)
+	}
 
-		// send artifact to user specified path or send to artifact directory
-		outputPath := os.Getenv("BUILDER_OUTPUT_PATH")
-		if outputPath != "" {
-			err := cp.Copy(artifactDir+archiveExt, outputPath+"/"+filepath.Base(artifactDir)+archiveExt)
-			if err != nil {
-				spinner.LogMessage(err.Error(), "warn")
-			}
-		} else {
-			err := cp.Copy(artifactDir+archiveExt, artifactDir+"/"+filepath.Base(artifactDir)+archiveExt)
-			if err != nil {
-				spinner.LogMessage(err.Error(), "warn")
-			}
-		}
+	return plan, nil
+}
 
-		errRemove := os.Remove(artifactDir + archiveExt)
-		if errRemove != nil {
-			spinner.LogMessage(errRemove.Error(), "warn")
-		}
+func (cSharpCompiler) DefaultBuildCommand(bc *utils.BuildContext) []string {
+	return []string{"dotnet", "build", bc.BuildDir + "/" + bc.Config.BuildFile}
+}
+
+func (cSharpCompiler) DefaultTestCommand(bc *utils.BuildContext) []string {
+	return []string{"dotnet", "test", bc.BuildDir + "/" + bc.Config.BuildFile}
+}
+
+func (cSharpCompiler) ToolchainCommands(bc *utils.BuildContext) [][]string {
+	return [][]string{{"dotnet", "--version"}}
+}
+
+func (cSharpCompiler) Package(bc *utils.BuildContext) error {
+	//find artifact by extension
+	paths, err := WalkMatch(bc.BuildDir, "*.dll")
+	if err != nil {
+		return fmt.Errorf("could not search build dir for artifacts: %w", err)
 	}
+
+	return collectArtifacts(bc, paths)
 }
 
-func WalkMatch(root, pattern string) ([]string, error) {
-	var matches []string
-	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if info.IsDir() {
-			return nil
-		}
-		if matched, err := filepath.Match(pattern, filepath.Base(path)); err != nil {
-			return err
-		} else if matched {
-			matches = append(matches, path)
-		}
-		return nil
-	})
+func selectPathToCompileFrom(filePaths []string) (string, error) {
+	prompt := promptui.Select{
+		Label: "Select a Path To Compile From: ",
+		Items: filePaths,
+	}
+	_, result, err := prompt.Run()
 	if err != nil {
-		return nil, err
+		return "", fmt.Errorf("prompt failed: %w", err)
 	}
-	return matches, nil
+
+	return result, nil
 }
@@ -1,398 +1,147 @@
 package compile
 
 import (
-	"Builder/artifact"
-	"Builder/directory"
-	"Builder/spinner"
 	"Builder/utils"
-	"Builder/utils/log"
 	"Builder/yaml"
-	"bufio"
-	"os"
-	"os/exec"
-	"path/filepath"
-	"runtime"
+	"errors"
+	"fmt"
 	"strings"
-	"time"
-
-	cp "github.com/otiai10/copy"
 )
 
-var closeLocalLogger func()
+type cCompiler struct{}
 
-// C/C++ does ...
-func C(filePath string) {
-	//Set default project type env for builder.yaml creation
-	projectType := os.Getenv("BUILDER_PROJECT_TYPE")
-	if projectType == "" {
-		os.Setenv("BUILDER_PROJECT_TYPE", "c")
-	}
+func init() {
+	Register(70, cCompiler{}, "c++")
+}
 
-	//Set up local logger
-	localPath, _ := os.LookupEnv("BUILDER_LOGS_DIR")
-	locallogger, closeLocalLogger = log.NewLogger("logs", localPath)
+func (cCompiler) ProjectType() string {
+	return "c"
+}
 
-	//define dir path for command to run in
-	var fullPath string
-	configPath := os.Getenv("BUILDER_DIR_PATH")
+func (cCompiler) Detect(bc *utils.BuildContext, dir string) (string, error) {
+	return detectBuildFile(bc, dir, "Makefile", "Makefile.am")
+}
 
-	if os.Getenv("BUILDER_COMMAND") == "true" {
-		// ex: C:/Users/Name/Projects/helloworld_19293/workspace/dir
-		fullPath = filePath
-	} else if configPath != "" { //if user defined path in builder.yaml, full path is included already, else add curren dir + local path
-		// ex: C:/Users/Name/Projects/helloworld_19293/workspace/dir
-		fullPath = filePath
-	} else {
-		path, _ := os.Getwd()
-		//combine local path to newly created tempWorkspace,
-		//gets rid of "." in path name
-		// ex: C:/Users/Name/Projects + /helloworld_19293/workspace/dir
-		fullPath = path + filePath[strings.Index(filePath, ".")+1:]
-		os.Setenv("BUILDER_DIR_PATH", path)
+// Build runs the pre-build, configure and build commands of a C/C++ project
+func (c cCompiler) Build(bc *utils.BuildContext, buildFile string) error {
+	plan, err := c.Plan(bc, buildFile)
+	if err != nil {
+		return err
 	}
 
-	//install dependencies/build, if yaml build type exists install accordingly
-	buildTool := strings.ToLower(os.Getenv("BUILDER_BUILD_TOOL"))
-	//find 'Makefile' to be built
-	buildFile := strings.ToLower(os.Getenv("BUILDER_BUILD_FILE"))
-	preBuildCmd := os.Getenv("BUILDER_PREBUILD_COMMAND")
-	configCmd := os.Getenv("BUILDER_CONFIG_COMMAND")
-	buildCmd := os.Getenv("BUILDER_BUILD_COMMAND")
-
-	var cmd *exec.Cmd
+	if err := utils.CopyDir(bc); err != nil {
+		return err
+	}
+	bc.BuildDir = plan.BuildDir
 
 	// If a pre-build command is provided execute it
-	if preBuildCmd != "" {
-		//user specified cmd
-		preBuildCmdArray := strings.Fields(preBuildCmd)
-		cmd = exec.Command(preBuildCmdArray[0], preBuildCmdArray[1:]...)
-		cmd.Dir = fullPath // or whatever directory it's in
-
-		//run pre-build cmd, check for err, log pre-build cmd
-		spinner.LogMessage("running command: "+cmd.String(), "info")
-
-		preBuildStdout, pipeErr := cmd.StdoutPipe()
-		if pipeErr != nil {
-			spinner.LogMessage(pipeErr.Error(), "fatal")
-		}
-
-		cmd.Stderr = cmd.Stdout
-
-		// Make a new channel which will be used to ensure we get all output
-		preBuildDone := make(chan struct{})
-
-		preBuildScanner := bufio.NewScanner(preBuildStdout)
-
-		// Use the scanner to scan the output line by line and log it
-		// It's running in a goroutine so that it doesn't block
-		go func() {
-			// Read line by line and process it
-			for preBuildScanner.Scan() {
-				line := preBuildScanner.Text()
-				// Have to stop spinner or it will get printed with log to console
-				spinner.Spinner.Stop()
-				locallogger.Info(line)
-				spinner.Spinner.Start()
-			}
-
-			// We're all done, unblock the channel
-			preBuildDone <- struct{}{}
-
-		}()
-
-		if err := cmd.Start(); err != nil {
-			spinner.LogMessage(err.Error(), "fatal")
-		}
-
-		// Wait for all output to be processed
-		<-preBuildDone
-
-		// Wait for cmd to finish
-		if err := cmd.Wait(); err != nil {
-			spinner.LogMessage(err.Error(), "fatal")
-		}
+	if err := runUserCommands(bc, "prebuildcmd", bc.Config.PreBuildCmd); err != nil {
+		return fmt.Errorf("prebuildcmd failed: %w", err)
 	}
 
 	// If a configure command is provided execute it
-	if configCmd != "" {
-		//user specified cmd
-		configCmdArray := strings.Fields(configCmd)
-		cmd = exec.Command(configCmdArray[0], configCmdArray[1:]...)
-		cmd.Dir = fullPath // or whatever directory it's in
-
-		//run config cmd, check for err, log config cmd
-		spinner.LogMessage("running command: "+cmd.String(), "info")
-
-		configStdout, pipeErr := cmd.StdoutPipe()
-		if pipeErr != nil {
-			spinner.LogMessage(pipeErr.Error(), "fatal")
-		}
-
-		cmd.Stderr = cmd.Stdout
-
-		// Make a new channel which will be used to ensure we get all output
-		configDone := make(chan struct{})
-
-		configScanner := bufio.NewScanner(configStdout)
-
-		// Use the scanner to scan the output line by line and log it
-		// It's running in a goroutine so that it doesn't block
-		go func() {
-			// Read line by line and process it
-			for configScanner.Scan() {
-				line := configScanner.Text()
-				// Have to stop spinner or it will get printed with log to console
-				spinner.Spinner.Stop()
-				locallogger.Info(line)
-				spinner.Spinner.Start()
-			}
-
-			// We're all done, unblock the channel
-			configDone <- struct{}{}
-
-		}()
-
-		if err := cmd.Start(); err != nil {
-			spinner.LogMessage(err.Error(), "fatal")
-		}
-
-		// Wait for all output to be processed
-		<-configDone
-
-		// Wait for cmd to finish
-		if err := cmd.Wait(); err != nil {
-			spinner.LogMessage(err.Error(), "fatal")
-		}
-	}
-
-	// Build command
-	if buildCmd != "" {
-		//user specified cmd
-		buildCmdArray := strings.Fields(buildCmd)
-		cmd = exec.Command(buildCmdArray[0], buildCmdArray[1:]...)
-		cmd.Dir = fullPath // or whatever directory it's in
-	} else if strings.Contains(buildTool, "Make") && buildFile != "" {
-		cmd = exec.Command("make -f", buildFile)
-		cmd.Dir = fullPath // or whatever directory it's in
-		os.Setenv("BUILDER_BUILD_COMMAND", "make -f "+buildFile)
-	} else {
-		//default
-		cmd = exec.Command("make")
-		cmd.Dir = fullPath   // or whatever directory it's in
-		if buildTool == "" { // If buildTool hasn't been set yet, set it
-			os.Setenv("BUILDER_BUILD_TOOL", "Make")
-		}
-		os.Setenv("BUILDER_BUILD_COMMAND", "make")
+	if err := runUserCommands(bc, "configcmd", bc.Config.ConfigCmd); err != nil {
+		return fmt.Errorf("configcmd failed: %w", err)
 	}
 
-	//run cmd, check for err, log cmd
-	spinner.LogMessage("running command: "+cmd.String(), "info")
+	return runBuildCommand(bc, c)
+}
 
-	stdout, pipeErr := cmd.StdoutPipe()
-	if pipeErr != nil {
-		spinner.LogMessage(pipeErr.Error(), "fatal")
+func (cCompiler) Plan(bc *utils.BuildContext, buildFile string) (CompilerPlan, error) {
+	if bc.Config.BuildTool == "" { // If buildTool hasn't been set yet, set it
+		bc.Config.BuildTool = "Make"
 	}
 
-	cmd.Stderr = cmd.Stdout
-
-	// Make a new channel which will be used to ensure we get all output
-	done := make(chan struct{})
-
-	scanner := bufio.NewScanner(stdout)
-
-	// Use the scanner to scan the output line by line and log it
-	// It's running in a goroutine so that it doesn't block
-	go func() {
-		// Read line by line and process it
-		for scanner.Scan() {
-			line := scanner.Text()
-			// Have to stop spinner or it will get printed with log to console
-			spinner.Spinner.Stop()
-			locallogger.Info(line)
-			spinner.Spinner.Start()
+	plan := CompilerPlan{BuildDir: workspaceBuildPath(bc, buildFile)}
+
+	// prebuildcmd and configcmd run in the build dir
+	buildDir := bc.BuildDir
+	bc.BuildDir = plan.BuildDir
+	defer func() { bc.BuildDir = buildDir }()
+
+	for _, cmds := range []struct {
+		stage, key string
+		commands   yaml.Commands
+	}{
+		{"prebuild", "prebuildcmd", bc.Config.PreBuildCmd},
+		{"configure", "configcmd", bc.Config.ConfigCmd},
+	} {
+		planned, err := planUserCommands(bc, cmds.stage, cmds.key, cmds.commands)
+		if err != nil {
+			return plan, err
 		}
-
-		// We're all done, unblock the channel
-		done <- struct{}{}
-
-	}()
-
-	os.Setenv("BUILD_START_TIME", time.Now().Format(time.RFC850))
-
-	if err := cmd.Start(); err != nil {
-		spinner.LogMessage(err.Error(), "fatal")
+		plan.Commands = append(plan.Commands, planned...)
 	}
 
-	// Wait for all output to be processed
-	<-done
-
-	// Wait for cmd to finish
-	if err := cmd.Wait(); err != nil {
-		spinner.LogMessage(err.Error(), "fatal")
+	if bc.Config.ArtifactList != "" {
+		plan.Artifacts = strings.Split(bc.Config.ArtifactList, ",")
+	} else {
+		plan.Artifacts = []string{"**/" + cArtifactPattern(bc)}
 	}
 
-	os.Setenv("BUILD_END_TIME", time.Now().Format(time.RFC850))
-
-	// Close log file
-	closeLocalLogger()
-
-	// Update parent dir name to include start time and send back new full path
-	fullPath = directory.UpdateParentDirName(fullPath)
-
-	//creates default builder.yaml if it doesn't exist
-	yaml.CreateBuilderYaml(fullPath)
-
-	packageCArtifact(fullPath)
-
-	spinner.LogMessage("C/C++ project compiled successfully.", "info")
+	return plan, nil
 }
 
-func packageCArtifact(fullPath string) {
-	archiveExt := ""
+func (cCompiler) DefaultBuildCommand(bc *utils.BuildContext) []string {
+	buildTool := strings.ToLower(bc.Config.BuildTool)
+	//find 'Makefile' to be built
+	buildFile := bc.Config.BuildFile
 
-	if runtime.GOOS == "windows" {
-		archiveExt = ".zip"
-	} else {
-		archiveExt = ".tar.gz"
+	if strings.Contains(buildTool, "make") && buildFile != "" {
+		return []string{"make", "-f", buildFile}
 	}
 
-	artifact.ArtifactDir()
-	artifactDir := os.Getenv("BUILDER_ARTIFACT_DIR")
-	artifactList := os.Getenv("BUILDER_ARTIFACT_LIST")
-	outputPath := os.Getenv("BUILDER_OUTPUT_PATH")
-	var artifactArray []string
-
-	// If we were given an artifacts list, handle it
-	if artifactList != "" {
-		artifactArray = strings.Split(artifactList, ",")
-		os.Setenv("BUILDER_ARTIFACT_NAMES", artifactList)
+	//default
+	return []string{"make"}
+}
 
-		//copy artifact(s), then remove artifact(s) from workspace
-		for _, artifact := range artifactArray {
-			err := cp.Copy(fullPath+"/"+artifact, artifactDir+"/"+artifact)
-			if err != nil {
-				spinner.LogMessage(err.Error(), "warn")
-			}
+func (cCompiler) DefaultTestCommand(bc *utils.BuildContext) []string {
+	// make has no standard test target ("test", "check"), a testcmd has to be given
+	return nil
+}
 
-			// If outputpath provided also cp artifacts to that location
-			if outputPath != "" {
-				// Check if outputPath exists.  If not, create it
-				if _, err := os.Stat(outputPath); os.IsNotExist(err) {
-					if err := os.Mkdir(outputPath, 0755); err != nil {
-						spinner.LogMessage("Could not create output path", "fatal")
-					}
-				}
-				// exec.Command("cp", fullPath+"/"+artifact, outputPath).Run()
-				err := cp.Copy(fullPath+"/"+artifact, outputPath+"/"+artifact)
-				if err != nil {
-					spinner.LogMessage(err.Error(), "warn")
-				}
+func (cCompiler) ToolchainCommands(bc *utils.BuildContext) [][]string {
+	return [][]string{{"make", "--version"}, {"cc", "--version"}}
+}
 
-				spinner.LogMessage("Artifact(s) copied to output path provided", "info")
-			}
+func (cCompiler) Package(bc *utils.BuildContext) error {
+	var paths []string
 
-			// exec.Command("rm", fullPath+"/"+artifact).Run()
-			errRemove := os.Remove(fullPath + "/" + artifact)
-			if errRemove != nil {
-				spinner.LogMessage(errRemove.Error(), "warn")
-			}
+	// If we were given an artifacts list, handle it
+	if bc.Config.ArtifactList != "" {
+		for _, name := range strings.Split(bc.Config.ArtifactList, ",") {
+			paths = append(paths, bc.BuildDir+"/"+name)
 		}
-
 	} else {
-		var artifactExt string
-		buildTool := strings.ToLower(os.Getenv("BUILDER_BUILD_TOOL"))
-		//Determine artifact extension
-		switch buildTool {
-		case "make-rpm":
-			artifactExt = "*.rpm"
-		case "make-deb":
-			artifactExt = "*.deb"
-		case "make-tar":
-			artifactExt = "*.tar.gz"
-		case "make-lib":
-			archiveExt = "*.lib"
-		case "make-dll":
-			archiveExt = "*.dll"
-		default:
-			artifactExt = "*.exe"
-		}
-
 		//find artifact(s) by extension
-		// WalkMatch function defined in compile/c#.go
-		artifactArray, _ = WalkMatch(fullPath, artifactExt)
-		if len(artifactArray) == 0 {
-			spinner.LogMessage("Could not find artifact(s).  Please specify the name(s) in the artifactlist of the builder.yaml", "fatal")
+		var err error
+		paths, err = WalkMatch(bc.BuildDir, cArtifactPattern(bc))
+		if err != nil {
+			return fmt.Errorf("could not search build dir for artifacts: %w", err)
 		}
-
-		var artifactNames []string
-
-		//copy artifact(s), then remove artifact(s) from workspace
-		for i := 0; i < len(artifactArray); i++ {
-			artifactNames = append(artifactNames, filepath.Base(artifactArray[i]))
-
-			err := cp.Copy(artifactArray[i], artifactDir+"/"+artifactNames[i])
-			if err != nil {
-				spinner.LogMessage(err.Error(), "warn")
-			}
-
-			// If outputpath provided also cp artifacts to that location
-			if outputPath != "" {
-				// Check if outputPath exists.  If not, create it
-				if _, err := os.Stat(outputPath); os.IsNotExist(err) {
-					if err := os.Mkdir(outputPath, 0755); err != nil {
-						spinner.LogMessage("Could not create output path", "fatal")
-					}
-				}
-
-				err := cp.Copy(artifactArray[i], outputPath+"/"+artifactNames[i])
-				if err != nil {
-					spinner.LogMessage(err.Error(), "warn")
-				}
-
-				spinner.LogMessage("Artifact(s) copied to output path provided", "info")
-			}
-
-			errRemove := os.Remove(artifactArray[i])
-			if errRemove != nil {
-				spinner.LogMessage(errRemove.Error(), "warn")
-			}
+		if len(paths) == 0 {
+			return errors.New("could not find artifact(s).  Please specify the name(s) in the artifactlist of the builder.yaml")
 		}
-
-		os.Setenv("BUILDER_ARTIFACT_NAMES", strings.Join([]string(artifactNames), ","))
 	}
 
-	//create metadata, then copy contents to zip dir
-	utils.Metadata(artifactDir)
-
-	if os.Getenv("ARTIFACT_ZIP_ENABLED") == "true" {
-		//zip artifact
-		artifact.ZipArtifactDir()
-
-		//remove uncompressed artifacts
-		for i := 0; i < len(artifactArray); i++ {
-			err := os.Remove(artifactDir + "/" + filepath.Base(artifactArray[i]))
-			if err != nil {
-				spinner.LogMessage(err.Error(), "warn")
-			}
-		}
-
-		// send artifact to user specified path or send to artifact directory
-		outputPath := os.Getenv("BUILDER_OUTPUT_PATH")
-		if outputPath != "" {
-			err := cp.Copy(artifactDir+archiveExt, outputPath+"/"+filepath.Base(artifactDir)+archiveExt)
-			if err != nil {
-				spinner.LogMessage(err.Error(), "warn")
-			}
-		} else {
-			err := cp.Copy(artifactDir+archiveExt, artifactDir+"/"+filepath.Base(artifactDir)+archiveExt)
-			if err != nil {
-				spinner.LogMessage(err.Error(), "warn")
-			}
-		}
+	return collectArtifacts(bc, paths)
+}
 
-		errRemove := os.Remove(artifactDir + archiveExt)
-		if errRemove != nil {
-			spinner.LogMessage(errRemove.Error(), "warn")
-		}
+// cArtifactPattern returns the pattern of the artifacts the buildtool produces
+func cArtifactPattern(bc *utils.BuildContext) string {
+	//Determine artifact extension
+	switch strings.ToLower(bc.Config.BuildTool) {
+	case "make-rpm":
+		return "*.rpm"
+	case "make-deb":
+		return "*.deb"
+	case "make-tar":
+		return "*.tar.gz"
+	case "make-lib":
+		return "*.lib"
+	case "make-dll":
+		return "*.dll"
+	default:
+		return "*.exe"
 	}
 }
@@ -0,0 +1,144 @@
+package compile
+
+import (
+	"Builder/directory"
+	"Builder/spinner"
+	"Builder/utils"
+	"Builder/yaml"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Compiler builds and packages one kind of project.  Each compile/*.go file
+// registers its compiler in init(), third-party compilers can do the same.
+type Compiler interface {
+	// ProjectType is the builder.yaml projecttype the compiler handles ("go", "java", etc)
+	ProjectType() string
+	// Detect searches dir for the project's build file and returns its path, or "" if not found
+	Detect(bc *utils.BuildContext, dir string) (string, error)
+	// Build prepares bc.BuildDir from the hidden dir and runs the build command(s) in it
+	Build(bc *utils.BuildContext, buildFile string) error
+	// Plan works out the build dir, the compiler's own commands and the artifacts without
+	// creating anything or running any commands, it fills in the config defaults Build would
+	Plan(bc *utils.BuildContext, buildFile string) (CompilerPlan, error)
+	// Package collects the built artifacts into the artifact dir
+	Package(bc *utils.BuildContext) error
+	// DefaultBuildCommand is the command run when no buildcmd is given in the builder.yaml
+	DefaultBuildCommand(bc *utils.BuildContext) []string
+	// DefaultTestCommand is the command run by the test stage when no testcmd is given in the
+	// builder.yaml, nil if the project type has no standard test command
+	DefaultTestCommand(bc *utils.BuildContext) []string
+	// ToolchainCommands are the commands that print the versions of the tools the build uses
+	ToolchainCommands(bc *utils.BuildContext) [][]string
+}
+
+type registration struct {
+	priority     int
+	projectTypes []string
+	compiler     Compiler
+}
+
+var registry []registration
+
+// Register adds a compiler to the registry.  When detecting a project type compilers are
+// tried from lowest to highest priority.  aliases are extra builder.yaml projecttype values
+// that select the compiler.
+func Register(priority int, compiler Compiler, aliases ...string) {
+	projectTypes := append([]string{compiler.ProjectType()}, aliases...)
+	for i := range projectTypes {
+		projectTypes[i] = strings.ToLower(projectTypes[i])
+	}
+
+	registry = append(registry, registration{priority: priority, projectTypes: projectTypes, compiler: compiler})
+
+	sort.SliceStable(registry, func(i, j int) bool {
+		return registry[i].priority < registry[j].priority
+	})
+}
+
+// Compilers returns the registered compilers in detection order
+func Compilers() []Compiler {
+	var compilers []Compiler
+	for _, r := range registry {
+		compilers = append(compilers, r.compiler)
+	}
+
+	return compilers
+}
+
+// Lookup returns the compiler registered for projectType
+func Lookup(projectType string) (Compiler, bool) {
+	projectType = strings.ToLower(projectType)
+	for _, r := range registry {
+		for _, t := range r.projectTypes {
+			if t == projectType {
+				return r.compiler, true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// Run builds and tests the project with compiler c, renames the parent dir to include the
+// start time, writes the default builder.yaml and packages the artifacts
+func Run(bc *utils.BuildContext, c Compiler, buildFile string) error {
+	//Set default project type for builder.yaml creation
+	if bc.Config.ProjectType == "" {
+		bc.Config.ProjectType = c.ProjectType()
+	}
+
+	//limit how long the build can run
+	if err := bc.StartTimeout(); err != nil {
+		return err
+	}
+
+	//Set up local logger
+	if err := bc.OpenLogger(); err != nil {
+		return err
+	}
+
+	bc.Step = "build"
+	buildErr := c.Build(bc, buildFile)
+
+	var testErr error
+	if buildErr == nil {
+		bc.Step = "test"
+		testErr = runTests(bc, c)
+	}
+
+	bc.EndTime = time.Now()
+
+	// Close log file
+	bc.CloseLogger()
+
+	if buildErr != nil {
+		return fmt.Errorf("%s build failed: %w", c.ProjectType(), buildErr)
+	}
+	if testErr != nil {
+		return fmt.Errorf("%s tests failed: %w", c.ProjectType(), testErr)
+	}
+
+	// Update parent dir name to include start time and send back new build path
+	buildDir, err := directory.UpdateParentDirName(bc, bc.BuildDir)
+	if err != nil {
+		return err
+	}
+	bc.BuildDir = buildDir
+
+	//creates default builder.yaml if it doesn't exist
+	if err := yaml.CreateBuilderYaml(bc.BuildDir, &bc.Config); err != nil {
+		return err
+	}
+
+	bc.Step = "package"
+	if err := c.Package(bc); err != nil {
+		return fmt.Errorf("%s packaging failed: %w", c.ProjectType(), err)
+	}
+
+	spinner.LogMessage(c.ProjectType()+" project built successfully.", "info")
+
+	return nil
+}
@@ -0,0 +1,114 @@
+package compile
+
+import (
+	"Builder/utils"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	cp "github.com/otiai10/copy"
+)
+
+// detectBuildFile looks for the first of files inside dir and returns its path, or "" if none
+// exist.  If a buildfile is given in the builder.yaml (only honored along with a projecttype)
+// it is searched for instead.
+func detectBuildFile(bc *utils.BuildContext, dir string, files ...string) (string, error) {
+	if bc.Config.ProjectType != "" && bc.Config.BuildFile != "" {
+		files = []string{bc.Config.BuildFile}
+	}
+
+	for _, file := range files {
+		filePath, err := findPath(dir, file)
+		if err != nil || filePath != "" {
+			return filePath, err
+		}
+	}
+
+	return "", nil
+}
+
+// takes in file, searches dirPath to find a match and returns path to file
+func findPath(dirPath string, file string) (string, error) {
+	// if f.Name is == to file passed in "coolProject.go", filePath becomes the path of that file
+	var filePath string
+	// Check top level dir for file first before checking subdirs
+	files, err := os.ReadDir(dirPath)
+	if err != nil {
+		return "", fmt.Errorf("could not read %s: %w", dirPath, err)
+	}
+
+	for _, f := range files {
+		if strings.EqualFold(f.Name(), file) {
+			filePath = filepath.Join(dirPath, f.Name())
+		}
+	}
+
+	// If file not found in top level dir check subdirs, the shallowest match wins
+	if filePath == "" {
+		err = filepath.Walk(dirPath, func(path string, f os.FileInfo, err error) error {
+			if err == nil && strings.EqualFold(f.Name(), file) {
+				if filePath == "" || pathDepth(path) < pathDepth(filePath) {
+					filePath = path
+				}
+			}
+			return err
+		})
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("could not search %s for %s: %w", dirPath, file, err)
+	}
+
+	return filePath, nil
+}
+
+// pathDepth returns the number of dirs in path
+func pathDepth(path string) int {
+	return strings.Count(filepath.ToSlash(path), "/")
+}
+
+// finds the first file in dirPath (top level only) with extension ext and returns its path
+func findExt(dirPath string, ext string) (string, error) {
+	files, err := os.ReadDir(dirPath)
+	if err != nil {
+		return "", fmt.Errorf("could not read %s: %w", dirPath, err)
+	}
+
+	for _, file := range files {
+		if file.Type().IsRegular() && filepath.Ext(file.Name()) == ext {
+			return filepath.Join(dirPath, file.Name()), nil
+		}
+	}
+
+	return "", nil
+}
+
+// workspaceBuildPath returns the dir in the workspace holding buildFile (a path inside the
+// hidden dir), for langs that produce a binary and get built in the workspace
+func workspaceBuildPath(bc *utils.BuildContext, buildFile string) string {
+	return bc.WorkspaceDir + strings.TrimPrefix(filepath.Dir(buildFile), bc.HiddenDir)
+}
+
+// tempBuildPath returns workspace/temp, where interpreted langs are built before getting
+// zipped up
+func tempBuildPath(bc *utils.BuildContext) string {
+	return bc.WorkspaceDir + "/temp/"
+}
+
+// copyToTemp copies the hidden dir into workspace/temp
+func copyToTemp(bc *utils.BuildContext) error {
+	tempWorkspace := tempBuildPath(bc)
+	//make temp dir
+	if err := os.Mkdir(tempWorkspace, 0755); err != nil && !os.IsExist(err) {
+		return fmt.Errorf("could not create temp dir: %w", err)
+	}
+
+	//add hidden dir contents to temp dir, install dependencies
+	err := cp.Copy(bc.HiddenDir+"/.", tempWorkspace)
+	if err != nil {
+		return fmt.Errorf("could not copy hidden dir into temp dir: %w", err)
+	}
+
+	return nil
+}
@@ -5,230 +5,379 @@ package compile
 
 import (
 	"Builder/artifact"
-	"Builder/directory"
 	"Builder/spinner"
 	"Builder/utils"
-	"Builder/utils/log"
-	"Builder/yaml"
-	"bufio"
+	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strings"
-	"time"
-
-	cp "github.com/otiai10/copy"
-	"go.uber.org/zap"
 )
 
-var locallogger *zap.Logger
+type goCompiler struct{}
 
-// Go creates exe from file passed in as arg
-func Go(filePath string) {
+func init() {
+	Register(10, goCompiler{})
+}
 
-	//Set default project type env for builder.yaml creation
-	projectType := os.Getenv("BUILDER_PROJECT_TYPE")
-	if projectType == "" {
-		os.Setenv("BUILDER_PROJECT_TYPE", "go")
-	}
+func (goCompiler) ProjectType() string {
+	return "go"
+}
 
-	//Set up local logger
-	localPath, _ := os.LookupEnv("BUILDER_LOGS_DIR")
-	locallogger, closeLocalLogger = log.NewLogger("logs", localPath)
+func (goCompiler) Detect(bc *utils.BuildContext, dir string) (string, error) {
+	if bc.Config.ProjectType != "" && bc.Config.BuildFile != "" {
+		return detectBuildFile(bc, dir)
+	}
 
-	//define dir path for command to run in
-	var fullPath string
-	configPath := os.Getenv("BUILDER_DIR_PATH")
-	//if user defined path in builder.yaml, full path is included already, else add curren dir + local path
-	if os.Getenv("BUILDER_COMMAND") == "true" {
-		// ex: C:/Users/Name/Projects/helloworld_19293/workspace/dir
-		fullPath = filePath
-	} else if configPath != "" {
-		// ex: C:/Users/Name/Projects/helloworld_19293/workspace/dir
-		fullPath = filePath
-	} else {
-		path, _ := os.Getwd()
-		//combine local path to newly created tempWorkspace,
-		//gets rid of "." in path name
-		// ex: C:/Users/Name/Projects + /helloworld_19293/workspace/dir
-		fullPath = path + filePath[strings.Index(filePath, ".")+1:]
-		os.Setenv("BUILDER_DIR_PATH", path)
+	// only a go.mod at the root makes the repo a go module, a nested one may belong to a tool
+	// kept in a project of another language
+	modPath := filepath.Join(dir, "go.mod")
+	if _, err := os.Stat(modPath); err == nil {
+		return modPath, nil
 	}
 
-	//install dependencies/build, if yaml build type exists install accordingly
-	buildTool := strings.ToLower(os.Getenv("BUILDER_BUILD_TOOL"))
-	//find 'go file' to be built
-	buildFile := strings.ToLower(os.Getenv("BUILDER_BUILD_FILE"))
-	buildCmd := os.Getenv("BUILDER_BUILD_COMMAND")
-	//if no file defined by user, use default main.go
-	if buildFile == "" {
-		buildFile = "main.go"
-		os.Setenv("BUILDER_BUILD_FILE", buildFile)
-	}
-
-	//buildName = buildfile (get rid of ".go") + Unix timestamp
-	var cmd *exec.Cmd
-	if buildCmd != "" {
-		//user specified cmd
-		buildCmdArray := strings.Fields(buildCmd)
-		cmd = exec.Command(buildCmdArray[0], buildCmdArray[1:]...)
-		cmd.Dir = fullPath // or whatever directory it's in
-	} else if buildTool == "go" {
-		cmd = exec.Command("go", "build", "-v", "-x", buildFile)
-		cmd.Dir = fullPath // or whatever directory it's in
-		os.Setenv("BUILDER_BUILD_COMMAND", "go build -v -x "+buildFile)
-	} else {
-		//default
-		if runtime.GOOS != "windows" {
-			cmd = exec.Command("go", "build", "-v", "-x", "-o", strings.TrimSuffix(utils.GetName(), ".git"))
-			cmd.Dir = fullPath // or whatever directory it's in
-			os.Setenv("BUILDER_BUILD_COMMAND", "go build -v -x -o "+strings.TrimSuffix(utils.GetName(), ".git"))
-		} else {
-			cmd = exec.Command("go", "build", "-v", "-x", "-o", strings.TrimSuffix(utils.GetName(), ".git")+".exe")
-			cmd.Dir = fullPath // or whatever directory it's in
-			os.Setenv("BUILDER_BUILD_COMMAND", "go build -v -x -o "+strings.TrimSuffix(utils.GetName(), ".git")+".exe")
+	return findGoMain(dir)
+}
+
+// findGoMain returns the shallowest main.go in dir that isn't part of a nested module, or ""
+func findGoMain(dir string) (string, error) {
+	var mainPath string
+	err := filepath.Walk(dir, func(path string, f os.FileInfo, err error) error {
+		if err != nil {
+			return err
 		}
+		if f.IsDir() {
+			if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil && path != dir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.EqualFold(f.Name(), "main.go") && (mainPath == "" || pathDepth(path) < pathDepth(mainPath)) {
+			mainPath = path
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("could not search %s for main.go: %w", dir, err)
 	}
 
-	//run cmd, check for err, log cmd
-	spinner.LogMessage("running command: "+cmd.String(), "info")
+	return mainPath, nil
+}
 
-	stdout, pipeErr := cmd.StdoutPipe()
-	if pipeErr != nil {
-		spinner.LogMessage(pipeErr.Error(), "fatal")
+// Build creates exe from file passed in as arg
+func (c goCompiler) Build(bc *utils.BuildContext, buildFile string) error {
+	plan, err := c.Plan(bc, buildFile)
+	if err != nil {
+		return err
 	}
 
-	cmd.Stderr = cmd.Stdout
+	if err := utils.CopyDir(bc); err != nil {
+		return err
+	}
+	bc.BuildDir = plan.BuildDir
 
-	// Make a new channel which will be used to ensure we get all output
-	done := make(chan struct{})
+	if goStampSkipped(bc) {
+		spinner.LogMessage(goStampWarning, "warn")
+	}
+	if goTargetsCollide(bc) {
+		spinner.LogMessage(goTargetsWarning, "warn")
+	}
 
-	scanner := bufio.NewScanner(stdout)
+	return runBuildCommand(bc, c)
+}
 
-	// Use the scanner to scan the output line by line and log it
-	// It's running in a goroutine so that it doesn't block
-	go func() {
-		// Read line by line and process it
-		for scanner.Scan() {
-			line := scanner.Text()
-			spinner.Spinner.Stop()
-			locallogger.Info(line)
-			spinner.Spinner.Start()
+func (goCompiler) Plan(bc *utils.BuildContext, buildFile string) (CompilerPlan, error) {
+	//if no file defined by user, use the detected go.mod or default main.go
+	if bc.Config.BuildFile == "" {
+		bc.Config.BuildFile = "main.go"
+		if isGoModFile(buildFile) {
+			bc.Config.BuildFile = "go.mod"
 		}
+	}
 
-		// We're all done, unblock the channel
-		done <- struct{}{}
+	plan := CompilerPlan{BuildDir: workspaceBuildPath(bc, buildFile)}
+	if goStampSkipped(bc) {
+		plan.Notes = append(plan.Notes, goStampWarning)
+	}
+	if goTargetsCollide(bc) {
+		plan.Notes = append(plan.Notes, goTargetsWarning)
+	}
+	if !isGoModFile(bc.Config.BuildFile) {
+		// Package collects the executable named after the project
+		name := strings.TrimSuffix(utils.GetName(bc), ".git")
+		for _, target := range bc.Config.Targets {
+			plan.Artifacts = append(plan.Artifacts, targetArtifactName(name, target))
+		}
+		if len(bc.Config.Targets) == 0 {
+			artifact := name
+			if runtime.GOOS == "windows" {
+				artifact = "*.exe"
+			}
+			plan.Artifacts = []string{artifact}
+		}
+		return plan, nil
+	}
 
-	}()
+	// the main packages can differ by target, a package may only build for some platforms
+	targets := bc.Config.Targets
+	if len(targets) == 0 {
+		targets = []string{""}
+	}
+	var importPaths []string
+	pkgTargets := map[string][]string{}
+	for _, target := range targets {
+		packages, err := goMainPackages(filepath.Dir(buildFile), bc.Config.GoPackages, target)
+		if err != nil {
+			return plan, err
+		}
+		if len(packages) == 0 && len(bc.Config.BuildCmd) == 0 && len(bc.Config.Steps) == 0 {
+			module, _ := readGoModule(buildFile)
+			if target != "" {
+				return plan, fmt.Errorf("go module %s has no main package to build for %s, list them in gopackages or set a buildcmd", module, target)
+			}
+			return plan, fmt.Errorf("go module %s has no main package to build, list them in gopackages or set a buildcmd", module)
+		}
+		for _, pkg := range packages {
+			if target == "" {
+				plan.Artifacts = append(plan.Artifacts, goBinDir+"/"+pkg.Binary(""))
+				plan.Notes = append(plan.Notes, "builds "+pkg.ImportPath+" into "+goBinDir+"/"+pkg.Binary(""))
+				continue
+			}
+			plan.Artifacts = append(plan.Artifacts, goTargetDir(target)+"/"+targetArtifactName(pkg.Binary(target), target))
+			if _, ok := pkgTargets[pkg.ImportPath]; !ok {
+				importPaths = append(importPaths, pkg.ImportPath)
+			}
+			pkgTargets[pkg.ImportPath] = append(pkgTargets[pkg.ImportPath], target)
+		}
+	}
+	for _, importPath := range importPaths {
+		plan.Notes = append(plan.Notes, "builds "+importPath+" for "+strings.Join(pkgTargets[importPath], ", "))
+	}
+	if bc.Config.ArtifactList != "" {
+		for _, name := range strings.Split(bc.Config.ArtifactList, ",") {
+			plan.Artifacts = append(plan.Artifacts, strings.TrimSpace(name))
+		}
+	}
 
-	os.Setenv("BUILD_START_TIME", time.Now().Format(time.RFC850))
+	return plan, nil
+}
 
-	if err := cmd.Start(); err != nil {
-		spinner.LogMessage(err.Error(), "fatal")
+func (goCompiler) DefaultBuildCommand(bc *utils.BuildContext) []string {
+	//install dependencies/build, if yaml build type exists install accordingly
+	buildTool := strings.ToLower(bc.Config.BuildTool)
+	//find 'go file' to be built
+	buildFile := strings.ToLower(bc.Config.BuildFile)
+
+	if isGoModFile(buildFile) {
+		// the module is read from the hidden dir the build dir is copied from, Plan has already
+		// failed the build if it has no main package
+		hiddenBuildDir := bc.HiddenDir + strings.TrimPrefix(bc.BuildDir, bc.WorkspaceDir)
+		packages, _ := goMainPackages(hiddenBuildDir, bc.Config.GoPackages, bc.Target)
+
+		// with -o ending in a separator go build writes each binary into that dir
+		outDir := goBinDir
+		if bc.Target != "" {
+			outDir = goTargetDir(bc.Target)
+		}
+		args := append(append([]string{"go", "build", "-v"}, goStampFlags(bc)...), "-o", outDir+"/")
+		for _, pkg := range packages {
+			args = append(args, pkg.Target())
+		}
+		return args
 	}
 
-	// Wait for all output to be processed
-	<-done
+	//the target's GOOS and GOARCH are in the environment, the binary gets its suffix
+	if bc.Target != "" {
+		name := strings.TrimSuffix(utils.GetName(bc), ".git")
+		return append(append([]string{"go", "build", "-v"}, goStampFlags(bc)...), "-o", targetArtifactName(name, bc.Target))
+	}
 
-	// Wait for cmd to finish
-	if err := cmd.Wait(); err != nil {
-		spinner.LogMessage(err.Error(), "fatal")
+	if buildTool == "go" {
+		return append(append([]string{"go", "build", "-v", "-x"}, goStampFlags(bc)...), buildFile)
 	}
 
-	os.Setenv("BUILD_END_TIME", time.Now().Format(time.RFC850))
+	//default
+	name := strings.TrimSuffix(utils.GetName(bc), ".git")
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
 
-	// Close log file
-	closeLocalLogger()
+	return append(append([]string{"go", "build", "-v", "-x"}, goStampFlags(bc)...), "-o", name)
+}
 
-	// Update parent dir name to include start time
-	fullPath = directory.UpdateParentDirName(fullPath)
+// TargetEnv returns GOOS and GOARCH for a GOOS/GOARCH target
+func (goCompiler) TargetEnv(target string) ([]string, error) {
+	goos, goarch, err := splitGoTarget(target)
+	if err != nil {
+		return nil, err
+	}
 
-	yaml.CreateBuilderYaml(fullPath)
+	return []string{"GOOS=" + goos, "GOARCH=" + goarch}, nil
+}
 
-	packageGoArtifact(fullPath)
+func (goCompiler) DefaultTestCommand(bc *utils.BuildContext) []string {
+	return []string{"go", "test", "-json", "./..."}
+}
 
-	spinner.LogMessage("Go project built successfully.", "info")
+func (goCompiler) ToolchainCommands(bc *utils.BuildContext) [][]string {
+	return [][]string{{"go", "version"}}
 }
 
-func packageGoArtifact(fullPath string) {
-	archiveExt := ""
+func (goCompiler) Package(bc *utils.BuildContext) error {
+	if isGoModFile(bc.Config.BuildFile) {
+		return packageGoModule(bc)
+	}
+
+	var paths []string
+	if len(bc.Config.Targets) > 0 {
+		name := strings.TrimSuffix(utils.GetName(bc), ".git")
+		for _, target := range bc.Config.Targets {
+			path := filepath.Join(bc.BuildDir, targetArtifactName(name, target))
+			if _, err := os.Stat(path); err != nil {
+				spinner.LogMessage(fmt.Sprintf("Could not find %s built for target %s", filepath.Base(path), target), "warn")
+				continue
+			}
+			bc.SetArtifactInfo(path, utils.ArtifactInfo{Target: target})
+			paths = append(paths, path)
+		}
+		return collectArtifacts(bc, paths)
+	}
+
 	artifactExt := ""
 
 	if runtime.GOOS == "windows" {
-		archiveExt = ".zip"
 		artifactExt = ".exe"
 	} else {
-		archiveExt = ".tar.gz"
 		artifactExt = "executable"
 	}
 
-	artifact.ArtifactDir()
-	artifactDir := os.Getenv("BUILDER_ARTIFACT_DIR")
-	outputPath := os.Getenv("BUILDER_OUTPUT_PATH")
-
 	//find artifact by extension
-	_, extName := artifact.ExtExistsFunction(fullPath, artifactExt)
-	os.Setenv("BUILDER_ARTIFACT_NAMES", extName)
-
-	//copy artifact, then remove artifact in workspace
-	err := cp.Copy(fullPath+"/"+extName, artifactDir+"/"+extName)
+	found, extName, err := artifact.ExtExistsFunction(bc, bc.BuildDir, artifactExt)
 	if err != nil {
-		spinner.LogMessage(err.Error(), "warn")
+		return err
+	}
+	if found {
+		paths = append(paths, bc.BuildDir+"/"+extName)
 	}
 
-	// If outputpath provided also cp artifacts to that location
-	if outputPath != "" {
-		// Check if outputPath exists.  If not, create it
-		if _, err := os.Stat(outputPath); os.IsNotExist(err) {
-			if err := os.Mkdir(outputPath, 0755); err != nil {
-				spinner.LogMessage("Could not create output path", "fatal")
+	return collectArtifacts(bc, paths)
+}
+
+// packageGoModule collects the binary of every main package of the module, from the bin dir
+// the default build command writes them to or else the build dir, and the artifactlist files.
+// With targets each binary is collected from the target's dir under bin with the target's suffix.
+func packageGoModule(bc *utils.BuildContext) error {
+	var paths []string
+	for _, target := range bc.Config.Targets {
+		packages, err := goMainPackages(bc.BuildDir, bc.Config.GoPackages, target)
+		if err != nil {
+			return err
+		}
+		for _, pkg := range packages {
+			info := utils.ArtifactInfo{ImportPath: pkg.ImportPath}
+			path, err := renameTargetArtifact(bc, filepath.Join(bc.BuildDir, goTargetDir(target), pkg.Binary(target)), target, info)
+			if err != nil {
+				return err
+			}
+			if path != "" {
+				paths = append(paths, path)
 			}
 		}
+	}
 
-		err := cp.Copy(fullPath+"/"+extName, outputPath+"/"+extName)
+	if len(bc.Config.Targets) == 0 {
+		packages, err := goMainPackages(bc.BuildDir, bc.Config.GoPackages, "")
 		if err != nil {
-			spinner.LogMessage(err.Error(), "warn")
+			return err
+		}
+		for _, pkg := range packages {
+			path := findGoBinary(bc, pkg)
+			if path == "" {
+				spinner.LogMessage(fmt.Sprintf("Could not find the binary of %s (%s)", pkg.ImportPath, pkg.Binary("")), "warn")
+				continue
+			}
+			bc.SetArtifactInfo(path, utils.ArtifactInfo{ImportPath: pkg.ImportPath})
+			paths = append(paths, path)
 		}
+	}
 
-		spinner.LogMessage("Artifact(s) copied to output path provided", "info")
+	for _, name := range strings.Split(bc.Config.ArtifactList, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(bc.BuildDir, name))
+		if err != nil {
+			return fmt.Errorf("bad artifactlist pattern %s: %w", name, err)
+		}
+		paths = append(paths, matches...)
 	}
 
-	errRemove := os.Remove(fullPath + "/" + extName)
-	if errRemove != nil {
-		spinner.LogMessage(errRemove.Error(), "warn")
+	return collectArtifacts(bc, paths)
+}
+
+// findGoBinary returns the path of pkg's binary in the bin dir or else the build dir, or ""
+func findGoBinary(bc *utils.BuildContext, pkg goPackage) string {
+	for _, dir := range []string{filepath.Join(bc.BuildDir, goBinDir), bc.BuildDir} {
+		path := filepath.Join(dir, pkg.Binary(""))
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			return path
+		}
 	}
 
-	//create metadata
-	utils.Metadata(artifactDir)
+	return ""
+}
 
-	if os.Getenv("ARTIFACT_ZIP_ENABLED") == "true" {
-		//zip artifact
-		artifact.ZipArtifactDir()
+// goStampWarning is shown when the go variables can't be stamped because the build command
+// isn't Builder's
+const goStampWarning = "stamp: -ldflags -X is only added to the default build command, a buildcmd or steps only get the build info in the BUILDER_* variables"
 
-		//remove uncompressed artifact
-		err := os.Remove(artifactDir + "/" + extName)
-		if err != nil {
-			spinner.LogMessage(err.Error(), "warn")
-		}
+// goStampSkipped reports whether stamp is on but a buildcmd or steps replace the default
+// build command the -ldflags are added to
+func goStampSkipped(bc *utils.BuildContext) bool {
+	return bc.Config.Stamp && (len(bc.Config.BuildCmd) > 0 || len(bc.Config.Steps) > 0)
+}
 
-		// send artifact to user specified path or send to artifact directory
-		outputPath := os.Getenv("BUILDER_OUTPUT_PATH")
-		if outputPath != "" {
-			err := cp.Copy(artifactDir+archiveExt, outputPath+"/"+filepath.Base(artifactDir)+archiveExt)
-			if err != nil {
-				spinner.LogMessage(err.Error(), "warn")
-			}
-		} else {
-			err := cp.Copy(artifactDir+archiveExt, artifactDir+"/"+filepath.Base(artifactDir)+archiveExt)
-			if err != nil {
-				spinner.LogMessage(err.Error(), "warn")
-			}
-		}
+// goTargetsWarning is shown when a buildcmd or steps build several targets without telling
+// them apart
+const goTargetsWarning = "targets: the buildcmd or steps don't use $GOOS or $GOARCH, so every target's build writes the same file, name the output after them (-o bin/$GOOS-$GOARCH/)"
 
-		errRemove := os.Remove(artifactDir + archiveExt)
-		if errRemove != nil {
-			spinner.LogMessage(errRemove.Error(), "warn")
+// goTargetsCollide reports whether a buildcmd or steps build more than one target with
+// commands that never mention GOOS or GOARCH
+func goTargetsCollide(bc *utils.BuildContext) bool {
+	if len(bc.Config.Targets) < 2 {
+		return false
+	}
+
+	commands := append([]string{}, bc.Config.BuildCmd...)
+	for _, step := range bc.Config.Steps {
+		commands = append(commands, step.Command...)
+	}
+	for _, command := range commands {
+		if strings.Contains(command, "GOOS") || strings.Contains(command, "GOARCH") {
+			return false
 		}
 	}
+
+	return len(commands) > 0
+}
+
+// isGoModFile reports whether buildFile is a go.mod, the project is then built as a module
+func isGoModFile(buildFile string) bool {
+	return strings.EqualFold(filepath.Base(buildFile), "go.mod")
+}
+
+// goTargetDir returns the dir in the build dir the binaries of a module are built into for
+// target, bin/linux-amd64 for linux/amd64
+func goTargetDir(target string) string {
+	return goBinDir + "/" + targetSuffix(target)
+}
+
+// splitGoTarget splits a GOOS/GOARCH target
+func splitGoTarget(target string) (string, string, error) {
+	parts := strings.Split(strings.TrimSpace(target), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("go target %q is not GOOS/GOARCH (linux/amd64, windows/arm64, ...)", target)
+	}
+
+	return parts[0], parts[1], nil
 }
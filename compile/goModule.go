@@ -0,0 +1,204 @@
+package compile
+
+import (
+	"bufio"
+	"fmt"
+	"go/build"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// goBinDir is the dir in the build dir the binaries of a go module are built into
+const goBinDir = "bin"
+
+var goMajorVersion = regexp.MustCompile(`^v[0-9]+$`)
+
+// goPackage is one main package of a go module
+type goPackage struct {
+	// Dir is the package's dir relative to the module root, "." for the root
+	Dir        string
+	ImportPath string
+}
+
+// Binary returns the name go build gives the package's binary when built for target ("" for
+// the platform Builder runs on), the last element of its import path that isn't a major
+// version suffix
+func (p goPackage) Binary(target string) string {
+	name := path.Base(p.ImportPath)
+	if goMajorVersion.MatchString(name) && strings.Contains(p.ImportPath, "/") {
+		name = path.Base(path.Dir(p.ImportPath))
+	}
+
+	return exeName(name, target)
+}
+
+// Target returns the package as a go build argument
+func (p goPackage) Target() string {
+	if p.Dir == "." {
+		return "."
+	}
+	return "./" + p.Dir
+}
+
+// readGoModule returns the module path declared in the go.mod at modPath
+func readGoModule(modPath string) (string, error) {
+	file, err := os.Open(modPath)
+	if err != nil {
+		return "", fmt.Errorf("could not read %s: %w", modPath, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "module" {
+			return strings.Trim(fields[1], "\"`"), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("could not read %s: %w", modPath, err)
+	}
+
+	return "", fmt.Errorf("%s has no module line", modPath)
+}
+
+// goMainPackages returns the main packages of the go module in moduleDir built for target
+// ("" for the platform Builder runs on), the ones listed in configured (comma separated dirs
+// or import paths of the module) or else every dir holding a package main for target.  vendor,
+// testdata, nested modules and dirs starting with . or _ are left out, like go does.  Two
+// packages whose binaries would have the same name are an error, go build would write one
+// over the other.
+func goMainPackages(moduleDir, configured, target string) ([]goPackage, error) {
+	modulePath, err := readGoModule(filepath.Join(moduleDir, "go.mod"))
+	if err != nil {
+		return nil, err
+	}
+
+	packages, err := findGoMainPackages(moduleDir, modulePath, configured, target)
+	if err != nil {
+		return nil, err
+	}
+
+	binaries := map[string]goPackage{}
+	for _, pkg := range packages {
+		if other, ok := binaries[pkg.Binary(target)]; ok {
+			return nil, fmt.Errorf("main packages %s and %s both build a binary named %s, list the ones to build in gopackages or set a buildcmd", other.ImportPath, pkg.ImportPath, pkg.Binary(target))
+		}
+		binaries[pkg.Binary(target)] = pkg
+	}
+
+	return packages, nil
+}
+
+// findGoMainPackages does the work of goMainPackages for the module modulePath
+func findGoMainPackages(moduleDir, modulePath, configured, target string) ([]goPackage, error) {
+
+	var packages []goPackage
+	newPackage := func(dir string) goPackage {
+		importPath := modulePath
+		if dir != "." {
+			importPath += "/" + dir
+		}
+		return goPackage{Dir: dir, ImportPath: importPath}
+	}
+
+	if configured != "" {
+		for _, entry := range strings.Split(configured, ",") {
+			entry = filepath.ToSlash(strings.TrimSpace(entry))
+			switch {
+			case entry == "":
+				continue
+			case entry == modulePath:
+				entry = "."
+			case strings.HasPrefix(entry, modulePath+"/"):
+				entry = strings.TrimPrefix(entry, modulePath+"/")
+			}
+
+			// anything else has to be a dir of the module, an import path of another module
+			// would be taken for a dir that isn't there
+			dir := path.Clean(entry)
+			if path.IsAbs(dir) || dir == ".." || strings.HasPrefix(dir, "../") {
+				return nil, fmt.Errorf("gopackages: %s is outside of module %s", entry, modulePath)
+			}
+			if info, err := os.Stat(filepath.Join(moduleDir, filepath.FromSlash(dir))); err != nil || !info.IsDir() {
+				return nil, fmt.Errorf("gopackages: %s is neither a dir of module %s nor one of its import paths", entry, modulePath)
+			}
+			packages = append(packages, newPackage(dir))
+		}
+		return packages, nil
+	}
+
+	ctx, err := goBuildContext(target)
+	if err != nil {
+		return nil, err
+	}
+
+	err = filepath.Walk(moduleDir, func(dir string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+
+		if dir != moduleDir {
+			name := info.Name()
+			if name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+				return filepath.SkipDir
+			}
+		}
+
+		// build constraints are honored, so generators tagged ignore and packages for other
+		// platforms aren't taken for a binary
+		pkg, err := ctx.ImportDir(dir, 0)
+		if err != nil || pkg.Name != "main" {
+			return nil
+		}
+
+		rel, err := filepath.Rel(moduleDir, dir)
+		if err != nil {
+			return err
+		}
+		packages = append(packages, newPackage(filepath.ToSlash(rel)))
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not search %s for main packages: %w", moduleDir, err)
+	}
+	sort.Slice(packages, func(i, j int) bool { return packages[i].Dir < packages[j].Dir })
+
+	return packages, nil
+}
+
+// goBuildContext returns the build context packages are matched against for target, "" for
+// the platform Builder runs on.  cgo is off for other platforms, like go build has it.
+func goBuildContext(target string) (build.Context, error) {
+	ctx := build.Default
+	if target == "" {
+		return ctx, nil
+	}
+
+	goos, goarch, err := splitGoTarget(target)
+	if err != nil {
+		return ctx, err
+	}
+	if goos != ctx.GOOS || goarch != ctx.GOARCH {
+		ctx.CgoEnabled = false
+	}
+	ctx.GOOS, ctx.GOARCH = goos, goarch
+
+	return ctx, nil
+}
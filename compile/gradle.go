@@ -0,0 +1,265 @@
+package compile
+
+import (
+	"Builder/spinner"
+	"Builder/utils"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// gradleBuildFiles are searched for in order, the settings file of a multi-project build comes
+// before the build files of its subprojects
+var gradleBuildFiles = []string{"settings.gradle.kts", "settings.gradle", "build.gradle.kts", "build.gradle"}
+
+// gradleSkippedJars are the jars in build/libs that aren't the project's artifact
+var gradleSkippedJars = []string{"-sources.jar", "-javadoc.jar", "-plain.jar", "-test-fixtures.jar"}
+
+type gradleCompiler struct{}
+
+func init() {
+	Register(45, gradleCompiler{}, "kotlin")
+}
+
+func (gradleCompiler) ProjectType() string {
+	return "gradle"
+}
+
+func (gradleCompiler) Detect(bc *utils.BuildContext, dir string) (string, error) {
+	return detectBuildFile(bc, dir, gradleBuildFiles...)
+}
+
+// Build runs the gradle task(s) on the project, with the gradle wrapper if it has one
+func (c gradleCompiler) Build(bc *utils.BuildContext, buildFile string) error {
+	plan, err := c.Plan(bc, buildFile)
+	if err != nil {
+		return err
+	}
+
+	if err := utils.CopyDir(bc); err != nil {
+		return err
+	}
+	bc.BuildDir = plan.BuildDir
+
+	if bc.Config.Stamp {
+		dirs, err := gradleProjectDirs(bc.BuildDir)
+		if err != nil {
+			return err
+		}
+		if err := stampJavaResources(bc, dirs); err != nil {
+			return err
+		}
+	}
+
+	return runBuildCommand(bc, c)
+}
+
+func (gradleCompiler) Plan(bc *utils.BuildContext, buildFile string) (CompilerPlan, error) {
+	if bc.Config.BuildTool == "" {
+		bc.Config.BuildTool = "gradle"
+	}
+	if bc.Config.GradleTask == "" {
+		bc.Config.GradleTask = "build"
+	}
+
+	plan := CompilerPlan{BuildDir: workspaceBuildPath(bc, buildFile)}
+	if bc.Config.ArtifactList != "" {
+		for _, name := range strings.Split(bc.Config.ArtifactList, ",") {
+			plan.Artifacts = append(plan.Artifacts, "**/build/libs/"+strings.TrimSpace(name))
+		}
+	} else {
+		plan.Artifacts = []string{"**/build/libs/*.jar", "**/build/libs/*.war"}
+		plan.Notes = append(plan.Notes, "the "+strings.Join(gradleSkippedJars, ", ")+" jars in build/libs aren't collected")
+	}
+
+	if wrapper := gradleWrapper(filepath.Dir(buildFile)); wrapper == "" {
+		plan.Notes = append(plan.Notes, "the project has no gradle wrapper, the gradle on the PATH is used")
+	}
+
+	return plan, nil
+}
+
+func (gradleCompiler) DefaultBuildCommand(bc *utils.BuildContext) []string {
+	return gradleCommand(bc, strings.Fields(bc.Config.GradleTask)...)
+}
+
+func (gradleCompiler) DefaultTestCommand(bc *utils.BuildContext) []string {
+	return gradleCommand(bc, "test")
+}
+
+func (c gradleCompiler) ToolchainCommands(bc *utils.BuildContext) [][]string {
+	gradle := "gradle"
+	if buildFile, err := c.Detect(bc, bc.HiddenDir); err == nil && buildFile != "" {
+		if wrapper := gradleWrapper(filepath.Dir(buildFile)); wrapper != "" {
+			gradle = filepath.Join(filepath.Dir(buildFile), wrapper)
+		}
+	}
+
+	return [][]string{{gradle, "--version"}, {"java", "-version"}}
+}
+
+// Package collects the jars and wars in the build/libs dirs of the project and its subprojects
+func (gradleCompiler) Package(bc *utils.BuildContext) error {
+	var names []string
+	for _, name := range strings.Split(bc.Config.ArtifactList, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+
+	var paths []string
+	err := filepath.Walk(bc.BuildDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			// gradle's own caches and the build logic aren't the project's artifacts
+			if info.Name() == ".gradle" || info.Name() == "buildSrc" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		libsDir := filepath.Dir(path)
+		if filepath.Base(libsDir) != "libs" || filepath.Base(filepath.Dir(libsDir)) != "build" {
+			return nil
+		}
+
+		if len(names) > 0 {
+			for _, name := range names {
+				if matched, _ := filepath.Match(name, info.Name()); matched {
+					paths = append(paths, path)
+					break
+				}
+			}
+			return nil
+		}
+
+		if ext := filepath.Ext(info.Name()); ext != ".jar" && ext != ".war" {
+			return nil
+		}
+		for _, suffix := range gradleSkippedJars {
+			if strings.HasSuffix(info.Name(), suffix) {
+				return nil
+			}
+		}
+		paths = append(paths, path)
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("could not search %s for artifacts: %w", bc.BuildDir, err)
+	}
+	sort.Strings(paths)
+
+	paths, err = renameCollidingJars(bc.BuildDir, paths)
+	if err != nil {
+		return err
+	}
+
+	return collectArtifacts(bc, paths)
+}
+
+// renameCollidingJars prefixes the jars and wars of subprojects that have the same file name as
+// another one with the subproject's path (core/api/build/libs/app.jar becomes core-api-app.jar),
+// they'd overwrite each other in the artifact dir.  The root project's keep their name.
+func renameCollidingJars(buildDir string, paths []string) ([]string, error) {
+	count := map[string]int{}
+	for _, path := range paths {
+		count[filepath.Base(path)]++
+	}
+
+	renamed := make([]string, 0, len(paths))
+	for _, path := range paths {
+		name := filepath.Base(path)
+		libsDir := filepath.Dir(path)
+		project, err := filepath.Rel(buildDir, filepath.Dir(filepath.Dir(libsDir)))
+		if count[name] < 2 || err != nil || project == "." {
+			renamed = append(renamed, path)
+			continue
+		}
+
+		prefixed := filepath.Join(libsDir, strings.ReplaceAll(filepath.ToSlash(project), "/", "-")+"-"+name)
+		if err := os.Rename(path, prefixed); err != nil {
+			return nil, fmt.Errorf("could not rename %s of %s: %w", name, project, err)
+		}
+		spinner.LogMessage(fmt.Sprintf("%s is built by more than one project, the one of %s is collected as %s", name, project, filepath.Base(prefixed)), "warn")
+		renamed = append(renamed, prefixed)
+	}
+
+	return renamed, nil
+}
+
+// gradleProjectDirs returns the dirs of the project and its subprojects that have a src/main,
+// relative to buildDir
+func gradleProjectDirs(buildDir string) ([]string, error) {
+	var dirs []string
+	err := filepath.Walk(buildDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if name := info.Name(); path != buildDir && (name == ".gradle" || name == "buildSrc" || name == "build" || name == "src" || strings.HasPrefix(name, ".")) {
+			return filepath.SkipDir
+		}
+
+		if info, err := os.Stat(filepath.Join(path, "src", "main")); err == nil && info.IsDir() {
+			rel, err := filepath.Rel(buildDir, path)
+			if err != nil {
+				return err
+			}
+			dirs = append(dirs, filepath.ToSlash(rel))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not search %s for gradle projects: %w", buildDir, err)
+	}
+
+	return dirs, nil
+}
+
+// gradleCommand returns the gradle command that runs tasks, with the wrapper if the project
+// has one
+func gradleCommand(bc *utils.BuildContext, tasks ...string) []string {
+	// the build dir is in the workspace, the wrapper is looked for in the hidden dir it's copied from
+	hiddenBuildDir := bc.HiddenDir + strings.TrimPrefix(bc.BuildDir, bc.WorkspaceDir)
+
+	args := []string{"gradle"}
+	switch wrapper := gradleWrapper(hiddenBuildDir); {
+	case wrapper == "":
+	case runtime.GOOS == "windows":
+		args = []string{".\\" + wrapper}
+	case isExecutable(filepath.Join(hiddenBuildDir, wrapper)):
+		args = []string{"./" + wrapper}
+	default:
+		args = []string{"sh", wrapper}
+	}
+
+	return append(append(args, tasks...), "--no-daemon", "--console=plain")
+}
+
+// gradleWrapper returns the name of the gradle wrapper script in dir, or "" if there isn't one
+func gradleWrapper(dir string) string {
+	wrapper := "gradlew"
+	if runtime.GOOS == "windows" {
+		wrapper = "gradlew.bat"
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, wrapper)); err != nil {
+		return ""
+	}
+
+	return wrapper
+}
+
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode()&0111 != 0
+}
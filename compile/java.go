@@ -1,216 +1,153 @@
 package compile
 
 import (
-	"Builder/artifact"
-	"Builder/directory"
 	"Builder/spinner"
 	"Builder/utils"
-	"Builder/utils/log"
-	"Builder/yaml"
-	"bufio"
-	"fmt"
-	"os"
-	"os/exec"
+	"path"
 	"path/filepath"
-	"runtime"
 	"strings"
-	"time"
-
-	cp "github.com/otiai10/copy"
 )
 
-// Java does ...
-func Java(filePath string) {
-	//Set default project type env for builder.yaml creation
-	projectType := os.Getenv("BUILDER_PROJECT_TYPE")
-	if projectType == "" {
-		os.Setenv("BUILDER_PROJECT_TYPE", "java")
-	}
-
-	//Set up local logger
-	localPath, _ := os.LookupEnv("BUILDER_LOGS_DIR")
-	locallogger, closeLocalLogger = log.NewLogger("logs", localPath)
-
-	//define dir path for command to run in
-	var fullPath string
-	configPath := os.Getenv("BUILDER_DIR_PATH")
-	//if user defined path in builder.yaml, full path is included already, else add curren dir + local path
-	if os.Getenv("BUILDER_COMMAND") == "true" {
-		// ex: C:/Users/Name/Projects/helloworld_19293/workspace/dir
-		fullPath = filePath
-	} else if configPath != "" {
-		// ex: C:/Users/Name/Projects/helloworld_19293/workspace/dir
-		fullPath = filePath
-	} else {
-		path, _ := os.Getwd()
-		//combine local path to newly created tempWorkspace,
-		//gets rid of "." in path name
-		// ex: C:/Users/Name/Projects + /helloworld_19293/workspace/dir
-		fullPath = path + filePath[strings.Index(filePath, ".")+1:]
-		os.Setenv("BUILDER_DIR_PATH", path)
+type javaCompiler struct{}
 
-	}
+func init() {
+	Register(40, javaCompiler{})
+}
 
-	//install dependencies/build, if yaml build type exists install accordingly
-	buildTool := strings.ToLower(os.Getenv("BUILDER_BUILD_TOOL"))
-	buildCmd := os.Getenv("BUILDER_BUILD_COMMAND")
-
-	var cmd *exec.Cmd
-	if buildCmd != "" {
-		//user specified cmd
-		buildCmdArray := strings.Fields(buildCmd)
-		cmd = exec.Command(buildCmdArray[0], buildCmdArray[1:]...)
-		cmd.Dir = fullPath // or whatever directory it's in
-	} else if buildTool == "maven" || buildTool == "mvn" {
-		fmt.Println(buildTool)
-		cmd = exec.Command("mvn", "clean", "install")
-		cmd.Dir = fullPath // or whatever directory it's in
-		os.Setenv("BUILDER_BUILD_COMMAND", "mvn clean install")
-	} else if buildTool == "gradle" {
-		// gradle, etc.
-	} else {
-		//default
-		cmd = exec.Command("mvn", "clean", "install")
-		cmd.Dir = fullPath // or whatever directory it's in
-		os.Setenv("BUILDER_BUILD_TOOL", "maven")
-		os.Setenv("BUILDER_BUILD_COMMAND", "mvn clean install")
-	}
+func (javaCompiler) ProjectType() string {
+	return "java"
+}
 
-	//run cmd, check for err, log cmd
-	spinner.LogMessage("running command: "+cmd.String(), "info")
+func (javaCompiler) Detect(bc *utils.BuildContext, dir string) (string, error) {
+	return detectBuildFile(bc, dir, "pom.xml")
+}
 
-	stdout, pipeErr := cmd.StdoutPipe()
-	if pipeErr != nil {
-		spinner.LogMessage(pipeErr.Error(), "fatal")
+// Build runs maven on the project
+func (c javaCompiler) Build(bc *utils.BuildContext, buildFile string) error {
+	plan, err := c.Plan(bc, buildFile)
+	if err != nil {
+		return err
 	}
 
-	cmd.Stderr = cmd.Stdout
-
-	// Make a new channel which will be used to ensure we get all output
-	done := make(chan struct{})
-
-	scanner := bufio.NewScanner(stdout)
+	if err := utils.CopyDir(bc); err != nil {
+		return err
+	}
+	bc.BuildDir = plan.BuildDir
 
-	// Use the scanner to scan the output line by line and log it
-	// It's running in a goroutine so that it doesn't block
-	go func() {
-		// Read line by line and process it
-		for scanner.Scan() {
-			line := scanner.Text()
-			spinner.Spinner.Stop()
-			locallogger.Info(line)
-			spinner.Spinner.Start()
+	if bc.Config.Stamp {
+		modules, err := readReactor(filepath.Join(bc.BuildDir, bc.Config.BuildFile))
+		if err != nil {
+			return err
+		}
+		var dirs []string
+		for _, module := range modules {
+			if ext := module.ArtifactExt(); ext == ".jar" || ext == ".war" {
+				dirs = append(dirs, module.Dir)
+			}
+		}
+		if err := stampJavaResources(bc, dirs); err != nil {
+			return err
 		}
-
-		// We're all done, unblock the channel
-		done <- struct{}{}
-
-	}()
-
-	os.Setenv("BUILD_START_TIME", time.Now().Format(time.RFC850))
-
-	if err := cmd.Start(); err != nil {
-		spinner.LogMessage(err.Error(), "fatal")
 	}
 
-	// Wait for all output to be processed
-	<-done
+	return runBuildCommand(bc, c)
+}
 
-	// Wait for cmd to finish
-	if err := cmd.Wait(); err != nil {
-		spinner.LogMessage(err.Error(), "fatal")
+func (javaCompiler) Plan(bc *utils.BuildContext, buildFile string) (CompilerPlan, error) {
+	if bc.Config.BuildTool == "" {
+		bc.Config.BuildTool = "maven"
+	}
+	//if no file defined by user, use default pom.xml
+	if bc.Config.BuildFile == "" {
+		bc.Config.BuildFile = "pom.xml"
 	}
 
-	os.Setenv("BUILD_END_TIME", time.Now().Format(time.RFC850))
-
-	// Close log file
-	closeLocalLogger()
-
-	// Update parent dir name to include start time and send back new full path
-	fullPath = directory.UpdateParentDirName(fullPath)
-
-	//creates default builder.yaml if it doesn't exist
-	yaml.CreateBuilderYaml(fullPath)
+	modules, err := readReactor(buildFile)
+	if err != nil {
+		return CompilerPlan{}, err
+	}
 
-	packageJavaArtifact(fullPath + "/target")
+	plan := CompilerPlan{BuildDir: workspaceBuildPath(bc, buildFile)}
+	for _, module := range modules {
+		for _, name := range javaArtifactNames(bc, module) {
+			plan.Artifacts = append(plan.Artifacts, path.Join(module.Dir, "target", name))
+		}
+		plan.Notes = append(plan.Notes, "module "+module.Dir+" is "+module.Coordinates()+" ("+module.Packaging+" packaging)")
+	}
 
-	spinner.LogMessage("Java project compiled successfully.", "info")
+	return plan, nil
 }
-func packageJavaArtifact(fullPath string) {
-	archiveExt := ""
 
-	if runtime.GOOS == "windows" {
-		archiveExt = ".zip"
-	} else {
-		archiveExt = ".tar.gz"
+func (javaCompiler) DefaultBuildCommand(bc *utils.BuildContext) []string {
+	// the test stage runs mvn test, the tests aren't run twice
+	if testsEnabled(bc) {
+		return []string{"mvn", "clean", "install", "-DskipTests"}
 	}
+	return []string{"mvn", "clean", "install"}
+}
 
-	artifact.ArtifactDir()
-	artifactDir := os.Getenv("BUILDER_ARTIFACT_DIR")
-	outputPath := os.Getenv("BUILDER_OUTPUT_PATH")
+func (javaCompiler) DefaultTestCommand(bc *utils.BuildContext) []string {
+	return []string{"mvn", "test"}
+}
 
-	//find artifact by extension
-	_, extName := artifact.ExtExistsFunction(fullPath, ".jar")
-	os.Setenv("BUILDER_ARTIFACT_NAMES", extName)
+func (javaCompiler) ToolchainCommands(bc *utils.BuildContext) [][]string {
+	return [][]string{{"mvn", "-v"}, {"java", "-version"}}
+}
 
-	//copy artifact, then remove artifact in workspace
-	err := cp.Copy(fullPath+"/"+extName, artifactDir+"/"+extName)
+// Package collects the primary artifact of each module of the reactor, picked by its
+// packaging, and records the module's coordinates for it
+func (javaCompiler) Package(bc *utils.BuildContext) error {
+	modules, err := readReactor(filepath.Join(bc.BuildDir, bc.Config.BuildFile))
 	if err != nil {
-		spinner.LogMessage(err.Error(), "warn")
+		return err
 	}
 
-	// If outputpath provided also cp artifacts to that location
-	if outputPath != "" {
-		// Check if outputPath exists.  If not, create it
-		if _, err := os.Stat(outputPath); os.IsNotExist(err) {
-			if err := os.Mkdir(outputPath, 0755); err != nil {
-				spinner.LogMessage("Could not create output path", "fatal")
+	var paths []string
+	for _, module := range modules {
+		var modulePaths []string
+		if bc.Config.ArtifactList != "" {
+			for _, name := range javaArtifactNames(bc, module) {
+				matches, _ := filepath.Glob(filepath.Join(bc.BuildDir, filepath.FromSlash(module.Dir), "target", name))
+				modulePaths = append(modulePaths, matches...)
+			}
+		} else {
+			artifactPath, err := mavenArtifact(bc.BuildDir, module)
+			if err != nil {
+				return err
+			}
+			if artifactPath == "" && module.ArtifactExt() != "" {
+				spinner.LogMessage("Could not find the "+module.ArtifactExt()+" of "+module.Coordinates(), "warn")
+			}
+			if artifactPath != "" {
+				modulePaths = append(modulePaths, artifactPath)
 			}
 		}
 
-		err := cp.Copy(fullPath+"/"+extName, outputPath+"/"+extName)
-		if err != nil {
-			spinner.LogMessage(err.Error(), "warn")
+		for _, artifactPath := range modulePaths {
+			bc.SetArtifactInfo(artifactPath, utils.ArtifactInfo{Module: module.Coordinates()})
 		}
-
-		spinner.LogMessage("Artifact(s) copied to output path provided", "info")
-	}
-
-	errRemove := os.Remove(fullPath + "/" + extName)
-	if errRemove != nil {
-		spinner.LogMessage(errRemove.Error(), "warn")
+		paths = append(paths, modulePaths...)
 	}
 
-	//create metadata, then copy contents to zip dir
-	utils.Metadata(artifactDir)
-
-	if os.Getenv("ARTIFACT_ZIP_ENABLED") == "true" {
-		//zip artifact
-		artifact.ZipArtifactDir()
+	return collectArtifacts(bc, paths)
+}
 
-		//remove uncompressed artifact
-		err := os.Remove(artifactDir + "/" + extName)
-		if err != nil {
-			spinner.LogMessage(err.Error(), "warn")
-		}
-
-		// send artifact to user specified path or send to artifact directory
-		outputPath := os.Getenv("BUILDER_OUTPUT_PATH")
-		if outputPath != "" {
-			err := cp.Copy(artifactDir+archiveExt, outputPath+"/"+filepath.Base(artifactDir)+archiveExt)
-			if err != nil {
-				spinner.LogMessage(err.Error(), "warn")
-			}
-		} else {
-			err := cp.Copy(artifactDir+archiveExt, artifactDir+"/"+filepath.Base(artifactDir)+archiveExt)
-			if err != nil {
-				spinner.LogMessage(err.Error(), "warn")
-			}
+// javaArtifactNames returns the names in the artifactlist, or the name of the primary artifact
+// of module
+func javaArtifactNames(bc *utils.BuildContext, module mavenModule) []string {
+	if bc.Config.ArtifactList == "" {
+		if ext := module.ArtifactExt(); ext != "" {
+			return []string{module.FinalName + ext}
 		}
+		return nil
+	}
 
-		errRemove := os.Remove(artifactDir + archiveExt)
-		if errRemove != nil {
-			spinner.LogMessage(errRemove.Error(), "warn")
+	var names []string
+	for _, name := range strings.Split(bc.Config.ArtifactList, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
 		}
 	}
+
+	return names
 }
@@ -0,0 +1,225 @@
+package compile
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// mavenSkippedClassifiers are the attached artifacts that aren't a module's primary artifact
+var mavenSkippedClassifiers = []string{"-sources", "-javadoc", "-tests", "-test-sources"}
+
+var mavenProperty = regexp.MustCompile(`\$\{([^}]+)\}`)
+
+// pom is the part of a pom.xml needed to find the modules of a reactor and their artifacts
+type pom struct {
+	GroupID    string `xml:"groupId"`
+	ArtifactID string `xml:"artifactId"`
+	Version    string `xml:"version"`
+	Packaging  string `xml:"packaging"`
+	Parent     struct {
+		GroupID string `xml:"groupId"`
+		Version string `xml:"version"`
+	} `xml:"parent"`
+	Modules    []string `xml:"modules>module"`
+	Properties struct {
+		Entries []struct {
+			XMLName xml.Name
+			Value   string `xml:",chardata"`
+		} `xml:",any"`
+	} `xml:"properties"`
+	Build struct {
+		FinalName string `xml:"finalName"`
+	} `xml:"build"`
+}
+
+// mavenModule is one module of a maven reactor
+type mavenModule struct {
+	// Dir is the module's dir relative to the root of the reactor, "." for the root
+	Dir        string
+	GroupID    string
+	ArtifactID string
+	Version    string
+	Packaging  string
+	// FinalName is the name of the module's artifact without its extension
+	FinalName string
+}
+
+// Coordinates returns the module's groupId:artifactId:version
+func (m mavenModule) Coordinates() string {
+	return m.GroupID + ":" + m.ArtifactID + ":" + m.Version
+}
+
+// ArtifactExt returns the extension of the module's primary artifact by its packaging, or ""
+// for a pom module which has none
+func (m mavenModule) ArtifactExt() string {
+	switch m.Packaging {
+	case "pom":
+		return ""
+	case "war", "ear", "rar":
+		return "." + m.Packaging
+	}
+
+	// jar, and packagings like maven-plugin, ejb and bundle that produce a jar
+	return ".jar"
+}
+
+// readReactor reads the pom.xml at pomPath and the poms of its modules, recursively.  The
+// modules are returned in the order they're listed, the root first.
+func readReactor(pomPath string) ([]mavenModule, error) {
+	var modules []mavenModule
+	seen := map[string]bool{}
+
+	var read func(path, dir string, inherited map[string]string) error
+	read = func(path, dir string, inherited map[string]string) error {
+		if seen[path] {
+			return nil
+		}
+		seen[path] = true
+
+		source, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("could not read %s: %w", path, err)
+		}
+		var p pom
+		if err := xml.Unmarshal(source, &p); err != nil {
+			return fmt.Errorf("could not parse %s: %w", path, err)
+		}
+
+		// groupId and version are inherited from the parent when they're left out
+		if p.GroupID == "" {
+			p.GroupID = p.Parent.GroupID
+		}
+		if p.Version == "" {
+			p.Version = p.Parent.Version
+		}
+		if p.Packaging == "" {
+			p.Packaging = "jar"
+		}
+
+		properties := map[string]string{}
+		for name, value := range inherited {
+			properties[name] = value
+		}
+		for _, entry := range p.Properties.Entries {
+			properties[entry.XMLName.Local] = strings.TrimSpace(entry.Value)
+		}
+		properties["project.groupId"] = resolveMavenProperties(p.GroupID, properties)
+		properties["project.version"] = resolveMavenProperties(p.Version, properties)
+		properties["project.artifactId"] = p.ArtifactID
+
+		module := mavenModule{
+			Dir:        dir,
+			GroupID:    properties["project.groupId"],
+			ArtifactID: p.ArtifactID,
+			Version:    properties["project.version"],
+			Packaging:  strings.TrimSpace(p.Packaging),
+			FinalName:  resolveMavenProperties(p.Build.FinalName, properties),
+		}
+		if module.FinalName == "" {
+			module.FinalName = module.ArtifactID + "-" + module.Version
+		}
+		modules = append(modules, module)
+
+		for _, name := range p.Modules {
+			// a module is a dir holding a pom.xml, or the path of a pom file
+			modulePath := filepath.Join(filepath.Dir(path), filepath.FromSlash(strings.TrimSpace(name)))
+			if info, err := os.Stat(modulePath); err == nil && info.IsDir() {
+				modulePath = filepath.Join(modulePath, "pom.xml")
+			}
+
+			moduleDir, err := filepath.Rel(filepath.Dir(pomPath), filepath.Dir(modulePath))
+			if err != nil {
+				return err
+			}
+			if err := read(modulePath, filepath.ToSlash(moduleDir), properties); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if err := read(pomPath, ".", nil); err != nil {
+		return nil, err
+	}
+
+	return modules, nil
+}
+
+// resolveMavenProperties fills in the ${...} properties of s, unknown ones are left as they are
+func resolveMavenProperties(s string, properties map[string]string) string {
+	s = strings.TrimSpace(s)
+
+	// properties can refer to other properties, a few rounds are enough for real poms
+	for i := 0; i < 5 && strings.Contains(s, "${"); i++ {
+		s = mavenProperty.ReplaceAllStringFunc(s, func(match string) string {
+			if value, ok := properties[match[2:len(match)-1]]; ok {
+				return value
+			}
+			return match
+		})
+	}
+
+	return s
+}
+
+// mavenArtifact returns the path of the primary artifact of module in its target dir, or ""
+// if it can't be found.  It's the file named after the module's finalName if there is one,
+// otherwise the only file with the packaging's extension that isn't an attached artifact
+// (sources, javadoc, tests) or the original jar a shade plugin left behind.
+func mavenArtifact(buildDir string, module mavenModule) (string, error) {
+	ext := module.ArtifactExt()
+	if ext == "" {
+		return "", nil
+	}
+
+	targetDir := filepath.Join(buildDir, filepath.FromSlash(module.Dir), "target")
+	expected := filepath.Join(targetDir, module.FinalName+ext)
+	if _, err := os.Stat(expected); err == nil {
+		return expected, nil
+	}
+
+	files, err := os.ReadDir(targetDir)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("could not read %s: %w", targetDir, err)
+	}
+
+	var candidates []string
+	for _, file := range files {
+		name := file.Name()
+		if !file.Type().IsRegular() || filepath.Ext(name) != ext || strings.HasPrefix(name, "original-") {
+			continue
+		}
+
+		attached := false
+		for _, classifier := range mavenSkippedClassifiers {
+			if strings.HasSuffix(strings.TrimSuffix(name, ext), classifier) {
+				attached = true
+			}
+		}
+		if !attached {
+			candidates = append(candidates, filepath.Join(targetDir, name))
+		}
+	}
+	sort.Strings(candidates)
+
+	// the one named after the module wins over the others
+	for _, candidate := range candidates {
+		if strings.HasPrefix(filepath.Base(candidate), module.ArtifactID) {
+			return candidate, nil
+		}
+	}
+	if len(candidates) > 0 {
+		return candidates[0], nil
+	}
+
+	return "", nil
+}
@@ -0,0 +1,237 @@
+package compile
+
+import (
+	"Builder/artifact"
+	"Builder/spinner"
+	"Builder/utils"
+	"archive/zip"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	cp "github.com/otiai10/copy"
+)
+
+// collectArtifacts copies the artifacts at paths into the artifact dir (and the output path if
+// one is given), removes them from the workspace, writes the build metadata and compresses
+// the artifact dir if the compress flag is given.  An artifact that can't be copied fails the
+// build, so it isn't reported as built without it.
+func collectArtifacts(bc *utils.BuildContext, paths []string) error {
+	archiveExt := ""
+
+	if runtime.GOOS == "windows" {
+		archiveExt = ".zip"
+	} else {
+		archiveExt = ".tar.gz"
+	}
+
+	if err := artifact.ArtifactDir(bc); err != nil {
+		return err
+	}
+	artifactDir := bc.ArtifactDir
+	outputPath := bc.Config.OutputPath
+
+	if len(paths) == 0 {
+		spinner.LogMessage("Could not find artifact(s).  Please specify the name(s) in the artifactlist of the builder.yaml", "warn")
+	}
+
+	bc.ArtifactNames = nil
+
+	//copy artifact(s), then remove artifact(s) from workspace
+	for _, path := range paths {
+		name := filepath.Base(path)
+		bc.ArtifactNames = append(bc.ArtifactNames, name)
+
+		if err := cp.Copy(path, artifactDir+"/"+name); err != nil {
+			return fmt.Errorf("could not copy artifact %s to the artifact dir: %w", name, err)
+		}
+
+		// If outputpath provided also cp artifacts to that location
+		if outputPath != "" {
+			// Check if outputPath exists.  If not, create it
+			if _, err := os.Stat(outputPath); os.IsNotExist(err) {
+				if err := os.MkdirAll(outputPath, 0755); err != nil {
+					return fmt.Errorf("could not create output path: %w", err)
+				}
+			}
+
+			if err := cp.Copy(path, outputPath+"/"+name); err != nil {
+				return fmt.Errorf("could not copy artifact %s to the output path: %w", name, err)
+			}
+
+			spinner.LogMessage("Artifact(s) copied to output path provided", "info")
+		}
+
+		errRemove := os.RemoveAll(path)
+		if errRemove != nil {
+			spinner.LogMessage(errRemove.Error(), "warn")
+		}
+	}
+
+	//create metadata, then copy contents to zip dir
+	if err := utils.Metadata(bc, artifactDir); err != nil {
+		return err
+	}
+
+	if bc.Flags.Compress {
+		//zip artifact
+		if err := artifact.ZipArtifactDir(bc); err != nil {
+			return err
+		}
+
+		//remove uncompressed artifacts
+		for _, name := range bc.ArtifactNames {
+			err := os.RemoveAll(artifactDir + "/" + name)
+			if err != nil {
+				spinner.LogMessage(err.Error(), "warn")
+			}
+		}
+
+		// send artifact to user specified path or send to artifact directory
+		archiveDir := artifactDir
+		if outputPath != "" {
+			archiveDir = outputPath
+		}
+		if err := cp.Copy(artifactDir+archiveExt, archiveDir+"/"+filepath.Base(artifactDir)+archiveExt); err != nil {
+			return fmt.Errorf("could not copy compressed artifacts: %w", err)
+		}
+
+		errRemove := os.Remove(artifactDir + archiveExt)
+		if errRemove != nil {
+			spinner.LogMessage(errRemove.Error(), "warn")
+		}
+	}
+
+	return nil
+}
+
+// zipBuildDir zips up the build dir of an interpreted project (sources plus installed
+// dependencies, and builder-info.json with stamp) into workspace/artifact_<unix>.zip and
+// returns its path
+func zipBuildDir(bc *utils.BuildContext) (string, error) {
+	if err := stampArchive(bc); err != nil {
+		return "", err
+	}
+
+	// CreateZip artifact dir with timestamp
+	zipPath := buildZipPath(bc)
+
+	outFile, err := os.Create(zipPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to create artifact zip: %w", err)
+	}
+
+	defer outFile.Close()
+
+	// Create a new zip archive.
+	w := zip.NewWriter(outFile)
+
+	// Add files from temp dir to the archive.
+	if err := addFiles(bc, w, bc.BuildDir, ""); err != nil {
+		return "", err
+	}
+
+	err = w.Close()
+	if err != nil {
+		return "", fmt.Errorf("failed to create artifact zip: %w", err)
+	}
+
+	return zipPath, nil
+}
+
+// buildZipPath returns the path of the zip zipBuildDir creates
+func buildZipPath(bc *utils.BuildContext) string {
+	return bc.WorkspaceDir + "/artifact_" + strconv.FormatInt(bc.StartTime.Unix(), 10) + ".zip"
+}
+
+// interpretedPlan is the plan of an interpreted lang, built in workspace/temp which gets zipped
+// up as the artifact
+func interpretedPlan(bc *utils.BuildContext) CompilerPlan {
+	return CompilerPlan{
+		BuildDir:  tempBuildPath(bc),
+		Artifacts: []string{buildZipPath(bc)},
+		Notes:     []string{"the build dir, with its installed dependencies, is zipped up as the artifact"},
+	}
+}
+
+// recursively add files
+func addFiles(bc *utils.BuildContext, w *zip.Writer, basePath, baseInZip string) error {
+	// If basePath includes old parent folder name, fix it before we start (necessary for symlinks)
+	projectName := utils.GetName(bc)
+	timeBuildStarted := bc.StartTime.Unix()
+	oldParentName := projectName + "_" + projectName
+	newParentName := projectName + "_" + strconv.FormatInt(timeBuildStarted, 10)
+	if strings.Contains(basePath, oldParentName) {
+		basePath = strings.Replace(basePath, oldParentName, newParentName, 1)
+	}
+
+	// Open the Directory
+	files, err := os.ReadDir(basePath)
+	if err != nil {
+		return fmt.Errorf("could not read %s: %w", basePath, err)
+	}
+
+	for _, file := range files {
+		if !file.IsDir() {
+			dat, err := os.ReadFile(basePath + file.Name())
+			if err != nil {
+				// Check if file is symlink
+				linkedFolder, erro := os.Readlink(basePath + file.Name())
+				if erro != nil {
+					// can't read file or symlink
+					return fmt.Errorf("could not read %s: %w", basePath+file.Name(), err)
+				}
+
+				// If symlink, copy all contents from symlinked directory to folder named after symlink
+				if err := addFiles(bc, w, linkedFolder, baseInZip+file.Name()+"/"); err != nil {
+					spinner.LogMessage("skipping symlink "+file.Name()+": "+err.Error(), "warn")
+				}
+				continue
+			}
+
+			// Add some files to the archive.
+			f, err := w.Create(baseInZip + file.Name())
+			if err != nil {
+				return fmt.Errorf("failed to create zip: %w", err)
+			}
+			_, err = f.Write(dat)
+			if err != nil {
+				return fmt.Errorf("failed to add files to zip: %w", err)
+			}
+		} else if file.IsDir() {
+			// Recurse
+			newBase := basePath + file.Name() + "/"
+			if err := addFiles(bc, w, newBase, baseInZip+file.Name()+"/"); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// WalkMatch returns the paths of all files under root whose name matches pattern
+func WalkMatch(root, pattern string) ([]string, error) {
+	var matches []string
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		if matched, err := filepath.Match(pattern, filepath.Base(path)); err != nil {
+			return err
+		} else if matched {
+			matches = append(matches, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return matches, nil
+}
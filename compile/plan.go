@@ -0,0 +1,304 @@
+package compile
+
+import (
+	"Builder/utils"
+	"Builder/yaml"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"sort"
+)
+
+// CompilerPlan is what a compiler will do for a build, worked out without touching the file
+// system or running anything
+type CompilerPlan struct {
+	// BuildDir is the dir the build commands run in
+	BuildDir string
+	// Commands are the compiler's own commands run before the build command (prebuildcmd, configcmd)
+	Commands []PlannedCommand
+	// Artifacts are the files Package collects, as patterns relative to BuildDir
+	Artifacts []string
+	// Notes are anything about the build that can only be decided when it runs
+	Notes []string
+}
+
+// PlannedCommand is one command a build will run
+type PlannedCommand struct {
+	// Stage is the part of the build the command runs in (prebuild, configure, build, test, docker)
+	Stage   string   `json:"stage"`
+	Name    string   `json:"name,omitempty"`
+	Command string   `json:"command"`
+	Dir     string   `json:"dir"`
+	Env     []string `json:"env,omitempty"`
+	Timeout string   `json:"timeout,omitempty"`
+	// ContinueOnError is set for steps that don't stop the build when they fail
+	ContinueOnError bool `json:"continueOnError,omitempty"`
+}
+
+// BuildPlan is everything a build will do, as shown by builder plan and --dry-run
+type BuildPlan struct {
+	Command     string           `json:"command"`
+	ProjectName string           `json:"projectName"`
+	ProjectType string           `json:"projectType"`
+	BuildFile   string           `json:"buildFile"`
+	Config      yaml.BuilderYaml `json:"config"`
+	Dirs        PlanDirs         `json:"dirs"`
+	Commands    []PlannedCommand `json:"commands"`
+	Env         []string         `json:"env,omitempty"`
+	Artifacts   []string         `json:"artifacts"`
+	Outputs     []string         `json:"outputs"`
+	Timeout     string           `json:"timeout,omitempty"`
+	Notes       []string         `json:"notes,omitempty"`
+}
+
+// PlanDirs are the dirs a build creates.  Parent, Hidden, Workspace, Logs and Build are the
+// paths while the build runs, the parent dir is renamed to FinishedParent once the project is
+// built.
+type PlanDirs struct {
+	Parent         string `json:"parent"`
+	FinishedParent string `json:"finishedParent"`
+	Hidden         string `json:"hidden"`
+	Workspace      string `json:"workspace"`
+	Logs           string `json:"logs"`
+	Build          string `json:"build"`
+	Artifact       string `json:"artifact"`
+}
+
+// PlanBuild works out everything compiler c would do to build buildFile: the build dir, the
+// commands and where they run, and the artifacts and where they go.  bc's dirs must already
+// be set (see directory.PlanDirs).  finishedParent is the path the parent dir gets renamed to.
+func PlanBuild(bc *utils.BuildContext, c Compiler, buildFile, finishedParent string) (*BuildPlan, error) {
+	if bc.Config.ProjectType == "" {
+		bc.Config.ProjectType = c.ProjectType()
+	}
+
+	cp, err := c.Plan(bc, buildFile)
+	if err != nil {
+		return nil, err
+	}
+	bc.BuildDir = cp.BuildDir
+
+	name := utils.GetName(bc)
+	stamp := fmt.Sprintf("%s_artifact_%d", name, bc.StartTime.Unix())
+
+	plan := &BuildPlan{
+		Command:     bc.Command,
+		ProjectName: name,
+		ProjectType: c.ProjectType(),
+		BuildFile:   buildFile,
+		Dirs: PlanDirs{
+			Parent:         bc.ParentDir,
+			FinishedParent: finishedParent,
+			Hidden:         bc.HiddenDir,
+			Workspace:      bc.WorkspaceDir,
+			Logs:           bc.LogsDir,
+			Build:          bc.BuildDir,
+			Artifact:       filepath.Join(finishedParent, stamp),
+		},
+		Commands:  cp.Commands,
+		Env:       planEnv(bc),
+		Artifacts: cp.Artifacts,
+		Notes:     cp.Notes,
+	}
+
+	buildCommands, err := planBuildCommands(bc, c)
+	if err != nil {
+		return nil, err
+	}
+	plan.Commands = append(plan.Commands, buildCommands...)
+
+	testCommands, err := planTestCommands(bc, c)
+	if err != nil {
+		return nil, err
+	}
+	plan.Commands = append(plan.Commands, testCommands...)
+
+	if bc.Flags.Docker {
+		dockerCmds, dir, err := utils.DockerCommands(bc)
+		if err != nil {
+			return nil, err
+		}
+		for _, command := range dockerCmds {
+			plan.Commands = append(plan.Commands, PlannedCommand{Stage: "docker", Command: command, Dir: dir})
+		}
+	}
+
+	if bc.Config.Stamp {
+		plan.Notes = append(plan.Notes, "stamp: the build info is in the BUILDER_* variables above, and set with -ldflags -X for go, written to src/main/resources/"+buildInfoProperties+" for java and gradle and to "+builderInfoJSON+" in the archive of node, python and ruby projects")
+	}
+	if bc.Hermetic() {
+		plan.Notes = append(plan.Notes, "hermetic: the build commands only get PATH, HOME, the locale, the toolchain variables and the env and secrets above from Builder's environment")
+	}
+
+	// where the artifacts end up
+	plan.Outputs = append(plan.Outputs, plan.Dirs.Artifact, filepath.Join(plan.Dirs.Artifact, "metadata.json"), filepath.Join(plan.Dirs.Artifact, "metadata.yaml"))
+	if bc.Config.OutputPath != "" {
+		plan.Outputs = append(plan.Outputs, bc.Config.OutputPath)
+	}
+	if bc.Flags.Compress {
+		archiveExt := ".tar.gz"
+		if runtime.GOOS == "windows" {
+			archiveExt = ".zip"
+		}
+		archiveDir := plan.Dirs.Artifact
+		if bc.Config.OutputPath != "" {
+			archiveDir = bc.Config.OutputPath
+		}
+		plan.Outputs = append(plan.Outputs, filepath.Join(archiveDir, stamp+archiveExt))
+	}
+
+	timeout := bc.Flags.Timeout
+	if timeout == 0 {
+		if timeout, err = utils.ParseTimeout(bc.Config.Timeout); err != nil {
+			return nil, err
+		}
+	}
+	if timeout > 0 {
+		plan.Timeout = timeout.String()
+	}
+
+	plan.Config = bc.Config
+
+	return plan, nil
+}
+
+// planBuildCommands returns the commands runBuildCommand would run
+func planBuildCommands(bc *utils.BuildContext, c Compiler) ([]PlannedCommand, error) {
+	if len(bc.Config.Targets) > 0 {
+		return planTargetBuilds(bc, c)
+	}
+
+	return planBuild(bc, c)
+}
+
+// planBuild returns the commands runBuild would run
+func planBuild(bc *utils.BuildContext, c Compiler) ([]PlannedCommand, error) {
+	var commands []PlannedCommand
+
+	if len(bc.Config.Steps) > 0 {
+		if len(bc.Config.BuildCmd) > 0 {
+			return nil, fmt.Errorf("builder.yaml has both steps and buildcmd, use one or the other")
+		}
+
+		for i, step := range bc.Config.Steps {
+			name := step.Name
+			if name == "" {
+				name = fmt.Sprintf("step-%d", i+1)
+			}
+			if len(step.Command) == 0 {
+				return nil, fmt.Errorf("step %q has no command", name)
+			}
+			if _, err := utils.ParseTimeout(step.Timeout); err != nil {
+				return nil, fmt.Errorf("step %q: %w", name, err)
+			}
+
+			dir := bc.BuildDir
+			if step.WorkingDir != "" {
+				dir = filepath.Join(bc.BuildDir, step.WorkingDir)
+			}
+
+			for _, command := range step.Command {
+				if err := checkCommand(bc, name, command, stepEnv(step.Env)); err != nil {
+					return nil, err
+				}
+				commands = append(commands, PlannedCommand{
+					Stage:           "build",
+					Name:            name,
+					Command:         command,
+					Dir:             dir,
+					Env:             stepEnv(step.Env),
+					Timeout:         step.Timeout,
+					ContinueOnError: step.ContinueOnError,
+				})
+			}
+		}
+
+		return commands, nil
+	}
+
+	buildCmds := bc.Config.BuildCmd
+	if len(buildCmds) == 0 {
+		buildCmds = yaml.Commands{utils.QuoteCommand(c.DefaultBuildCommand(bc))}
+	}
+	for _, command := range buildCmds {
+		if err := checkCommand(bc, "buildcmd", command, nil); err != nil {
+			return nil, err
+		}
+		commands = append(commands, PlannedCommand{Stage: "build", Command: command, Dir: bc.BuildDir})
+	}
+
+	return commands, nil
+}
+
+// planTestCommands returns the commands runTests would run
+func planTestCommands(bc *utils.BuildContext, c Compiler) ([]PlannedCommand, error) {
+	if !testsEnabled(bc) {
+		return nil, nil
+	}
+
+	testCmds := bc.Config.TestCmd
+	if len(testCmds) == 0 {
+		args := c.DefaultTestCommand(bc)
+		if len(args) == 0 {
+			return nil, nil
+		}
+		testCmds = yaml.Commands{utils.QuoteCommand(args)}
+	}
+
+	var commands []PlannedCommand
+	for _, command := range testCmds {
+		if err := checkCommand(bc, "testcmd", command, nil); err != nil {
+			return nil, err
+		}
+		commands = append(commands, PlannedCommand{Stage: "test", Command: command, Dir: bc.BuildDir})
+	}
+
+	return commands, nil
+}
+
+// planUserCommands returns the commands runUserCommands would run for key
+func planUserCommands(bc *utils.BuildContext, stage, key string, commands yaml.Commands) ([]PlannedCommand, error) {
+	var planned []PlannedCommand
+	for _, command := range commands {
+		if err := checkCommand(bc, key, command, nil); err != nil {
+			return nil, err
+		}
+		planned = append(planned, PlannedCommand{Stage: stage, Command: command, Dir: bc.BuildDir})
+	}
+
+	return planned, nil
+}
+
+// checkCommand returns the error the command would fail with before it is run, if any.  The
+// secrets aren't read, so $VAR is checked against the planned env.
+func checkCommand(bc *utils.BuildContext, key, command string, env []string) error {
+	if _, _, err := bc.CommandArgs(command, append(env, planEnv(bc)...)); err != nil {
+		return fmt.Errorf("%s %q: %w", key, command, err)
+	}
+
+	return nil
+}
+
+// planEnv returns the builder.yaml env and secrets the build commands get.  The secrets
+// aren't read, they're shown with where their value comes from.
+func planEnv(bc *utils.BuildContext) []string {
+	var env []string
+	for name, value := range bc.Config.Env {
+		if _, ok := bc.Config.Secrets[name]; !ok {
+			env = append(env, name+"="+value)
+		}
+	}
+	for name, secret := range bc.Config.Secrets {
+		source := "env " + secret.Env
+		if secret.File != "" {
+			source = "file " + secret.File
+		} else if secret.Store != "" {
+			source = "store " + secret.Store
+		}
+		env = append(env, name+"=*** (secret from "+source+")")
+	}
+	env = append(env, bc.StampEnv()...)
+	sort.Strings(env)
+
+	return env
+}
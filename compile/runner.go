@@ -0,0 +1,204 @@
+package compile
+
+import (
+	"Builder/spinner"
+	"Builder/utils"
+	"Builder/yaml"
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"time"
+)
+
+// RunCommand runs args in dir, with the builder.yaml env and secrets and then env (NAME=value)
+// added to Builder's environment (see BuildContext.CommandEnv), and writes the combined output
+// to the build log.  An error is returned if the command can't
+// be started or exits non-zero.
+func RunCommand(bc *utils.BuildContext, dir string, env []string, args []string) error {
+	return RunCommandTimeout(bc, dir, env, args, 0)
+}
+
+// RunCommandTimeout is RunCommand with the command killed if it runs longer than timeout.
+// A timeout of 0 means no timeout.  The command and every process it starts are also killed
+// when the build is cancelled or runs past the build timeout.
+func RunCommandTimeout(bc *utils.BuildContext, dir string, env []string, args []string, timeout time.Duration) error {
+	return runCommand(bc, dir, env, args, timeout, nil)
+}
+
+// runCommand is RunCommandTimeout with each line of output also passed to onLine, if given
+func runCommand(bc *utils.BuildContext, dir string, env []string, args []string, timeout time.Duration, onLine func(string)) error {
+	ctx := bc.Context()
+	if err := ctx.Err(); err != nil {
+		return buildStopped(bc, args[0])
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// found in the commands' own PATH, the way GetTool finds the tools it records
+	env = bc.CommandEnv(env)
+	path, err := utils.LookPath(args[0], dir, env)
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(path, args[1:]...)
+	cmd.Dir = dir
+	cmd.Env = env
+
+	//run cmd, check for err, log cmd
+	spinner.LogMessage("running command: "+bc.Redact(cmd.String()), "info")
+
+	stdout, pipeErr := cmd.StdoutPipe()
+	if pipeErr != nil {
+		return fmt.Errorf("%s failed: %w", cmd.String(), pipeErr)
+	}
+
+	cmd.Stderr = cmd.Stdout
+
+	// Make a new channel which will be used to ensure we get all output
+	done := make(chan struct{}, 1)
+
+	scanner := bufio.NewScanner(stdout)
+
+	// Use the scanner to scan the output line by line and log it
+	// It's running in a goroutine so that it doesn't block
+	go func() {
+		// Read line by line and process it
+		for scanner.Scan() {
+			line := scanner.Text()
+			if onLine != nil {
+				onLine(line)
+			}
+			// Have to stop spinner or it will get printed with log to console
+			spinner.Spinner.Stop()
+			bc.Logger.Info(line)
+			spinner.Spinner.Start()
+		}
+
+		// We're all done, unblock the channel
+		done <- struct{}{}
+
+	}()
+
+	wait, err := utils.StartProcess(ctx, cmd)
+	if err != nil {
+		return fmt.Errorf("%s failed to start: %w", cmd.String(), err)
+	}
+
+	// Wait for all output to be processed
+	<-done
+
+	// Wait for cmd to finish
+	if err := wait(); err != nil {
+		if bc.Context().Err() != nil {
+			return buildStopped(bc, cmd.String())
+		}
+		if errors.Is(err, utils.ErrTimedOut) {
+			return fmt.Errorf("%s %w after %s", cmd.String(), utils.ErrTimedOut, timeout.Round(time.Millisecond))
+		}
+		return fmt.Errorf("%s failed: %w", cmd.String(), err)
+	}
+
+	return nil
+}
+
+// buildStopped returns the error for command being stopped by the build being cancelled or
+// running past the build timeout
+func buildStopped(bc *utils.BuildContext, command string) error {
+	if bc.Cancelled() {
+		return fmt.Errorf("%s: %w", command, utils.ErrCancelled)
+	}
+
+	return fmt.Errorf("%s: build %w after %s", command, utils.ErrTimedOut, bc.Timeout)
+}
+
+// runUserCommands runs the commands given for key in the builder.yaml (buildcmd,
+// prebuildcmd, configcmd) one after another in the build dir
+func runUserCommands(bc *utils.BuildContext, key string, commands yaml.Commands) error {
+	return runCommands(bc, key, commands, bc.BuildDir, nil, 0)
+}
+
+// runCommands runs commands one after another in dir with env added to their environment.
+// timeout is shared by all of the commands, 0 means no timeout.
+func runCommands(bc *utils.BuildContext, key string, commands yaml.Commands, dir string, env []string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+
+	for i, command := range commands {
+		cmdEnv, args, err := bc.CommandArgs(command, env)
+		if err != nil {
+			return fmt.Errorf("%s %q: %w", key, command, err)
+		}
+
+		if len(commands) > 1 {
+			spinner.LogMessage(fmt.Sprintf("%s %d of %d", key, i+1, len(commands)), "info")
+		}
+		// mark where each command's output starts in the build log
+		bc.Logger.Info("$ " + command)
+
+		var remaining time.Duration
+		if timeout > 0 {
+			if remaining = time.Until(deadline); remaining <= 0 {
+				return fmt.Errorf("%s %w after %s", key, utils.ErrTimedOut, timeout)
+			}
+		}
+
+		if err := RunCommandTimeout(bc, dir, append(env, cmdEnv...), args, remaining); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// runBuildCommand records the toolchain and runs the steps or the buildcmd from the
+// builder.yaml, or the compiler's default build command, in the build dir, once for every
+// target if targets are given.
+func runBuildCommand(bc *utils.BuildContext, c Compiler) error {
+	recordToolchain(bc, c)
+
+	if len(bc.Config.Targets) > 0 {
+		return runTargetBuilds(bc, c)
+	}
+
+	return runBuild(bc, c)
+}
+
+// recordToolchain records the versions of the tools the build uses.  It runs once the build
+// dir is set up, so the tools are asked from the dir they build in.
+func recordToolchain(bc *utils.BuildContext, c Compiler) {
+	bc.Toolchain = nil
+	for _, versionCmd := range c.ToolchainCommands(bc) {
+		tool := utils.GetTool(bc, versionCmd)
+		spinner.LogMessage(tool.Name+" version: "+tool.Version+" ("+tool.Path+")", "info")
+		bc.Toolchain = append(bc.Toolchain, tool)
+	}
+}
+
+// runBuild runs the steps, the buildcmd or the default build command.  The default is
+// recorded so it ends up in the builder.yaml, unless it's stamped.
+func runBuild(bc *utils.BuildContext, c Compiler) error {
+	if len(bc.Config.Steps) > 0 {
+		if len(bc.Config.BuildCmd) > 0 {
+			return fmt.Errorf("builder.yaml has both steps and buildcmd, use one or the other")
+		}
+		return runSteps(bc)
+	}
+
+	if len(bc.Config.BuildCmd) > 0 {
+		//user specified cmd(s)
+		return runUserCommands(bc, "buildcmd", bc.Config.BuildCmd)
+	}
+
+	args := c.DefaultBuildCommand(bc)
+	// a stamped command holds this build's info, the next build would stamp it again, and
+	// --test can change the default command of a build that doesn't always test
+	if !bc.Config.Stamp && !bc.Flags.Test {
+		bc.Config.BuildCmd = yaml.Commands{utils.QuoteCommand(args)}
+	}
+
+	return RunCommand(bc, bc.BuildDir, nil, args)
+}
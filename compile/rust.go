@@ -4,220 +4,141 @@
 package compile
 
 import (
-	"Builder/artifact"
-	"Builder/directory"
-	"Builder/spinner"
 	"Builder/utils"
-	"Builder/utils/log"
-	"Builder/yaml"
 	"bufio"
+	"fmt"
 	"os"
-	"os/exec"
 	"runtime"
 	"strings"
-	"time"
 )
 
-// Rust creates exe from file passed in as arg
-func Rust(filePath string) {
+type rustCompiler struct{}
 
-	//Set default project type env for builder.yaml creation
-	projectType := os.Getenv("BUILDER_PROJECT_TYPE")
-	if projectType == "" {
-		os.Setenv("BUILDER_PROJECT_TYPE", "rust")
-	}
+func init() {
+	Register(20, rustCompiler{})
+}
 
-	//Set up local logger
-	localPath, _ := os.LookupEnv("BUILDER_LOGS_DIR")
-	locallogger, closeLocalLogger = log.NewLogger("logs", localPath)
-
-	//define dir path for command to run in
-	var fullPath string
-	configPath := os.Getenv("BUILDER_DIR_PATH")
-	//if user defined path in builder.yaml, full path is included already, else add curren dir + local path
-	if os.Getenv("BUILDER_COMMAND") == "true" {
-		fullPath = filePath
-	} else if configPath != "" {
-		// ex: C:/Users/Name/Projects/helloworld_19293/workspace/dir
-		fullPath = filePath
-	} else {
-		path, _ := os.Getwd()
-		//combine local path to newly created tempWorkspace,
-		//gets rid of "." in path name
-		// ex: C:/Users/Name/Projects + /helloworld_19293/workspace/dir
-		fullPath = path + filePath[strings.Index(filePath, ".")+1:]
-		os.Setenv("BUILDER_DIR_PATH", path)
-	}
+func (rustCompiler) ProjectType() string {
+	return "rust"
+}
 
-	//install dependencies/build, if yaml build type exists install accordingly
-	buildTool := strings.ToLower(os.Getenv("BUILDER_BUILD_TOOL"))
-	//find 'rs file' to be built
-	buildFile := os.Getenv("BUILDER_BUILD_FILE")
-	buildCmd := os.Getenv("BUILDER_BUILD_COMMAND")
-	//if no file defined by user, use default Cargo.toml
-	if buildFile == "" {
-		buildFile = "Cargo.toml"
-		os.Setenv("BUILDER_BUILD_FILE", buildFile)
-	}
+func (rustCompiler) Detect(bc *utils.BuildContext, dir string) (string, error) {
+	return detectBuildFile(bc, dir, "Cargo.toml")
+}
 
-	//buildName = buildfile (get rid of ".rs") + Unix timestamp
-	var cmd *exec.Cmd
-	if buildCmd != "" {
-		//user specified cmd
-		buildCmdArray := strings.Fields(buildCmd)
-		cmd = exec.Command(buildCmdArray[0], buildCmdArray[1:]...)
-		cmd.Dir = fullPath // or whatever directory it's in
-	} else if buildTool == "rust" {
-		cmd = exec.Command("cargo", "build", "-r")
-		cmd.Dir = fullPath // or whatever directory it's in
-		os.Setenv("BUILDER_BUILD_COMMAND", "cargo build -r")
-	} else {
-		cmd = exec.Command("cargo", "build", "-r")
-		cmd.Dir = fullPath // or whatever directory it's in
-		os.Setenv("BUILDER_BUILD_COMMAND", "cargo build -r")
-		os.Setenv("BUILDER_BUILD_TOOL", "rust")
+// Build creates exe from file passed in as arg
+func (c rustCompiler) Build(bc *utils.BuildContext, buildFile string) error {
+	plan, err := c.Plan(bc, buildFile)
+	if err != nil {
+		return err
 	}
 
-	//run cmd, check for err, log cmd
-	spinner.LogMessage("running command: "+cmd.String(), "info")
-
-	stdout, pipeErr := cmd.StdoutPipe()
-	if pipeErr != nil {
-		spinner.LogMessage(pipeErr.Error(), "fatal")
+	if err := utils.CopyDir(bc); err != nil {
+		return err
 	}
+	bc.BuildDir = plan.BuildDir
 
-	cmd.Stderr = cmd.Stdout
-
-	// Make a new channel which will be used to ensure we get all output
-	done := make(chan struct{})
+	return runBuildCommand(bc, c)
+}
 
-	scanner := bufio.NewScanner(stdout)
+func (rustCompiler) Plan(bc *utils.BuildContext, buildFile string) (CompilerPlan, error) {
+	//if no file defined by user, use default Cargo.toml
+	if bc.Config.BuildFile == "" {
+		bc.Config.BuildFile = "Cargo.toml"
+	}
+	if bc.Config.BuildTool == "" {
+		bc.Config.BuildTool = "rust"
+	}
 
-	// Use the scanner to scan the output line by line and log it
-	// It's running in a goroutine so that it doesn't block
-	go func() {
-		// Read line by line and process it
-		for scanner.Scan() {
-			line := scanner.Text()
-			spinner.Spinner.Stop()
-			locallogger.Info(line)
-			spinner.Spinner.Start()
+	var artifacts []string
+	for _, name := range rustArtifactNames(bc, buildFile) {
+		for _, target := range bc.Config.Targets {
+			artifacts = append(artifacts, "target/"+target+"/release/"+targetArtifactName(name, target))
 		}
+		if len(bc.Config.Targets) == 0 {
+			artifacts = append(artifacts, "target/release/"+name)
+		}
+	}
 
-		// We're all done, unblock the channel
-		done <- struct{}{}
-
-	}()
-
-	os.Setenv("BUILD_START_TIME", time.Now().Format(time.RFC850))
+	return CompilerPlan{BuildDir: workspaceBuildPath(bc, buildFile), Artifacts: artifacts}, nil
+}
 
-	if err := cmd.Start(); err != nil {
-		spinner.LogMessage(err.Error(), "fatal")
+func (rustCompiler) DefaultBuildCommand(bc *utils.BuildContext) []string {
+	if bc.Target != "" {
+		return []string{"cargo", "build", "-r", "--target", bc.Target}
 	}
 
-	// Wait for all output to be processed
-	<-done
+	return []string{"cargo", "build", "-r"}
+}
 
-	// Wait for cmd to finish
-	if err := cmd.Wait(); err != nil {
-		spinner.LogMessage(err.Error(), "fatal")
+// TargetEnv returns CARGO_BUILD_TARGET for a target triple, so a buildcmd running cargo build
+// builds for the target too
+func (rustCompiler) TargetEnv(target string) ([]string, error) {
+	if target = strings.TrimSpace(target); target == "" || strings.ContainsAny(target, "/ ") {
+		return nil, fmt.Errorf("rust target %q is not a target triple (x86_64-unknown-linux-gnu, aarch64-apple-darwin, ...)", target)
 	}
 
-	os.Setenv("BUILD_END_TIME", time.Now().Format(time.RFC850))
-
-	// Close log file
-	closeLocalLogger()
+	return []string{"CARGO_BUILD_TARGET=" + target}, nil
+}
 
-	// Update parent dir name to include start time
-	fullPath = directory.UpdateParentDirName(fullPath)
+func (rustCompiler) DefaultTestCommand(bc *utils.BuildContext) []string {
+	return []string{"cargo", "test"}
+}
 
-	yaml.CreateBuilderYaml(fullPath)
+func (rustCompiler) ToolchainCommands(bc *utils.BuildContext) [][]string {
+	return [][]string{{"cargo", "--version"}, {"rustc", "--version"}}
+}
 
-	packageRustArtifact(fullPath)
+func (rustCompiler) Package(bc *utils.BuildContext) error {
+	var paths []string
+	for _, name := range rustArtifactNames(bc, bc.BuildDir+"/"+bc.Config.BuildFile) {
+		// a target's build goes into target/<triple>/release
+		for _, target := range bc.Config.Targets {
+			path, err := renameTargetArtifact(bc, bc.BuildDir+"/target/"+target+"/release/"+exeName(name, target), target, utils.ArtifactInfo{})
+			if err != nil {
+				return err
+			}
+			if path != "" {
+				paths = append(paths, path)
+			}
+		}
+		if len(bc.Config.Targets) == 0 {
+			paths = append(paths, bc.BuildDir+"/target/release/"+name)
+		}
+	}
 
-	spinner.LogMessage("Rust project built successfully.", "info")
+	return collectArtifacts(bc, paths)
 }
 
-func packageRustArtifact(fullPath string) {
-	archiveExt := ""
+// rustArtifactNames returns the names in the artifactlist, or the package name from the
+// Cargo.toml at tomlPath
+func rustArtifactNames(bc *utils.BuildContext, tomlPath string) []string {
 	artifactExt := ""
 	if runtime.GOOS == "windows" {
-		archiveExt = ".zip"
 		artifactExt = ".exe"
-	} else {
-		archiveExt = ".tar.gz"
-		artifactExt = ""
 	}
 
-	artifact.ArtifactDir()
-	artifactDir := os.Getenv("BUILDER_ARTIFACT_DIR")
-	outputPath := os.Getenv("BUILDER_OUTPUT_PATH")
-	artifactList := os.Getenv("BUILDER_ARTIFACT_LIST")
+	artifactList := bc.Config.ArtifactList
 
-	if artifactList != "" {
-		os.Setenv("BUILDER_ARTIFACT_NAMES", artifactList)
-	} else {
-		extName := ""
-		tomlfile, _ := os.Open(fullPath + "/" + os.Getenv("BUILDER_BUILD_FILE"))
+	if artifactList == "" {
+		// Use the package name from the Cargo.toml
+		tomlfile, _ := os.Open(tomlPath)
+		defer tomlfile.Close()
 		scanner := bufio.NewScanner(tomlfile)
 		for scanner.Scan() {
 			line := scanner.Text()
-			if strings.HasPrefix(line, "name = ") == true {
-				extName = strings.ReplaceAll(line[7:]+artifactExt, "\"", "")
+			if strings.HasPrefix(line, "name = ") {
+				artifactList = strings.ReplaceAll(line[7:]+artifactExt, "\"", "")
 			}
 		}
-		defer tomlfile.Close()
-		os.Setenv("BUILDER_ARTIFACT_NAMES", extName)
-		artifactList = extName
-
 	}
-	artifactArray := strings.Split(artifactList, ",")
-	if len(artifactArray) == 0 {
-		spinner.LogMessage("Could not find artifact(s).  Please specify the name(s) in the artifactlist of the builder.yaml", "fatal")
-	}
-
-	//copy artifact(s), then remove artifact(s) from workspace
-	for _, artifact := range artifactArray {
-		exec.Command("cp", "-a", fullPath+"/target/release/"+artifact, artifactDir).Run()
-
-		// If outputpath provided also cp artifacts to that location
-		if outputPath != "" {
-			// Check if outputPath exists.  If not, create it
-			if _, err := os.Stat(outputPath); os.IsNotExist(err) {
-				if err := os.Mkdir(outputPath, 0755); err != nil {
-					spinner.LogMessage("Could not create output path", "fatal")
-				}
-			}
 
-			exec.Command("cp", "-a", fullPath+"/target/release/"+artifact, outputPath).Run()
-
-			spinner.LogMessage("Artifact(s) copied to output path provided", "info")
+	var names []string
+	for _, name := range strings.Split(artifactList, ",") {
+		if name != "" {
+			names = append(names, name)
 		}
-
-		exec.Command("rm", fullPath+"/target/release/"+artifact).Run()
 	}
 
-	//create metadata
-	utils.Metadata(artifactDir)
-
-	if os.Getenv("ARTIFACT_ZIP_ENABLED") == "true" {
-		//zip artifact
-		artifact.ZipArtifactDir()
-
-		//copy zip into open artifactDir, delete zip in workspace (keeps entire artifact contained)
-		exec.Command("cp", "-a", artifactDir+archiveExt, artifactDir).Run()
-		exec.Command("rm", artifactDir+archiveExt).Run()
-
-		// send artifact to user specified path or send to parent directory
-		artifactStamp := os.Getenv("BUILDER_ARTIFACT_STAMP")
-		outputPath := os.Getenv("BUILDER_OUTPUT_PATH")
-		if outputPath != "" {
-			exec.Command("cp", "-a", artifactDir+"/"+artifactStamp+archiveExt, outputPath).Run()
-		} else {
-			exec.Command("cp", "-a", artifactDir+"/"+artifactStamp+archiveExt, os.Getenv("BUILDER_PARENT_DIR")).Run()
-		}
-
-		//remove artifact directory
-		exec.Command("rm", "-r", artifactDir).Run()
-	}
+	return names
 }
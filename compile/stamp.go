@@ -0,0 +1,110 @@
+package compile
+
+import (
+	"Builder/spinner"
+	"Builder/utils"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// buildInfoProperties is the file stamped into the resources of a java project's jars
+const buildInfoProperties = "build-info.properties"
+
+// builderInfoJSON is the file stamped into the archive of an interpreted project
+const builderInfoJSON = "builder-info.json"
+
+// stampNames are the build info values in the order they're stamped
+var stampNames = []string{"githash", "branch", "buildid", "timestamp"}
+
+// goStampVars are the go variables the build info is set in when no stampvars are given
+var goStampVars = map[string]string{
+	"githash":   "main.gitHash",
+	"branch":    "main.branch",
+	"buildid":   "main.buildID",
+	"timestamp": "main.buildTime",
+}
+
+// goStampFlags returns the -ldflags that set the stampvars (or main.gitHash, main.branch,
+// main.buildID and main.buildTime) to the build info, nil if stamp is off
+func goStampFlags(bc *utils.BuildContext) []string {
+	if !bc.Config.Stamp {
+		return nil
+	}
+
+	vars := goStampVars
+	if len(bc.Config.StampVars) > 0 {
+		vars = map[string]string{}
+		for name, variable := range bc.Config.StampVars {
+			vars[strings.ToLower(name)] = variable
+		}
+	}
+
+	values := bc.BuildInfo().Values()
+	var flags []string
+	for _, name := range stampNames {
+		if variable := vars[name]; variable != "" {
+			// go splits -ldflags like a shell, the timestamp has spaces in it
+			value := variable + "=" + values[name]
+			switch {
+			case strings.Contains(value, "'"):
+				value = `"` + value + `"`
+			case strings.ContainsAny(value, " \t\""):
+				value = "'" + value + "'"
+			}
+			flags = append(flags, "-X "+value)
+		}
+	}
+	if len(flags) == 0 {
+		return nil
+	}
+
+	return []string{"-ldflags", strings.Join(flags, " ")}
+}
+
+// stampJavaResources writes build-info.properties into src/main/resources of each of the
+// project dirs (relative to the build dir), so it ends up at the root of their jars.  A
+// build-info.properties the project has itself is left as it is.
+func stampJavaResources(bc *utils.BuildContext, dirs []string) error {
+	if !bc.Config.Stamp {
+		return nil
+	}
+
+	properties := bc.BuildInfo().Properties()
+	for _, dir := range dirs {
+		resources := filepath.Join(bc.BuildDir, filepath.FromSlash(dir), "src", "main", "resources")
+		path := filepath.Join(resources, buildInfoProperties)
+		if _, err := os.Stat(path); err == nil {
+			spinner.LogMessage(fmt.Sprintf("%s already has a %s, it isn't stamped", dir, buildInfoProperties), "warn")
+			continue
+		}
+
+		if err := os.MkdirAll(resources, 0755); err != nil {
+			return fmt.Errorf("could not stamp %s: %w", dir, err)
+		}
+		if err := os.WriteFile(path, properties, 0644); err != nil {
+			return fmt.Errorf("could not stamp %s: %w", dir, err)
+		}
+	}
+
+	return nil
+}
+
+// stampArchive writes builder-info.json into the build dir of an interpreted project, so it's
+// at the root of the archive the build dir is zipped into
+func stampArchive(bc *utils.BuildContext) error {
+	if !bc.Config.Stamp {
+		return nil
+	}
+
+	info, err := bc.BuildInfo().JSON()
+	if err != nil {
+		return fmt.Errorf("could not stamp the archive: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(bc.BuildDir, builderInfoJSON), info, 0644); err != nil {
+		return fmt.Errorf("could not stamp the archive: %w", err)
+	}
+
+	return nil
+}
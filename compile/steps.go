@@ -0,0 +1,92 @@
+package compile
+
+import (
+	"Builder/spinner"
+	"Builder/utils"
+	"Builder/utils/log"
+	"Builder/yaml"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var unsafeLogChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// runSteps runs the steps from the builder.yaml in order.  Each step's output goes to the
+// build log and to its own step-NN-<name>.json log, and its result is recorded in bc.Steps.
+// A failed step stops the build unless it has continueonerror set.
+func runSteps(bc *utils.BuildContext) error {
+	bc.Steps = nil
+
+	for i, step := range bc.Config.Steps {
+		name := step.Name
+		if name == "" {
+			name = "step-" + strconv.Itoa(i+1)
+		}
+
+		if len(step.Command) == 0 {
+			return fmt.Errorf("step %q has no command", name)
+		}
+
+		timeout, err := utils.ParseTimeout(step.Timeout)
+		if err != nil {
+			return fmt.Errorf("step %q: %w", name, err)
+		}
+
+		spinner.LogMessage(fmt.Sprintf("step %d of %d: %s", i+1, len(bc.Config.Steps), name), "info")
+
+		logName := fmt.Sprintf("step-%02d-%s", i+1, unsafeLogChars.ReplaceAllString(name, "_"))
+		start := time.Now()
+		stepErr := runStep(bc, step, name, logName, timeout)
+		result := utils.NewStepResult(name, step.Command.String(), logName+".json", start, time.Now(), stepErr)
+		bc.Steps = append(bc.Steps, result)
+
+		if stepErr != nil {
+			if !step.ContinueOnError {
+				return fmt.Errorf("step %q failed: %w", name, stepErr)
+			}
+			spinner.LogMessage(bc.Redact(fmt.Sprintf("step %q failed, continuing: %v", name, stepErr)), "warn")
+		}
+	}
+
+	return nil
+}
+
+// runStep runs the commands of step with its output teed into the step's own log file
+func runStep(bc *utils.BuildContext, step yaml.Step, name, logName string, timeout time.Duration) error {
+	stepLogger, closeStepLogger, err := log.NewLogger(logName, bc.LogsDir, false, bc.Flags.Debug)
+	if err != nil {
+		return err
+	}
+	buildLogger := bc.Logger
+	bc.Logger = zap.New(zapcore.NewTee(buildLogger.Core(), bc.RedactLogger(stepLogger).Core()))
+	defer func() {
+		bc.Logger.Sync()
+		bc.Logger = buildLogger
+		closeStepLogger()
+	}()
+
+	dir := bc.BuildDir
+	if step.WorkingDir != "" {
+		dir = filepath.Join(bc.BuildDir, step.WorkingDir)
+	}
+
+	return runCommands(bc, name, step.Command, dir, stepEnv(step.Env), timeout)
+}
+
+// stepEnv returns env as sorted NAME=value pairs
+func stepEnv(env map[string]string) []string {
+	var pairs []string
+	for name, value := range env {
+		pairs = append(pairs, name+"="+value)
+	}
+	sort.Strings(pairs)
+
+	return pairs
+}
@@ -0,0 +1,137 @@
+package compile
+
+import (
+	"Builder/spinner"
+	"Builder/utils"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// TargetCompiler is a Compiler that can cross-compile the project for the builder.yaml
+// targets.  The build command is run once per target with bc.Target and bc.TargetEnv set, and
+// DefaultBuildCommand and Package take the target into account.
+type TargetCompiler interface {
+	Compiler
+	// TargetEnv returns the variables the build commands get to build for target, or an error
+	// if target isn't one the compiler understands
+	TargetEnv(target string) ([]string, error)
+}
+
+// runTargetBuilds runs the build command once for every target in the builder.yaml
+func runTargetBuilds(bc *utils.BuildContext, c Compiler) error {
+	tc, ok := c.(TargetCompiler)
+	if !ok {
+		return fmt.Errorf("targets can't be built for %s projects, only for go and rust projects", c.ProjectType())
+	}
+
+	// the default build command differs per target, so it isn't recorded in the builder.yaml
+	buildCmd := bc.Config.BuildCmd
+	defer func() {
+		bc.Config.BuildCmd = buildCmd
+		bc.Target, bc.TargetEnv = "", nil
+	}()
+
+	for _, target := range bc.Config.Targets {
+		env, err := tc.TargetEnv(target)
+		if err != nil {
+			return err
+		}
+
+		bc.Config.BuildCmd = buildCmd
+		bc.Target, bc.TargetEnv = target, env
+		spinner.LogMessage("Building for target "+target, "info")
+		bc.Logger.Info("target " + target + " (" + strings.Join(env, " ") + ")")
+
+		if err := runBuild(bc, c); err != nil {
+			return fmt.Errorf("target %s: %w", target, err)
+		}
+	}
+
+	return nil
+}
+
+// planTargetBuilds returns the commands runTargetBuilds would run
+func planTargetBuilds(bc *utils.BuildContext, c Compiler) ([]PlannedCommand, error) {
+	tc, ok := c.(TargetCompiler)
+	if !ok {
+		return nil, fmt.Errorf("targets can't be built for %s projects, only for go and rust projects", c.ProjectType())
+	}
+	defer func() { bc.Target, bc.TargetEnv = "", nil }()
+
+	var commands []PlannedCommand
+	for _, target := range bc.Config.Targets {
+		env, err := tc.TargetEnv(target)
+		if err != nil {
+			return nil, err
+		}
+		bc.Target, bc.TargetEnv = target, env
+
+		planned, err := planBuild(bc, c)
+		if err != nil {
+			return nil, err
+		}
+		for _, command := range planned {
+			command.Env = append(append([]string{}, env...), command.Env...)
+			commands = append(commands, command)
+		}
+	}
+
+	return commands, nil
+}
+
+// targetSuffix returns the suffix of the artifacts built for target, "linux-amd64" for the go
+// target linux/amd64 and the triple itself for a rust target
+func targetSuffix(target string) string {
+	return strings.ReplaceAll(target, "/", "-")
+}
+
+// targetArtifactName returns the name of an artifact built for target: name with the target's
+// suffix, before .exe for a windows target
+func targetArtifactName(name, target string) string {
+	name = strings.TrimSuffix(name, ".exe") + "-" + targetSuffix(target)
+	if isWindowsTarget(target) {
+		name += ".exe"
+	}
+
+	return name
+}
+
+// exeName returns name with .exe for a windows target and without it for any other, target ""
+// is the platform Builder runs on
+func exeName(name, target string) string {
+	name = strings.TrimSuffix(name, ".exe")
+	if isWindowsTarget(target) || (target == "" && runtime.GOOS == "windows") {
+		name += ".exe"
+	}
+
+	return name
+}
+
+func isWindowsTarget(target string) bool {
+	return strings.HasPrefix(target, "windows/") || strings.Contains(target, "-windows")
+}
+
+// renameTargetArtifact gives the artifact a target's build left at path its name with the
+// target's suffix, so it's collected under that name, and records the target on it.  "" is
+// returned if there's no file at path.
+func renameTargetArtifact(bc *utils.BuildContext, path, target string, info utils.ArtifactInfo) (string, error) {
+	if _, err := os.Stat(path); err != nil {
+		spinner.LogMessage(fmt.Sprintf("Could not find %s built for target %s", filepath.Base(path), target), "warn")
+		return "", nil
+	}
+
+	renamed := filepath.Join(filepath.Dir(path), targetArtifactName(filepath.Base(path), target))
+	if renamed != path {
+		if err := os.Rename(path, renamed); err != nil {
+			return "", fmt.Errorf("could not rename the artifact built for target %s: %w", target, err)
+		}
+	}
+
+	info.Target = target
+	bc.SetArtifactInfo(renamed, info)
+
+	return renamed, nil
+}
@@ -0,0 +1,167 @@
+package compile
+
+import (
+	"Builder/spinner"
+	"Builder/testresult"
+	"Builder/utils"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// JUnit reports searched for in the build dir when no testresults are given in the builder.yaml
+var defaultTestResults = []string{
+	"**/surefire-reports/TEST-*.xml",
+	"**/test-results/**/TEST-*.xml",
+}
+
+// testReportPath is where default test commands that can write a JUnit report put it
+func testReportPath(bc *utils.BuildContext) string {
+	return filepath.Join(bc.LogsDir, "junit.xml")
+}
+
+// testsEnabled reports whether the test stage runs: it is turned on by the --test flag, or by
+// test or testcmd in the builder.yaml
+func testsEnabled(bc *utils.BuildContext) bool {
+	return bc.Flags.Test || bc.Config.Test || len(bc.Config.TestCmd) > 0
+}
+
+// runTests runs the testcmd from the builder.yaml, or the compiler's default test command, in
+// the build dir.  The results are read from the command's output (go test -json, TAP, cargo
+// test) and from JUnit reports, and stored in bc.Tests.  An error is returned if the command
+// fails or any test failed.
+func runTests(bc *utils.BuildContext, c Compiler) error {
+	if !testsEnabled(bc) {
+		return nil
+	}
+
+	type testCommand struct {
+		command   string
+		env, args []string
+	}
+
+	var commands []testCommand
+	for _, command := range bc.Config.TestCmd {
+		env, args, err := bc.CommandArgs(command, nil)
+		if err != nil {
+			return fmt.Errorf("testcmd %q: %w", command, err)
+		}
+		commands = append(commands, testCommand{command, env, args})
+	}
+
+	if len(commands) == 0 {
+		args := c.DefaultTestCommand(bc)
+		if len(args) == 0 {
+			spinner.LogMessage("No default test command for "+c.ProjectType()+" projects, please provide a testcmd in the builder.yaml", "warn")
+			return nil
+		}
+		commands = append(commands, testCommand{utils.QuoteCommand(args), nil, args})
+	}
+
+	spinner.LogMessage("Running tests...", "info")
+
+	var lines []string
+	var commandStrings []string
+	start := time.Now()
+	var testErr error
+	for _, tc := range commands {
+		commandStrings = append(commandStrings, tc.command)
+		bc.Logger.Info("$ " + tc.command)
+
+		// keep the output so the test results can be read from it
+		testErr = runCommand(bc, bc.BuildDir, tc.env, tc.args, 0, func(line string) {
+			lines = append(lines, line)
+		})
+		if testErr != nil {
+			break
+		}
+	}
+
+	summary := &testresult.Summary{Command: strings.Join(commandStrings, " && ")}
+	summary.Add(testresult.ParseOutput(lines))
+
+	reports, err := testReports(bc)
+	if err != nil {
+		return err
+	}
+	for _, report := range reports {
+		cases, err := readJUnit(report)
+		if err != nil {
+			spinner.LogMessage(err.Error(), "warn")
+			continue
+		}
+		summary.Add(testresult.FormatJUnit, cases)
+	}
+
+	summary.Finish(time.Since(start).Round(time.Millisecond).Seconds(), testErr)
+	bc.Tests = summary
+
+	spinner.LogMessage(fmt.Sprintf("Tests %s: %d passed, %d failed, %d skipped", summary.Status, summary.Passed, summary.Failed, summary.Skipped), "info")
+
+	if summary.Failed > 0 {
+		failed := fmt.Sprintf("%d test(s) failed: %s", summary.Failed, strings.Join(summary.Failures, ", "))
+		if testErr != nil {
+			// keep the command's error so its exit code is recorded
+			return fmt.Errorf("%s: %w", failed, testErr)
+		}
+		return errors.New(failed)
+	}
+
+	return testErr
+}
+
+// testReports returns the JUnit reports matching the testresults patterns of the builder.yaml
+// (or the default patterns), plus the report written by the default test command if any
+func testReports(bc *utils.BuildContext) ([]string, error) {
+	patterns := defaultTestResults
+	if bc.Config.TestResults != "" {
+		patterns = strings.Split(bc.Config.TestResults, ",")
+	}
+
+	var reports []string
+	if _, err := os.Stat(testReportPath(bc)); err == nil {
+		reports = append(reports, testReportPath(bc))
+	}
+
+	err := filepath.Walk(bc.BuildDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(bc.BuildDir, path)
+		if err != nil {
+			return err
+		}
+
+		for _, pattern := range patterns {
+			if testresult.MatchGlob(strings.TrimSpace(pattern), filepath.ToSlash(rel)) {
+				reports = append(reports, path)
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not search build dir for test results: %w", err)
+	}
+
+	return reports, nil
+}
+
+func readJUnit(path string) ([]testresult.TestCase, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	cases, err := testresult.ParseJUnit(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return cases, nil
+}
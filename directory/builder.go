@@ -2,12 +2,12 @@ package directory
 
 import (
 	"Builder/spinner"
+	"Builder/utils/dirs"
+	"fmt"
 	"os"
-	"os/user"
-	"runtime"
 )
 
-func BuilderDir(path string) (bool, error) {
+func BuilderDir(path string) error {
 	//check if file path exists, returns err = nil if file exists
 	_, err := os.Stat(path)
 
@@ -21,30 +21,19 @@ func BuilderDir(path string) (bool, error) {
 		errDir := os.Mkdir(path, 0755)
 		//should return nil once directory is made, if not, throw err
 		if errDir != nil {
-			spinner.LogMessage("failed to make directory at "+path+": "+err.Error(), "fatal")
+			return fmt.Errorf("failed to make directory at %s: %w", path, errDir)
 		}
 	}
 
-	return true, err
+	return nil
 }
 
-// MakeBuilderDir does...
-func MakeBuilderDir() {
-	if runtime.GOOS != "windows" {
-		user, _ := user.Current()
-		homeDir := user.HomeDir
-
-		builderPath := homeDir + "/.builder"
-
-		BuilderDir(builderPath)
-	} else {
-		appDataDir := os.Getenv("LOCALAPPDATA")
-		if appDataDir == "" {
-			appDataDir = os.Getenv("APPDATA")
-		}
-
-		builderPath := appDataDir + "/Builder"
-
-		BuilderDir(builderPath)
+// MakeBuilderDir creates the application builder dir (~/.builder) that holds the build history
+func MakeBuilderDir() error {
+	builderPath, err := dirs.BuilderDir()
+	if err != nil {
+		return err
 	}
+
+	return BuilderDir(builderPath)
 }
@@ -0,0 +1,64 @@
+package directory
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"Builder/utils"
+)
+
+// CleanupCancelledBuild removes what a cancelled build left half done (the workspace, the
+// hidden copy of the repo and any partial artifacts) and renames the parent dir to include the
+// start time if the build was stopped before it got renamed.  The logs dir is kept.
+func CleanupCancelledBuild(bc *utils.BuildContext) error {
+	if bc.ParentDir == "" {
+		return nil
+	}
+
+	var partial []string
+	for _, dir := range []string{bc.WorkspaceDir, bc.HiddenDir} {
+		if dir != "" {
+			partial = append(partial, dir)
+		}
+	}
+	if bc.ArtifactDir != "" {
+		partial = append(partial, bc.ArtifactDir, bc.ArtifactDir+".zip", bc.ArtifactDir+".tar.gz")
+	}
+
+	for _, path := range partial {
+		if err := os.RemoveAll(path); err != nil {
+			return fmt.Errorf("could not remove %s: %w", path, err)
+		}
+	}
+
+	return renameUnfinishedParentDir(bc)
+}
+
+// CleanupFailedBuild renames the parent dir of a build that failed before it got renamed to
+// include the start time, so the next build doesn't reuse it and append to its logs.  What the
+// build left is kept for looking into the failure.
+func CleanupFailedBuild(bc *utils.BuildContext) error {
+	if bc.ParentDir == "" {
+		return nil
+	}
+
+	return renameUnfinishedParentDir(bc)
+}
+
+// renameUnfinishedParentDir renames the parent dir to include the start time if it's still
+// named name_name
+func renameUnfinishedParentDir(bc *utils.BuildContext) error {
+	if _, err := os.Stat(bc.ParentDir); err != nil {
+		return nil
+	}
+
+	name := utils.GetName(bc)
+	if filepath.Base(bc.ParentDir) == name+"_"+name {
+		if _, err := UpdateParentDirName(bc, bc.ParentDir); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
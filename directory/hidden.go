@@ -8,12 +8,11 @@ import (
 	"strings"
 )
 
-func hiddenDir(path string) (bool, error) {
+func hiddenDir(bc *utils.BuildContext, path string) error {
 	//check if file path exists, returns err = nil if file exists
 	_, err := os.Stat(path)
 
 	if err == nil {
-		fmt.Println("Path already exists")
 		spinner.LogMessage("Path already exists", "warn")
 	}
 
@@ -21,42 +20,37 @@ func hiddenDir(path string) (bool, error) {
 	if os.IsNotExist(err) {
 		errDir := os.Mkdir(path, 0755)
 		if errDir != nil {
-			spinner.LogMessage("failed to create hidden directory: "+err.Error(), "fatal")
+			return fmt.Errorf("failed to create hidden directory: %w", errDir)
 		}
 	}
 
-	//check workspace env exists, if not, create it
-	val, present := os.LookupEnv("BUILDER_HIDDEN_DIR")
-	if !present {
-		os.Setenv("BUILDER_HIDDEN_DIR", path)
-	} else {
-		fmt.Println("BUILDER_HIDDEN_DIR", val)
-	}
-	return true, err
+	bc.HiddenDir = path
+
+	return nil
 }
 
-// MakeHiddenDir does...
-func MakeHiddenDir(path string) {
+// MakeHiddenDir creates the dir the repo files get copied into
+func MakeHiddenDir(bc *utils.BuildContext, path string) error {
+	return hiddenDir(bc, hiddenDirPath(bc, path))
+}
 
-	if os.Getenv("HIDDEN_DIR_ENABLED") == "true" {
-		hiddenPath := path + "/.hidden"
-		hiddenDir(hiddenPath)
+// hiddenDirPath returns the path of the hidden dir inside the parent dir at path
+func hiddenDirPath(bc *utils.BuildContext, path string) string {
+	if bc.Flags.Hidden {
+		return path + "/.hidden"
+	}
+
+	repo := utils.GetRepoURL(bc)
+	var repoName string
+	if repo == "" {
+		repoName = ".hidden"
 	} else {
-		repo := utils.GetRepoURL()
-		var repoName string
-		if repo == "" {
-			repoName = ".hidden"
-		} else {
-			repoName = strings.TrimSuffix(repo[strings.LastIndex(repo, "/"):], ".git")
-		}
-		// Don't add extra slash if one exists
-		var visiblePath string
-		if strings.Contains(repoName, "/") {
-			visiblePath = path + repoName
-		} else {
-			visiblePath = path + "/" + repoName
-		}
+		repoName = strings.TrimSuffix(repo[strings.LastIndex(repo, "/"):], ".git")
+	}
 
-		hiddenDir(visiblePath)
+	// Don't add extra slash if one exists
+	if strings.Contains(repoName, "/") {
+		return path + repoName
 	}
+	return path + "/" + repoName
 }
@@ -2,16 +2,16 @@ package directory
 
 import (
 	"Builder/spinner"
+	"Builder/utils"
 	"fmt"
 	"os"
 )
 
-func logDir(path string) (bool, error) {
+func logDir(bc *utils.BuildContext, path string) error {
 	//check if file path exists, returns err = nil if file exists
 	_, err := os.Stat(path)
 
 	if err == nil {
-		fmt.Println("Path already exists")
 		spinner.LogMessage("Path already exists", "info")
 	}
 
@@ -21,23 +21,22 @@ func logDir(path string) (bool, error) {
 		errDir := os.Mkdir(path, 0755)
 		//should return nil once directory is made, if not, throw err
 		if errDir != nil {
-			spinner.LogMessage("failed to make directory: "+err.Error(), "fatal")
+			return fmt.Errorf("failed to make directory at %s: %w", path, errDir)
 		}
 
 	}
 
-	//check workspace env exists, if not, create it
-	val, present := os.LookupEnv("BUILDER_LOGS_DIR")
-	if !present {
-		os.Setenv("BUILDER_LOGS_DIR", path)
-	} else {
-		fmt.Println("BUILDER_LOGS_DIR", val)
-	}
-	return true, err
+	bc.LogsDir = path
+
+	return nil
+}
+
+// MakeLogsDir creates the dir the build logs are written to
+func MakeLogsDir(bc *utils.BuildContext, path string) error {
+	return logDir(bc, logsDirPath(path))
 }
 
-// MakeLogsDir does...
-func MakeLogsDir(path string) {
-	visiblePath := path + "/logs"
-	logDir(visiblePath)
+// logsDirPath returns the path of the logs dir inside the parent dir at path
+func logsDirPath(path string) string {
+	return path + "/logs"
 }
@@ -3,66 +3,94 @@ package directory
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
-	"time"
 
 	"Builder/spinner"
 	"Builder/utils"
 )
 
-// MakeDirs does...
-func MakeDirs() {
+// MakeDirs creates the parent, hidden, workspace and logs dirs for the build
+func MakeDirs(bc *utils.BuildContext) error {
+	path := ParentDirPath(bc)
+
+	if err := MakeParentDir(bc, path); err != nil {
+		return err
+	}
+
+	if err := MakeHiddenDir(bc, path); err != nil {
+		return err
+	}
+	if err := MakeWorkspaceDir(bc, path); err != nil {
+		return err
+	}
+
+	if err := MakeLogsDir(bc, path); err != nil {
+		return err
+	}
+	return MakeBuilderDir()
+}
+
+// PlanDirs sets the parent, hidden, workspace and logs dirs of the build to the paths MakeDirs
+// would create, without creating them
+func PlanDirs(bc *utils.BuildContext) {
+	path := ParentDirPath(bc)
+
+	bc.ParentDir = path
+	bc.HiddenDir = hiddenDirPath(bc, path)
+	bc.WorkspaceDir = workspaceDirPath(path)
+	bc.LogsDir = logsDirPath(path)
+}
+
+// ParentDirPath returns the absolute path of the build's parent dir, before it is renamed to
+// include the start time
+func ParentDirPath(bc *utils.BuildContext) string {
 	//handles -n flag
-	name := utils.GetName()
+	name := utils.GetName(bc)
+
+	//check for projectPath from builder.yaml
+	configPath := bc.Config.ProjectPath
 
-	//check for projectPath env from builder.yaml
-	configPath := os.Getenv("BUILDER_DIR_PATH")
+	// Check if user wants to name builder folder a different name
+	buildsDir := "builder"
+	if bc.Config.BuildsDir != "" {
+		buildsDir = bc.Config.BuildsDir
+	}
 
 	var path string
-	if os.Getenv("BUILDER_COMMAND") == "true" {
+	if bc.IsBuilderCommand() {
 		if configPath != "" {
 			path = configPath + "/" + name + "_" + name
 		} else { // Place builds in builder folder in repo
-			// Check if user wants to name builder folder a different name
-			if os.Getenv("BUILDER_BUILDS_DIR") != "" {
-				path = "./" + os.Getenv("BUILDER_BUILDS_DIR") + "/" + name + "_" + name
-			} else {
-				path = "./builder/" + name + "_" + name
-			}
+			path = "./" + buildsDir + "/" + name + "_" + name
 		}
 	} else { // builder init so create an initial repo dir
 		if configPath != "" {
-			// Check if user wants to name builder folder a different name
-			if os.Getenv("BUILDER_BUILDS_DIR") != "" {
-				path = configPath + "/" + name + "/" + os.Getenv("BUILDER_BUILDS_DIR") + "/" + name + "_" + name
-			} else {
-				path = configPath + "/" + name + "/builder/" + name + "_" + name
-			}
+			path = configPath + "/" + name + "/" + buildsDir + "/" + name + "_" + name
 		} else {
-			// Check if user wants to name builder folder a different name
-			if os.Getenv("BUILDER_BUILDS_DIR") != "" {
-				path = "./" + name + "/" + os.Getenv("BUILDER_BUILDS_DIR") + "/" + name + "_" + name
-			} else {
-				path = "./" + name + "/builder/" + name + "_" + name
-			}
+			path = "./" + name + "/" + buildsDir + "/" + name + "_" + name
 		}
 	}
 
-	MakeParentDir(path)
+	absPath, err := filepath.Abs(path)
+	if err == nil {
+		path = absPath
+	}
 
-	MakeHiddenDir(path)
-	MakeWorkspaceDir(path)
+	return path
+}
 
-	MakeLogsDir(path)
-	MakeBuilderDir()
+// FinishedParentDirPath returns the path the parent dir is renamed to once the project is built
+func FinishedParentDirPath(bc *utils.BuildContext) string {
+	return strings.TrimSuffix(bc.ParentDir, utils.GetName(bc)) + strconv.FormatInt(bc.StartTime.Unix(), 10)
 }
 
-func MakeParentDir(path string) (bool, error) {
+func MakeParentDir(bc *utils.BuildContext, path string) error {
 	//check if file path exists, returns err = nil if file exists
 	_, err := os.Stat(path)
 
 	if err == nil {
-		fmt.Println("Path already exists")
 		spinner.LogMessage("Path already exists", "info")
 	}
 
@@ -71,63 +99,41 @@ func MakeParentDir(path string) (bool, error) {
 		errDir := os.MkdirAll(path, 0755)
 		//should return nil once directory is made, if not, throw err
 		if errDir != nil {
-			spinner.LogMessage("failed to create directory at "+path+": "+err.Error(), "fatal")
+			return fmt.Errorf("failed to create directory at %s: %w", path, errDir)
 		}
 	}
 
-	//check workspace env exists, if not, create it
-	val, present := os.LookupEnv("BUILDER_PARENT_DIR")
-	if !present {
-		os.Setenv("BUILDER_PARENT_DIR", path)
-	} else {
-		fmt.Println("BUILDER_PARENT_DIR", val)
-	}
+	bc.ParentDir = path
 
-	return true, err
+	return nil
 }
 
-func UpdateParentDirName(pathWithWrongParentName string) string {
-	oldName, _ := os.LookupEnv("BUILDER_PARENT_DIR")
-	projectName := utils.GetName()
-	startTime, _ := time.Parse(time.RFC850, os.Getenv("BUILD_START_TIME"))
-	unixTimestamp := startTime.Unix()
-	newName := strings.TrimSuffix(oldName, projectName) + fmt.Sprint(unixTimestamp)
-
-	path := os.Getenv("BUILDER_DIR_PATH")
-
-	// user using builder command and did not provide a path to build in
-	if os.Getenv("BUILDER_COMMAND") == "true" && path == "" {
-		wdPath, err := os.Getwd()
-		if err != nil {
-			spinner.LogMessage("error getting builder working directory", "error")
-		}
-
-		path = wdPath
-	}
+// UpdateParentDirName renames the parent dir to include the build start time and
+// returns pathWithWrongParentName updated to point inside the renamed dir
+func UpdateParentDirName(bc *utils.BuildContext, pathWithWrongParentName string) (string, error) {
+	oldName := bc.ParentDir
+	newName := FinishedParentDirPath(bc)
 
-	if oldName[0:2] == "./" {
-		err := os.Rename(path+"/"+oldName[2:], path+"/"+newName[2:])
-		if err != nil {
-			fmt.Println(err.(*os.LinkError).Err)
-			spinner.LogMessage("could not rename parent dir", "fatal")
-		}
-	} else {
-		err := os.Rename(oldName, newName)
-		if err != nil {
-			fmt.Println(err.(*os.LinkError).Err)
-			spinner.LogMessage("could not rename parent dir", "fatal")
-		}
+	err := os.Rename(oldName, newName)
+	if err != nil {
+		return "", fmt.Errorf("could not rename parent dir: %w", err)
 	}
 
-	// Update env vars to include new parent folder name
-	name := utils.GetName()
-	os.Setenv("BUILDER_PARENT_DIR", newName)
-	os.Setenv("BUILDER_HIDDEN_DIR", newName+"/"+name)
-	os.Setenv("BUILDER_WORKSPACE_DIR", newName+"/workspace")
-	os.Setenv("BUILDER_LOGS_DIR", newName+"/logs")
+	// Update context to include new parent folder name
+	bc.ParentDir = newName
+	bc.HiddenDir = replaceParent(bc.HiddenDir, oldName, newName)
+	bc.WorkspaceDir = replaceParent(bc.WorkspaceDir, oldName, newName)
+	bc.LogsDir = replaceParent(bc.LogsDir, oldName, newName)
 
 	// Return new path with new parent directory name
-	newPath := strings.Replace(pathWithWrongParentName, oldName[2:], newName[2:], 1)
+	return replaceParent(pathWithWrongParentName, oldName, newName), nil
+}
+
+// replaceParent swaps the oldParent prefix of path with newParent
+func replaceParent(path string, oldParent string, newParent string) string {
+	if strings.HasPrefix(path, oldParent) {
+		return newParent + strings.TrimPrefix(path, oldParent)
+	}
 
-	return newPath
+	return path
 }
@@ -2,16 +2,16 @@ package directory
 
 import (
 	"Builder/spinner"
+	"Builder/utils"
 	"fmt"
 	"os"
 )
 
-func workSpaceDir(path string) (bool, error) {
+func workSpaceDir(bc *utils.BuildContext, path string) error {
 	//check if file path exists, returns err = nil if file exists
 	_, err := os.Stat(path)
 
 	if err == nil {
-		fmt.Println("Path already exists")
 		spinner.LogMessage("Path already exists", "info")
 	}
 
@@ -21,25 +21,21 @@ func workSpaceDir(path string) (bool, error) {
 		errDir := os.Mkdir(path, 0755)
 		//should return nil once directory is made, if not, throw err
 		if errDir != nil {
-			spinner.LogMessage("failed to create directory at "+path+": "+err.Error(), "fatal")
+			return fmt.Errorf("failed to create directory at %s: %w", path, errDir)
 		}
 	}
 
-	//check workspace env exists, if not, create it
-	val, present := os.LookupEnv("BUILDER_WORKSPACE_DIR")
-	if !present {
-		os.Setenv("BUILDER_WORKSPACE_DIR", path)
-	} else {
-		fmt.Println("BUILDER_WORKSPACE_DIR", val)
-	}
-	return true, err
-}
-
-// MakeWorkspaceDir does...
-func MakeWorkspaceDir(path string) {
+	bc.WorkspaceDir = path
 
-	workPath := path + "/workspace"
+	return nil
+}
 
-	workSpaceDir(workPath)
+// MakeWorkspaceDir creates the dir the project gets built in
+func MakeWorkspaceDir(bc *utils.BuildContext, path string) error {
+	return workSpaceDir(bc, workspaceDirPath(path))
+}
 
+// workspaceDirPath returns the path of the workspace dir inside the parent dir at path
+func workspaceDirPath(path string) string {
+	return path + "/workspace"
 }
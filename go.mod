@@ -6,12 +6,11 @@ require (
 	github.com/kr/text v0.2.0 // indirect
 	github.com/manifoldco/promptui v0.8.0
 	github.com/niemeyer/pretty v0.0.0-20200227124842-a10e7caefd8e // indirect
-	github.com/otiai10/copy v1.12.0 // indirect
+	github.com/otiai10/copy v1.12.0
 	github.com/theckman/yacspin v0.13.12
 	github.com/zserge/lorca v0.1.10
 	go.uber.org/zap v1.24.0
 	golang.org/x/text v0.12.0
 	gopkg.in/check.v1 v1.0.0-20200227125254-8fa46927fb4f // indirect
-	gopkg.in/yaml.v2 v2.4.0
 	gopkg.in/yaml.v3 v3.0.1
 )
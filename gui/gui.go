@@ -1,14 +1,15 @@
 package gui
 
 import (
+	"Builder/history"
 	"bufio"
 	_ "embed"
 	"encoding/base64"
+	"encoding/json"
 	"log"
 	"net/url"
 	"os"
 	"regexp"
-	"runtime"
 	"strings"
 
 	"github.com/zserge/lorca"
@@ -87,46 +88,35 @@ type Build struct {
 	MasterGitHash    string `json:"MasterGitHash"`
 	BranchName       string `json:"BranchName"`
 	BuildHash        string `json:"BuildHash"`
+	Status           string `json:"Status"`
+	Step             string `json:"Step"`
+	ExitCode         int    `json:"ExitCode"`
+	Error            string `json:"Error"`
 }
 
 func Gui() {
 
 	// Read in json data
 	getBuildsJSON := func() string {
-		// Read in builds JSON data from application builder folder
-		var buildsPath string
-		if runtime.GOOS == "windows" {
-			appDataDir := os.Getenv("LOCALAPPDATA")
-			if appDataDir == "" {
-				appDataDir = os.Getenv("APPDATA")
-			}
-
-			buildsPath = appDataDir + "/Builder/builds.json"
-		} else {
-			homeDir, err := os.UserHomeDir()
-			if err != nil {
-				log.Fatal(err)
-			}
-
-			buildsPath = homeDir + "/.builder/builds.json"
+		// Read in builds from the build history in the application builder folder
+		store, err := history.OpenDefault()
+		if err != nil {
+			log.Fatal(err)
 		}
 
-		buildsJSON, err := os.ReadFile(buildsPath)
+		buildsJSON, err := json.Marshal(store.All())
 		if err != nil {
 			log.Fatal(err)
 		}
 
-		FormattedBuildsJSON := strings.TrimSuffix(string(buildsJSON), ",\n")
-
-		FormattedBuildsJSON = "[" + FormattedBuildsJSON + "]"
-
-		return FormattedBuildsJSON
+		return string(buildsJSON)
 	}
 
 	getLogsJSON := func(path string) string {
 		logsFile, err := os.Open(path)
 		if err != nil {
-			log.Fatal(err)
+			// Builds that failed early have no logs
+			return "[]"
 		}
 		defer logsFile.Close()
 
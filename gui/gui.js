@@ -61,12 +61,12 @@ function createBuildsListTable(buildsJSON) {
         text += "<tr>"
 
         let tdString = "<td class='buildsListTableCell' onclick='displayDetailsPage(`" + builds[build].BuildID + "`)'>"
-        let dateObj = Date.parse(builds[build].EndTime)
+        let dateObj = Date.parse(builds[build].EndTime || builds[build].StartTime)
         let time = new Date(dateObj).toUTCString();
 
         // Determine what language logo to show
         let image
-        switch (builds[build].ProjectType.toLowerCase()) {
+        switch ((builds[build].ProjectType || "").toLowerCase()) {
             case "c":
                 image = "<img src='' alt='C Logo' class='c_logo' width='30' height='30'>"
                 break;
@@ -99,9 +99,9 @@ function createBuildsListTable(buildsJSON) {
         // Fill in table data
         text += tdString + time + "</td>"
         text += tdString + builds[build].UserName + "</td>"
-        text += tdString + builds[build].ArtifactName + "</td>"
+        text += tdString + (builds[build].ArtifactName || buildStatus(builds[build])) + "</td>"
         text += tdString + builds[build].ProjectName + "</td>"
-        text += tdString + builds[build].MasterGitHash.slice(0,7) + "</td>"
+        text += tdString + (builds[build].MasterGitHash || "").slice(0,7) + "</td>"
         text += tdString + builds[build].BuildID + "</td>"
 
         text += "</tr>"
@@ -110,6 +110,11 @@ function createBuildsListTable(buildsJSON) {
     return text
 }
 
+// Builds recorded before statuses were added all succeeded
+function buildStatus(build) {
+    return build.Status || "succeeded";
+}
+
 const renderBuildsList = async () => {
     // Render builds data into table
     let buildsJSON = await getBuildsJSON();
@@ -243,10 +248,32 @@ async function displayDetailsData(buildID) {
 
     document.getElementById("projectName").innerHTML = build.ProjectName;
 
-    let dateObj = Date.parse(build.EndTime)
+    let dateObj = Date.parse(build.EndTime || build.StartTime)
     let time = new Date(dateObj).toUTCString();
     document.getElementById("timestamp").innerHTML = time;
 
+    // Display status, and where and why the build stopped if it didn't succeed
+    let status = buildStatus(build);
+    document.getElementById("status").innerHTML = status;
+    let failureRows = document.getElementsByClassName("failureRow");
+    for (let i = 0; i < failureRows.length; i++) {
+        failureRows[i].style.display = (status == "failed" || status == "cancelled") ? "" : "none";
+    }
+    document.getElementById("failedStep").innerHTML = build.Step || "";
+    document.getElementById("exitCode").innerHTML = build.ExitCode || "";
+    document.getElementById("buildError").innerHTML = build.Error || "";
+
+    // Display the test summary if the test stage ran
+    let tests = build.Tests;
+    let testsRows = document.getElementsByClassName("testsRow");
+    for (let i = 0; i < testsRows.length; i++) {
+        testsRows[i].style.display = tests ? "" : "none";
+    }
+    if (tests) {
+        document.getElementById("tests").innerHTML = tests.status + ": " + tests.passed + " passed, " + tests.failed + " failed, " + tests.skipped + " skipped (" + tests.durationSeconds + "s)";
+        document.getElementById("failingTests").innerHTML = (tests.failures || []).join("<br>") || "none";
+    }
+
     // Display metadata
     document.getElementById("projectType").innerHTML = build.ProjectType;
     document.getElementById("username").innerHTML = build.UserName;
@@ -260,7 +287,7 @@ async function displayDetailsData(buildID) {
     // Display artifact(s)
     let text = "";
 
-    let artifactArray = build.ArtifactName.split(",")
+    let artifactArray = (build.ArtifactName || "").split(",")
 
     for (artifact in artifactArray) {
         text += "</tr>"
@@ -0,0 +1,70 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// migrateBuildsJSON moves the builds from an old builds.json (json objects each followed by
+// ",\n") into the history.  builds.json is renamed afterwards so it is only migrated once.  It
+// runs under the lock, so of two Builders opening the history for the first time only one
+// migrates and the other finds builds.json gone.
+func (s *Store) migrateBuildsJSON(buildsJSONPath string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// builds another Builder added while this one waited are in the file, not the index
+	if err := s.load(); err != nil {
+		return err
+	}
+
+	buildsJSON, err := os.ReadFile(buildsJSONPath)
+	if os.IsNotExist(err) {
+		// migrated by another Builder while this one waited for the lock
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not read builds.json file: %w", err)
+	}
+
+	var builds []Build
+	seen := map[string]bool{}
+	for _, entry := range strings.Split(string(buildsJSON), ",\n") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+
+		var build Build
+		if err := json.Unmarshal([]byte(entry), &build); err != nil || build.ID() == "" {
+			// skip entries broken by an interrupted write
+			continue
+		}
+
+		// don't overwrite a build already in the history
+		if _, ok := s.index[build.ID()]; ok || seen[build.ID()] {
+			continue
+		}
+		seen[build.ID()] = true
+		builds = append(builds, build)
+	}
+
+	if len(builds) > 0 {
+		if err := s.appendBuilds(builds); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Rename(buildsJSONPath, buildsJSONPath+".migrated"); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("could not rename migrated builds.json file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,402 @@
+package history
+
+import (
+	"Builder/utils/dirs"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// file name of the history inside the application builder dir
+const historyFile = "history.jsonl"
+
+// StartedAt is the key of the build's start time as an RFC 3339 UTC timestamp, which sorts and
+// parses without the ambiguity of the RFC 850 StartTime shown to users
+const StartedAt = "StartedAt"
+
+// Build is one build's entry in the history.  It holds the fields of the build's
+// metadata.json plus the fields Builder records about the build itself (BuildID, Status,
+// Step, ExitCode, ...).
+type Build map[string]interface{}
+
+// ID returns the build's BuildID
+func (b Build) ID() string {
+	return b.Get("BuildID")
+}
+
+// Get returns the value of key as a string, or "" if it isn't set
+func (b Build) Get(key string) string {
+	switch value := b[key].(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// Status returns the build's status.  Builds recorded before statuses were added all succeeded.
+func (b Build) Status() string {
+	if status := b.Get("Status"); status != "" {
+		return status
+	}
+
+	return "succeeded"
+}
+
+// StartTime returns the time the build started, or the zero time if it isn't known.  Builds
+// recorded before StartedAt was added only have the RFC 850 StartTime, its zone is read as
+// the local one.
+func (b Build) StartTime() time.Time {
+	if startedAt, err := time.Parse(time.RFC3339, b.Get(StartedAt)); err == nil {
+		return startedAt
+	}
+
+	startTime, _ := time.ParseInLocation(time.RFC850, b.Get("StartTime"), time.Local)
+	return startTime
+}
+
+// setStartedAt sets the StartedAt of a build recorded without one from its StartTime
+func (b Build) setStartedAt() {
+	if _, ok := b[StartedAt]; ok {
+		return
+	}
+	if startTime := b.StartTime(); !startTime.IsZero() {
+		b[StartedAt] = startTime.UTC().Format(time.RFC3339)
+	}
+}
+
+// Query filters the builds returned by Store.Query.  Empty fields match every build.
+type Query struct {
+	ProjectName string
+	BranchName  string
+	UserName    string
+	Status      string
+	// Since and Until bound the build's start time, builds without a known start time are left
+	// out when either is set
+	Since time.Time
+	Until time.Time
+}
+
+// Store is the local build history.  Builds are appended to a JSON Lines file, one line per
+// insert, and fsynced so a crash can lose at most the line being written.  The latest line of
+// each BuildID wins.  An index of the builds is kept in memory for lookups and queries.
+type Store struct {
+	path  string
+	mu    sync.Mutex
+	index map[string]Build
+	order []string
+	// stale counts the lines in the file replaced by a later line with the same BuildID
+	stale int
+}
+
+// OpenDefault opens the history in the application builder dir, migrating an old
+// builds.json into it the first time
+func OpenDefault() (*Store, error) {
+	dir, err := dirs.BuilderDir()
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := Open(filepath.Join(dir, historyFile))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := store.migrateBuildsJSON(filepath.Join(dir, "builds.json")); err != nil {
+		return nil, err
+	}
+
+	// Every build writes a running entry that gets replaced, drop them once there are as many
+	// of them as builds
+	if store.stale > 0 && store.stale >= len(store.index) {
+		if err := store.Compact(); err != nil {
+			return nil, err
+		}
+	}
+
+	return store, nil
+}
+
+// Open reads the history at path into memory, creating the file's dir if needed.  Lines that
+// can't be parsed (a write cut short by a crash) are skipped.
+func Open(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("could not create history dir: %w", err)
+	}
+
+	store := &Store{path: path}
+	if err := store.load(); err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// load reads the history file into the in-memory index, replacing what was in it
+func (s *Store) load() error {
+	s.index = map[string]Build{}
+	s.order = nil
+	s.stale = 0
+
+	historyFile, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not open build history: %w", err)
+	}
+	defer historyFile.Close()
+
+	reader := bufio.NewReader(historyFile)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var build Build
+			if json.Unmarshal(line, &build) == nil && build.ID() != "" {
+				s.add(build)
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("could not read build history: %w", err)
+		}
+	}
+}
+
+// lock takes the lock other Builder processes honor while they append to or rewrite the
+// history file, it's a separate file since Compact replaces the history file
+func (s *Store) lock() (unlock func(), err error) {
+	lockFile, err := os.OpenFile(s.path+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("could not open build history lock: %w", err)
+	}
+	if err := lockExclusive(lockFile); err != nil {
+		lockFile.Close()
+		return nil, fmt.Errorf("could not lock build history: %w", err)
+	}
+
+	return func() {
+		unlockFile(lockFile)
+		lockFile.Close()
+	}, nil
+}
+
+// Path returns the path of the history file
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Insert adds build to the history, replacing the build with the same BuildID if there is one
+func (s *Store) Insert(build Build) error {
+	if build.ID() == "" {
+		return errors.New("build has no BuildID")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return s.appendBuilds([]Build{build})
+}
+
+// appendBuilds writes builds to the end of the history file and adds them to the index.  The
+// caller holds s.mu and the lock.
+func (s *Store) appendBuilds(builds []Build) error {
+	var line []byte
+	for _, build := range builds {
+		build.setStartedAt()
+		entry, err := json.Marshal(build)
+		if err != nil {
+			return fmt.Errorf("could not create build history entry: %w", err)
+		}
+		line = append(append(line, entry...), '\n')
+	}
+
+	historyFile, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Errorf("could not open build history: %w", err)
+	}
+	defer historyFile.Close()
+
+	// If the last write was cut short start on a new line so this entry isn't lost with it
+	if info, err := historyFile.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := historyFile.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			line = append([]byte("\n"), line...)
+		}
+	}
+
+	// One write per insert so concurrent builds don't interleave lines
+	if _, err := historyFile.Write(line); err != nil {
+		return fmt.Errorf("could not write to build history: %w", err)
+	}
+	if err := historyFile.Sync(); err != nil {
+		return fmt.Errorf("could not sync build history: %w", err)
+	}
+
+	for _, build := range builds {
+		s.add(build)
+	}
+
+	return nil
+}
+
+// Lookup returns the build with BuildID id.  A unique prefix of the id is also accepted.
+func (s *Store) Lookup(id string) (Build, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if build, ok := s.index[id]; ok {
+		return build, true
+	}
+
+	var match Build
+	for buildID, build := range s.index {
+		if id != "" && strings.HasPrefix(buildID, id) {
+			if match != nil {
+				return nil, false
+			}
+			match = build
+		}
+	}
+
+	return match, match != nil
+}
+
+// Query returns the builds matching q, oldest first
+func (s *Store) Query(q Query) []Build {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	builds := []Build{}
+	for _, id := range s.order {
+		build := s.index[id]
+		if q.matches(build) {
+			builds = append(builds, build)
+		}
+	}
+
+	sort.SliceStable(builds, func(i, j int) bool {
+		return builds[i].StartTime().Before(builds[j].StartTime())
+	})
+
+	return builds
+}
+
+// All returns every build in the history, oldest first
+func (s *Store) All() []Build {
+	return s.Query(Query{})
+}
+
+// Compact rewrites the history file with only the latest entry of each build, giving the
+// builds recorded before StartedAt was added one.  The file is read again under the lock, so
+// builds other processes added since it was opened are kept, and the new file is written
+// next to the old one, synced and renamed over it.
+func (s *Store) Compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+
+	var buffer bytes.Buffer
+	for _, id := range s.order {
+		s.index[id].setStartedAt()
+		line, err := json.Marshal(s.index[id])
+		if err != nil {
+			return fmt.Errorf("could not create build history entry: %w", err)
+		}
+		buffer.Write(append(line, '\n'))
+	}
+
+	tempPath := s.path + ".tmp"
+	tempFile, err := os.OpenFile(tempPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("could not compact build history: %w", err)
+	}
+	if _, err := tempFile.Write(buffer.Bytes()); err != nil {
+		tempFile.Close()
+		return fmt.Errorf("could not compact build history: %w", err)
+	}
+	if err := tempFile.Sync(); err != nil {
+		tempFile.Close()
+		return fmt.Errorf("could not compact build history: %w", err)
+	}
+	if err := tempFile.Close(); err != nil {
+		return fmt.Errorf("could not compact build history: %w", err)
+	}
+
+	if err := os.Rename(tempPath, s.path); err != nil {
+		return fmt.Errorf("could not compact build history: %w", err)
+	}
+
+	s.stale = 0
+
+	return nil
+}
+
+// add puts build in the in-memory index
+func (s *Store) add(build Build) {
+	if _, ok := s.index[build.ID()]; ok {
+		s.stale++
+	} else {
+		s.order = append(s.order, build.ID())
+	}
+	s.index[build.ID()] = build
+}
+
+func (q Query) matches(build Build) bool {
+	if q.ProjectName != "" && !strings.EqualFold(build.Get("ProjectName"), q.ProjectName) {
+		return false
+	}
+	if q.BranchName != "" && build.Get("BranchName") != q.BranchName {
+		return false
+	}
+	if q.UserName != "" && build.Get("UserName") != q.UserName {
+		return false
+	}
+	if q.Status != "" && !strings.EqualFold(build.Status(), q.Status) {
+		return false
+	}
+
+	if q.Since.IsZero() && q.Until.IsZero() {
+		return true
+	}
+	startTime := build.StartTime()
+	if startTime.IsZero() {
+		return false
+	}
+	if !q.Since.IsZero() && startTime.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && startTime.After(q.Until) {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,260 @@
+package history
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func openTemp(t *testing.T) *Store {
+	t.Helper()
+	store, err := Open(filepath.Join(t.TempDir(), "history.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func countLines(t *testing.T, path string) int {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bytes.Count(data, []byte("\n"))
+}
+
+func TestInsertLookup(t *testing.T) {
+	store := openTemp(t)
+	for _, build := range []Build{
+		{"BuildID": "abc123", "Status": "running"},
+		{"BuildID": "abd456", "Status": "succeeded"},
+		{"BuildID": "abc123", "Status": "failed"},
+	} {
+		if err := store.Insert(build); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		id     string
+		found  bool
+		status string
+	}{
+		{"abc123", true, "failed"},
+		{"abd", true, "succeeded"},
+		{"ab", false, ""},
+		{"zzz", false, ""},
+		{"", false, ""},
+	}
+	for _, test := range tests {
+		build, ok := store.Lookup(test.id)
+		if ok != test.found || (ok && build.Status() != test.status) {
+			t.Errorf("Lookup(%q) = %v, %v, want status %q, %v", test.id, build, ok, test.status, test.found)
+		}
+	}
+
+	// a reopened store sees the latest entry of each build
+	reopened, err := Open(store.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(reopened.All()); got != 2 {
+		t.Errorf("reopened store has %d builds, want 2", got)
+	}
+	if build, _ := reopened.Lookup("abc123"); build.Status() != "failed" {
+		t.Errorf("reopened abc123 has status %q, want failed", build.Status())
+	}
+}
+
+func TestOpenTruncatedLastLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	content := `{"BuildID":"one","Status":"succeeded"}` + "\n" + `{"BuildID":"two","Sta`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(store.All()); got != 1 {
+		t.Fatalf("store has %d builds, want 1", got)
+	}
+
+	// the next entry starts on a line of its own instead of being glued to the broken one
+	if err := store.Insert(Build{"BuildID": "three"}); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"one", "three"} {
+		if _, ok := reopened.Lookup(id); !ok {
+			t.Errorf("build %s is missing after reopening", id)
+		}
+	}
+}
+
+func TestCompact(t *testing.T) {
+	store := openTemp(t)
+	for _, status := range []string{"running", "succeeded"} {
+		for _, id := range []string{"one", "two"} {
+			if err := store.Insert(Build{"BuildID": id, "Status": status}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// another process adds a build after this store was opened
+	other, err := Open(store.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Insert(Build{"BuildID": "three", "Status": "succeeded"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if got := countLines(t, store.Path()); got != 3 {
+		t.Errorf("compacted history has %d lines, want 3", got)
+	}
+	if store.stale != 0 {
+		t.Errorf("stale = %d after compacting, want 0", store.stale)
+	}
+
+	reopened, err := Open(store.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"one", "two", "three"} {
+		build, ok := reopened.Lookup(id)
+		if !ok || build.Status() != "succeeded" {
+			t.Errorf("build %s = %v, %v after compacting, want succeeded", id, build, ok)
+		}
+	}
+}
+
+func TestMigrateBuildsJSON(t *testing.T) {
+	dir := t.TempDir()
+	buildsJSON := filepath.Join(dir, "builds.json")
+	content := `{"BuildID":"one","ProjectName":"a"},` + "\n" +
+		`{"BuildID":"two","ProjectName":"b"},` + "\n" +
+		`{"BuildID":"thr`
+	if err := os.WriteFile(buildsJSON, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := Open(filepath.Join(dir, "history.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Insert(Build{"BuildID": "two", "ProjectName": "newer"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.migrateBuildsJSON(buildsJSON); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := len(store.All()); got != 2 {
+		t.Errorf("store has %d builds after migrating, want 2", got)
+	}
+	if build, _ := store.Lookup("two"); build.Get("ProjectName") != "newer" {
+		t.Errorf("migration replaced build two with %v", build)
+	}
+	if _, err := os.Stat(buildsJSON); !os.IsNotExist(err) {
+		t.Errorf("builds.json is still there after migrating")
+	}
+	if _, err := os.Stat(buildsJSON + ".migrated"); err != nil {
+		t.Errorf("builds.json wasn't renamed: %v", err)
+	}
+
+	// a second run finds nothing to migrate
+	if err := store.migrateBuildsJSON(buildsJSON); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// two Builders opening the history for the first time migrate builds.json once between them
+func TestMigrateBuildsJSONConcurrently(t *testing.T) {
+	dir := t.TempDir()
+	buildsJSON := filepath.Join(dir, "builds.json")
+	content := `{"BuildID":"one"},` + "\n" + `{"BuildID":"two"},` + "\n"
+	if err := os.WriteFile(buildsJSON, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	stores := make([]*Store, 4)
+	for i := range stores {
+		store, err := Open(filepath.Join(dir, "history.jsonl"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		stores[i] = store
+	}
+
+	errs := make(chan error, len(stores))
+	for _, store := range stores {
+		go func(store *Store) {
+			errs <- store.migrateBuildsJSON(buildsJSON)
+		}(store)
+	}
+	for range stores {
+		if err := <-errs; err != nil {
+			t.Errorf("migrateBuildsJSON() error = %v", err)
+		}
+	}
+
+	if got := countLines(t, filepath.Join(dir, "history.jsonl")); got != 2 {
+		t.Errorf("history has %d lines after migrating, want 2", got)
+	}
+	for _, store := range stores {
+		if got := len(store.All()); got != 2 {
+			t.Errorf("store has %d builds after migrating, want 2", got)
+		}
+	}
+}
+
+func TestQueryTimeRange(t *testing.T) {
+	store := openTemp(t)
+	builds := []Build{
+		{"BuildID": "utc", StartedAt: "2026-03-02T10:00:00Z"},
+		// StartedAt wins over a StartTime that would fall outside the range
+		{"BuildID": "both", StartedAt: "2026-03-03T10:00:00Z", "StartTime": "Sunday, 01-Mar-26 10:00:00 UTC"},
+		// recorded before StartedAt, it gets one on insert
+		{"BuildID": "legacy", "StartTime": time.Date(2026, 3, 4, 10, 0, 0, 0, time.Local).Format(time.RFC850)},
+		{"BuildID": "unknown", "StartTime": "sometime"},
+		{"BuildID": "late", StartedAt: "2026-04-01T10:00:00Z"},
+	}
+	for _, build := range builds {
+		if err := store.Insert(build); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if build, _ := store.Lookup("legacy"); build.Get(StartedAt) == "" {
+		t.Errorf("legacy build has no %s after insert", StartedAt)
+	}
+
+	got := store.Query(Query{
+		Since: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+		Until: time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC),
+	})
+	var ids []string
+	for _, build := range got {
+		ids = append(ids, build.ID())
+	}
+	if want := []string{"utc", "both", "legacy"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("Query() = %v, want %v", ids, want)
+	}
+
+	if got := len(store.All()); got != len(builds) {
+		t.Errorf("All() has %d builds, want %d", got, len(builds))
+	}
+}
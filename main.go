@@ -20,11 +20,25 @@ func main() {
 		if builderCommand == "init" {
 			cmd.Init()
 			fmt.Println("Build Complete 🔨")
+		} else if builderCommand == "config" && cmd.IsConfigFileCommand(os.Args[2:]) {
+			cmd.ConfigFile()
 		} else if builderCommand == "config" {
 			cmd.Config()
 			fmt.Println("Build Complete 🔨")
 		} else if builderCommand == "gui" {
 			gui.Gui()
+		} else if builderCommand == "history" {
+			cmd.History()
+		} else if builderCommand == "show" {
+			cmd.Show()
+		} else if builderCommand == "plan" {
+			cmd.Plan()
+		} else if builderCommand == "validate" {
+			cmd.Validate()
+		} else if builderCommand == "verify" {
+			cmd.Verify()
+		} else if builderCommand == "secret" {
+			cmd.Secret()
 		} else {
 			cmd.Builder()
 			fmt.Println("Build Complete 🔨")
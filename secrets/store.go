@@ -0,0 +1,205 @@
+// Package secrets keeps the values of build secrets in a local encrypted file,
+// ~/.builder/secrets.enc.  The file is encrypted with AES-256-GCM using the key in
+// ~/.builder/secrets.key, which is created the first time a secret is stored, or the
+// base64 key in the BUILDER_SECRETS_KEY environment variable.
+package secrets
+
+import (
+	"Builder/utils/dirs"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// KeyEnv is the environment variable that holds the key in place of the key file
+const KeyEnv = "BUILDER_SECRETS_KEY"
+
+const keySize = 32
+
+// ErrNoKey is returned when the secrets file exists but there is no key to decrypt it
+var ErrNoKey = errors.New("no secrets key")
+
+// Store holds the decrypted secrets of the secrets file
+type Store struct {
+	path    string
+	keyPath string
+	values  map[string]string
+}
+
+// Open reads the secrets file in the builder dir.  A missing file is an empty store.
+func Open() (*Store, error) {
+	dir, err := dirs.BuilderDir()
+	if err != nil {
+		return nil, err
+	}
+
+	return OpenFile(filepath.Join(dir, "secrets.enc"), filepath.Join(dir, "secrets.key"))
+}
+
+// OpenFile reads the secrets file at path, encrypted with the key at keyPath
+func OpenFile(path, keyPath string) (*Store, error) {
+	s := &Store{path: path, keyPath: keyPath, values: map[string]string{}}
+
+	sealed, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read secrets file: %w", err)
+	}
+
+	key, err := s.key(false)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf("secrets file %s is damaged", path)
+	}
+
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not decrypt %s, wrong secrets key?", path)
+	}
+	if err := json.Unmarshal(plain, &s.values); err != nil {
+		return nil, fmt.Errorf("secrets file %s is damaged: %w", path, err)
+	}
+
+	return s, nil
+}
+
+// Path returns the path of the secrets file
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Get returns the value of the secret name
+func (s *Store) Get(name string) (string, bool) {
+	value, ok := s.values[name]
+	return value, ok
+}
+
+// Names returns the names of the stored secrets in order
+func (s *Store) Names() []string {
+	names := make([]string, 0, len(s.values))
+	for name := range s.values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Set stores value as the secret name and writes the secrets file
+func (s *Store) Set(name, value string) error {
+	s.values[name] = value
+	return s.save()
+}
+
+// Delete removes the secret name and writes the secrets file.  It reports whether the
+// secret was stored.
+func (s *Store) Delete(name string) (bool, error) {
+	if _, ok := s.values[name]; !ok {
+		return false, nil
+	}
+
+	delete(s.values, name)
+	return true, s.save()
+}
+
+func (s *Store) save() error {
+	key, err := s.key(true)
+	if err != nil {
+		return err
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+
+	plain, err := json.Marshal(s.values)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return fmt.Errorf("could not encrypt secrets: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("could not create %s: %w", filepath.Dir(s.path), err)
+	}
+
+	// written next to the file and renamed so an interrupted write can't lose the secrets
+	tempPath := s.path + ".tmp"
+	if err := os.WriteFile(tempPath, gcm.Seal(nonce, nonce, plain, nil), 0600); err != nil {
+		return fmt.Errorf("could not write secrets file: %w", err)
+	}
+
+	return os.Rename(tempPath, s.path)
+}
+
+// key returns the key from BUILDER_SECRETS_KEY or the key file.  If create is set a missing
+// key file is created with a new random key.
+func (s *Store) key(create bool) ([]byte, error) {
+	if encoded := os.Getenv(KeyEnv); encoded != "" {
+		return decodeKey(encoded, KeyEnv)
+	}
+
+	encoded, err := os.ReadFile(s.keyPath)
+	if err == nil {
+		return decodeKey(string(encoded), s.keyPath)
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("could not read secrets key: %w", err)
+	}
+	if !create {
+		return nil, fmt.Errorf("%w to decrypt %s, set %s or restore %s", ErrNoKey, s.path, KeyEnv, s.keyPath)
+	}
+
+	key := make([]byte, keySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, fmt.Errorf("could not create secrets key: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.keyPath), 0700); err != nil {
+		return nil, fmt.Errorf("could not create %s: %w", filepath.Dir(s.keyPath), err)
+	}
+	if err := os.WriteFile(s.keyPath, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0600); err != nil {
+		return nil, fmt.Errorf("could not write secrets key: %w", err)
+	}
+
+	return key, nil
+}
+
+func decodeKey(encoded, source string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(key) != keySize {
+		return nil, fmt.Errorf("secrets key in %s must be %d base64 encoded bytes", source, keySize)
+	}
+
+	return key, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
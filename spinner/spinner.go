@@ -41,12 +41,8 @@ func LogMessage(msg string, level string) {
 			case "warn":
 				fmt.Println(time.Now().Local().String() + "   WARN   " + Caller + ":   " + msg)
 				break
-			case "error":
+			default:
 				fmt.Println(time.Now().Local().String() + "   ERROR   " + Caller + ":   " + msg)
-				break
-			default: // Fatal
-				fmt.Println(time.Now().Local().String() + "   FATAL   " + Caller + ":   " + msg)
-				BuilderLog.Fatal()
 			}
 		}
 	}
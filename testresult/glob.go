@@ -0,0 +1,38 @@
+package testresult
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// MatchGlob reports whether the slash separated path of a report matches pattern (a
+// testresults pattern), where a ** path element matches any number of dirs
+func MatchGlob(pattern, path string) bool {
+	return matchElems(strings.Split(pattern, "/"), strings.Split(path, "/"))
+}
+
+func matchElems(pattern, path []string) bool {
+	if len(pattern) == 0 {
+		return len(path) == 0
+	}
+
+	if pattern[0] == "**" {
+		for i := 0; i <= len(path); i++ {
+			if matchElems(pattern[1:], path[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(path) == 0 {
+		return false
+	}
+
+	matched, err := filepath.Match(pattern[0], path[0])
+	if err != nil || !matched {
+		return false
+	}
+
+	return matchElems(pattern[1:], path[1:])
+}
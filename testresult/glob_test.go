@@ -0,0 +1,29 @@
+package testresult
+
+import "testing"
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"**/surefire-reports/TEST-*.xml", "target/surefire-reports/TEST-App.xml", true},
+		{"**/surefire-reports/TEST-*.xml", "core/target/surefire-reports/TEST-App.xml", true},
+		{"**/surefire-reports/TEST-*.xml", "surefire-reports/TEST-App.xml", true},
+		{"**/surefire-reports/TEST-*.xml", "target/surefire-reports/App.txt", false},
+		{"**/test-results/**/TEST-*.xml", "app/build/test-results/test/TEST-App.xml", true},
+		{"**/test-results/**/TEST-*.xml", "build/test-results/TEST-App.xml", true},
+		{"reports/*.xml", "reports/junit.xml", true},
+		{"reports/*.xml", "reports/sub/junit.xml", false},
+		{"reports/junit.xml", "other/junit.xml", false},
+		{"**", "any/thing/at/all.xml", true},
+		{"[", "[", false},
+	}
+
+	for _, test := range tests {
+		if got := MatchGlob(test.pattern, test.path); got != test.want {
+			t.Errorf("MatchGlob(%q, %q) = %v, want %v", test.pattern, test.path, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package testresult
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure"`
+	Error     *junitFailure `xml:"error"`
+	Skipped   *struct{}     `xml:"skipped"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// ParseJUnit reads the testcases of a JUnit XML report.  The testcases can be nested in any
+// number of testsuites/testsuite elements.
+func ParseJUnit(r io.Reader) ([]TestCase, error) {
+	var cases []TestCase
+
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not parse JUnit XML: %w", err)
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "testcase" {
+			continue
+		}
+
+		var jc junitCase
+		if err := decoder.DecodeElement(&jc, &start); err != nil {
+			return nil, fmt.Errorf("could not parse JUnit XML: %w", err)
+		}
+
+		c := TestCase{Name: jc.Name, Status: StatusPassed}
+		if jc.ClassName != "" {
+			c.Name = jc.ClassName + "." + jc.Name
+		}
+		c.DurationSeconds, _ = strconv.ParseFloat(jc.Time, 64)
+
+		failure := jc.Failure
+		if failure == nil {
+			failure = jc.Error
+		}
+		switch {
+		case failure != nil:
+			c.Status = StatusFailed
+			c.Message = failure.Message
+			if c.Message == "" {
+				c.Message = strings.TrimSpace(failure.Text)
+			}
+		case jc.Skipped != nil:
+			c.Status = StatusSkipped
+		}
+
+		cases = append(cases, c)
+	}
+
+	return cases, nil
+}
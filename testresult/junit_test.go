@@ -0,0 +1,61 @@
+package testresult
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseJUnit(t *testing.T) {
+	tests := []struct {
+		name    string
+		xml     string
+		want    []TestCase
+		wantErr bool
+	}{
+		{
+			name: "surefire report",
+			xml: `<?xml version="1.0" encoding="UTF-8"?>
+<testsuite name="com.example.AppTest" tests="4">
+  <testcase name="adds" classname="com.example.AppTest" time="0.012"/>
+  <testcase name="subtracts" classname="com.example.AppTest" time="0.5">
+    <failure message="expected 1 but was 2" type="AssertionError">stack</failure>
+  </testcase>
+  <testcase name="divides" classname="com.example.AppTest">
+    <error type="ArithmeticException">  / by zero  </error>
+  </testcase>
+  <testcase name="later" classname="com.example.AppTest"><skipped/></testcase>
+</testsuite>`,
+			want: []TestCase{
+				{Name: "com.example.AppTest.adds", Status: StatusPassed, DurationSeconds: 0.012},
+				{Name: "com.example.AppTest.subtracts", Status: StatusFailed, DurationSeconds: 0.5, Message: "expected 1 but was 2"},
+				{Name: "com.example.AppTest.divides", Status: StatusFailed, Message: "/ by zero"},
+				{Name: "com.example.AppTest.later", Status: StatusSkipped},
+			},
+		},
+		{
+			name: "nested suites without classname",
+			xml: `<testsuites><testsuite name="outer"><testsuite name="inner">
+  <testcase name="works" time="1"/>
+</testsuite></testsuite></testsuites>`,
+			want: []TestCase{{Name: "works", Status: StatusPassed, DurationSeconds: 1}},
+		},
+		{
+			name:    "broken xml",
+			xml:     `<testsuite><testcase name="a">`,
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseJUnit(strings.NewReader(test.xml))
+			if (err != nil) != test.wantErr {
+				t.Fatalf("ParseJUnit() error = %v, want error %v", err, test.wantErr)
+			}
+			if !test.wantErr && !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ParseJUnit() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package testresult
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ParseOutput reads test results out of the output of a test command.  go test -json output
+// is tried first, then TAP, then cargo's libtest output.  The format found is returned along
+// with the cases, or "" if the output has no test results.
+func ParseOutput(lines []string) (string, []TestCase) {
+	if cases := ParseGoTestJSON(lines); len(cases) > 0 {
+		return FormatGoJSON, cases
+	}
+	if cases := ParseTAP(lines); len(cases) > 0 {
+		return FormatTAP, cases
+	}
+	if cases := ParseLibtest(lines); len(cases) > 0 {
+		return FormatLibtest, cases
+	}
+
+	return "", nil
+}
+
+type goTestEvent struct {
+	Action  string
+	Package string
+	Test    string
+	Elapsed float64
+	Output  string
+}
+
+// ParseGoTestJSON reads the test events printed by go test -json.  The output of a failed test
+// is kept as its message.
+func ParseGoTestJSON(lines []string) []TestCase {
+	var cases []TestCase
+	output := map[string]*strings.Builder{}
+
+	for _, line := range lines {
+		if !strings.HasPrefix(line, "{") {
+			continue
+		}
+
+		var event goTestEvent
+		if json.Unmarshal([]byte(line), &event) != nil || event.Test == "" {
+			continue
+		}
+
+		name := event.Package + "." + event.Test
+		switch event.Action {
+		case "output":
+			if output[name] == nil {
+				output[name] = &strings.Builder{}
+			}
+			output[name].WriteString(event.Output)
+		case "pass", "fail", "skip":
+			c := TestCase{Name: name, DurationSeconds: event.Elapsed}
+			switch event.Action {
+			case "pass":
+				c.Status = StatusPassed
+			case "fail":
+				c.Status = StatusFailed
+				if output[name] != nil {
+					c.Message = strings.TrimSpace(output[name].String())
+				}
+			case "skip":
+				c.Status = StatusSkipped
+			}
+			cases = append(cases, c)
+			delete(output, name)
+		}
+	}
+
+	return cases
+}
+
+var (
+	tapLine     = regexp.MustCompile(`^(not ok|ok) (\d+)\b\s*(?:-\s*)?([^#]*?)\s*(?:#\s*(\w+)\b\s*(.*))?$`)
+	tapDuration = regexp.MustCompile(`^  duration_ms:\s*([0-9.]+)`)
+)
+
+// ParseTAP reads the top level, numbered test points of TAP output.  Tests with a SKIP directive, and
+// failing tests with a TODO directive, are counted as skipped.  The duration_ms of a test's
+// YAML block is read if there is one.
+func ParseTAP(lines []string) []TestCase {
+	var cases []TestCase
+	inBlock := false
+
+	for _, line := range lines {
+		if m := tapLine.FindStringSubmatch(line); m != nil {
+			inBlock = false
+
+			c := TestCase{Name: m[3], Status: StatusPassed}
+			if c.Name == "" {
+				c.Name = "test " + m[2]
+			}
+			if m[1] == "not ok" {
+				c.Status = StatusFailed
+			}
+
+			switch strings.ToUpper(m[4]) {
+			case "SKIP":
+				c.Status = StatusSkipped
+			case "TODO":
+				if c.Status == StatusFailed {
+					c.Status = StatusSkipped
+				}
+			}
+
+			cases = append(cases, c)
+			continue
+		}
+
+		// YAML diagnostic block of the previous test point, indented two spaces (blocks of
+		// subtests are indented further and come before their parent's test point)
+		switch strings.TrimRight(line, " \t") {
+		case "  ---":
+			inBlock = len(cases) > 0
+			continue
+		case "  ...":
+			inBlock = false
+			continue
+		}
+
+		if inBlock {
+			if m := tapDuration.FindStringSubmatch(line); m != nil {
+				ms, _ := strconv.ParseFloat(m[1], 64)
+				cases[len(cases)-1].DurationSeconds = ms / 1000
+			}
+		}
+	}
+
+	return cases
+}
+
+var libtestLine = regexp.MustCompile(`^test (\S+)(?: - should panic)? \.\.\. (ok|FAILED|ignored)`)
+
+// ParseLibtest reads the test lines printed by Rust's test harness (cargo test)
+func ParseLibtest(lines []string) []TestCase {
+	var cases []TestCase
+
+	for _, line := range lines {
+		m := libtestLine.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+
+		c := TestCase{Name: m[1], Status: StatusPassed}
+		switch m[2] {
+		case "FAILED":
+			c.Status = StatusFailed
+		case "ignored":
+			c.Status = StatusSkipped
+		}
+
+		cases = append(cases, c)
+	}
+
+	return cases
+}
@@ -0,0 +1,164 @@
+package testresult
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseGoTestJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  []TestCase
+	}{
+		{
+			name: "pass fail skip",
+			lines: []string{
+				`{"Action":"run","Package":"p","Test":"TestA"}`,
+				`{"Action":"pass","Package":"p","Test":"TestA","Elapsed":0.5}`,
+				`{"Action":"output","Package":"p","Test":"TestB","Output":"b_test.go:3: boom\n"}`,
+				`{"Action":"fail","Package":"p","Test":"TestB","Elapsed":1}`,
+				`{"Action":"skip","Package":"p","Test":"TestC"}`,
+			},
+			want: []TestCase{
+				{Name: "p.TestA", Status: StatusPassed, DurationSeconds: 0.5},
+				{Name: "p.TestB", Status: StatusFailed, DurationSeconds: 1, Message: "b_test.go:3: boom"},
+				{Name: "p.TestC", Status: StatusSkipped},
+			},
+		},
+		{
+			name: "package events and other output are ignored",
+			lines: []string{
+				"go: downloading example.com/x v1.0.0",
+				`{"Action":"pass","Package":"p","Elapsed":2}`,
+				`{not json`,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := ParseGoTestJSON(test.lines); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ParseGoTestJSON() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseTAP(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  []TestCase
+	}{
+		{
+			name: "statuses and directives",
+			lines: []string{
+				"TAP version 13",
+				"1..5",
+				"ok 1 - adds",
+				"not ok 2 - subtracts",
+				"ok 3 - network # SKIP offline",
+				"not ok 4 - later # TODO not done",
+				"ok 5",
+			},
+			want: []TestCase{
+				{Name: "adds", Status: StatusPassed},
+				{Name: "subtracts", Status: StatusFailed},
+				{Name: "network", Status: StatusSkipped},
+				{Name: "later", Status: StatusSkipped},
+				{Name: "test 5", Status: StatusPassed},
+			},
+		},
+		{
+			name: "duration from the yaml block",
+			lines: []string{
+				"ok 1 - fast",
+				"  ---",
+				"  duration_ms: 250",
+				"  ...",
+				"ok 2 - slow",
+			},
+			want: []TestCase{
+				{Name: "fast", Status: StatusPassed, DurationSeconds: 0.25},
+				{Name: "slow", Status: StatusPassed},
+			},
+		},
+		{
+			name:  "subtests are left out",
+			lines: []string{"    ok 1 - inner", "ok 1 - outer"},
+			want:  []TestCase{{Name: "outer", Status: StatusPassed}},
+		},
+		{
+			name:  "not tap",
+			lines: []string{"okay then", "all good"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := ParseTAP(test.lines); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ParseTAP() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseLibtest(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  []TestCase
+	}{
+		{
+			name: "cargo test output",
+			lines: []string{
+				"running 4 tests",
+				"test math::adds ... ok",
+				"test math::divides - should panic ... ok",
+				"test net::fetch ... ignored",
+				"test math::subtracts ... FAILED",
+				"test result: FAILED. 2 passed; 1 failed; 1 ignored",
+			},
+			want: []TestCase{
+				{Name: "math::adds", Status: StatusPassed},
+				{Name: "math::divides", Status: StatusPassed},
+				{Name: "net::fetch", Status: StatusSkipped},
+				{Name: "math::subtracts", Status: StatusFailed},
+			},
+		},
+		{
+			name:  "no tests",
+			lines: []string{"running 0 tests", "test result: ok. 0 passed"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := ParseLibtest(test.lines); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ParseLibtest() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseOutput(t *testing.T) {
+	tests := []struct {
+		name   string
+		lines  []string
+		format string
+	}{
+		{"go test -json", []string{`{"Action":"pass","Package":"p","Test":"TestA"}`}, FormatGoJSON},
+		{"tap", []string{"ok 1 - a"}, FormatTAP},
+		{"libtest", []string{"test a ... ok"}, FormatLibtest},
+		{"none", []string{"BUILD SUCCESS"}, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if format, _ := ParseOutput(test.lines); format != test.format {
+				t.Errorf("ParseOutput() format = %q, want %q", format, test.format)
+			}
+		})
+	}
+}
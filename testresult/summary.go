@@ -0,0 +1,96 @@
+// Package testresult reads the results of a project's tests (JUnit XML, go test -json, TAP
+// or cargo's libtest output) into a Summary that is stored in the build metadata.
+package testresult
+
+import (
+	"sort"
+)
+
+// Test result formats
+const (
+	FormatJUnit   = "junit"
+	FormatGoJSON  = "go-test-json"
+	FormatTAP     = "tap"
+	FormatLibtest = "libtest"
+	// FormatNone is used when the test output couldn't be parsed, only the exit status is known
+	FormatNone = "none"
+)
+
+// Test statuses
+const (
+	StatusPassed  = "passed"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped"
+)
+
+// maximum length of a failure message kept in the summary
+const maxMessageLen = 500
+
+// Summary is the outcome of a build's test stage
+type Summary struct {
+	Command string `json:"command" yaml:"command"`
+	// Status is passed if the test command exited 0 and no test failed
+	Status          string     `json:"status" yaml:"status"`
+	Formats         []string   `json:"formats" yaml:"formats"`
+	Total           int        `json:"total" yaml:"total"`
+	Passed          int        `json:"passed" yaml:"passed"`
+	Failed          int        `json:"failed" yaml:"failed"`
+	Skipped         int        `json:"skipped" yaml:"skipped"`
+	DurationSeconds float64    `json:"durationSeconds" yaml:"durationSeconds"`
+	Failures        []string   `json:"failures" yaml:"failures"`
+	Cases           []TestCase `json:"cases,omitempty" yaml:"cases,omitempty"`
+}
+
+// TestCase is the result of a single test
+type TestCase struct {
+	Name            string  `json:"name" yaml:"name"`
+	Status          string  `json:"status" yaml:"status"`
+	DurationSeconds float64 `json:"durationSeconds" yaml:"durationSeconds"`
+	Message         string  `json:"message,omitempty" yaml:"message,omitempty"`
+}
+
+// Add adds the test cases read from one result source in format to the summary
+func (s *Summary) Add(format string, cases []TestCase) {
+	if len(cases) == 0 {
+		return
+	}
+
+	s.Formats = append(s.Formats, format)
+	for _, c := range cases {
+		if len(c.Message) > maxMessageLen {
+			c.Message = c.Message[:maxMessageLen] + "..."
+		}
+		s.Cases = append(s.Cases, c)
+	}
+}
+
+// Finish totals up the test cases.  duration is how long the test command ran, exitErr is its
+// error if it exited non-zero.
+func (s *Summary) Finish(durationSeconds float64, exitErr error) {
+	s.Total, s.Passed, s.Failed, s.Skipped = 0, 0, 0, 0
+	s.Failures = []string{}
+
+	for _, c := range s.Cases {
+		s.Total++
+		switch c.Status {
+		case StatusPassed:
+			s.Passed++
+		case StatusFailed:
+			s.Failed++
+			s.Failures = append(s.Failures, c.Name)
+		case StatusSkipped:
+			s.Skipped++
+		}
+	}
+	sort.Strings(s.Failures)
+
+	if len(s.Formats) == 0 {
+		s.Formats = []string{FormatNone}
+	}
+
+	s.DurationSeconds = durationSeconds
+	s.Status = StatusPassed
+	if exitErr != nil || s.Failed > 0 {
+		s.Status = StatusFailed
+	}
+}
@@ -0,0 +1,278 @@
+package utils
+
+import (
+	"Builder/testresult"
+	"Builder/utils/log"
+	"Builder/yaml"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// BuildContext holds all of the state for a single build.  It is created once
+// per build by the cmd package and passed explicitly into every stage.
+type BuildContext struct {
+	// BuildID identifies the build in the build history
+	BuildID string
+	// Command is the Builder command that started the build ("init", "config" or "builder")
+	Command string
+	// RepoURL is the repo passed to init/config, or the origin of the current dir
+	RepoURL string
+	// Flags holds the parsed CLI flags
+	Flags Flags
+	// Config holds the parsed builder.yaml values (plus values Builder fills in as defaults)
+	Config yaml.BuilderYaml
+
+	// Absolute paths of the directories created for the build
+	RepoDir      string
+	ParentDir    string
+	HiddenDir    string
+	WorkspaceDir string
+	LogsDir      string
+	ArtifactDir  string
+	// BuildDir is the dir inside the workspace the build command runs in
+	BuildDir string
+	// Target is the builder.yaml target the build command is building for, and TargetEnv the
+	// variables that make it build for that target.  Both are empty outside of a target's build.
+	Target    string
+	TargetEnv []string
+
+	// ArtifactStamp is the name of the artifact dir ("name_artifact_<unix>")
+	ArtifactStamp string
+	// ArtifactNames are the file names of the artifacts produced by the build
+	ArtifactNames []string
+	// ArtifactInfo holds what the compiler knows about the artifacts, by artifact name
+	ArtifactInfo map[string]ArtifactInfo
+	// Steps holds the results of the builder.yaml steps that have run
+	Steps []StepResult
+	// Tests holds the results of the test stage, nil if it didn't run
+	Tests *testresult.Summary
+	// Toolchain holds the versions of the tools the compiler builds with
+	Toolchain []Tool
+	// BranchName is the repo branch that was built
+	BranchName string
+	// Step is the build step currently running, recorded if the build fails
+	Step string
+
+	StartTime time.Time
+	EndTime   time.Time
+
+	// Timeout is how long the whole build may run, 0 for no limit
+	Timeout time.Duration
+
+	// Logger writes the build tool output to the logs dir
+	Logger      *zap.Logger
+	closeLogger func()
+
+	// secrets holds the values of the builder.yaml secrets, redactor removes them from the logs
+	secrets  map[string]string
+	redactor *log.Redactor
+
+	// buildInfo is read the first time it's needed, see BuildInfo
+	buildInfo *BuildInfo
+
+	// ctx is done when the build is cancelled or runs past its timeout, which kills the
+	// running build command
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// Flags holds the CLI flags given to Builder
+type Flags struct {
+	Name       string
+	Branch     string
+	OutputPath string
+	Compress   bool
+	Hidden     bool
+	Docker     bool
+	Debug      bool
+	Verbose    bool
+	Test       bool
+	Timeout    time.Duration
+	DryRun     bool
+	Profile    string
+	Hermetic   bool
+	Help       bool
+}
+
+// NewBuildContext creates the context for a build started by command with the given CLI args
+func NewBuildContext(command string, args []string) (*BuildContext, error) {
+	flags, err := ParseFlags(args)
+	if err != nil {
+		return nil, err
+	}
+
+	bc := &BuildContext{
+		Command:   command,
+		Flags:     flags,
+		StartTime: time.Now(),
+		redactor:  &log.Redactor{},
+	}
+	bc.BuildID = NewBuildID(bc.StartTime)
+	bc.ctx, bc.cancel = context.WithCancel(context.Background())
+
+	// init and config take the repo url as their first argument
+	if command != "builder" && len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		bc.RepoURL = args[0]
+	}
+
+	bc.Config.OutputPath = bc.Flags.OutputPath
+	bc.Config.RepoBranch = bc.Flags.Branch
+	bc.Config.Profile = bc.Flags.Profile
+
+	return bc, nil
+}
+
+// ParseFlags reads Builder's CLI flags out of args
+func ParseFlags(args []string) (Flags, error) {
+	var flags Flags
+
+	for i, v := range args {
+		switch v {
+		case "--name", "-n":
+			if len(args) <= i+1 {
+				return flags, errors.New("please provide a name")
+			}
+			if specialChar(args[i+1]) {
+				return flags, errors.New("special characters not allowed in names")
+			}
+			flags.Name = args[i+1]
+		case "--branch", "-b":
+			if len(args) <= i+1 {
+				return flags, errors.New("no branch name provided")
+			}
+			flags.Branch = args[i+1]
+		case "--output", "-o":
+			if len(args) <= i+1 {
+				return flags, errors.New("no output path provided")
+			}
+			flags.OutputPath = args[i+1]
+		case "--compress", "-z", "-C":
+			flags.Compress = true
+		case "--hidden", "-H":
+			flags.Hidden = true
+		case "--docker", "-D":
+			flags.Docker = true
+		case "--debug", "-d":
+			flags.Debug = true
+		case "--verbose", "-v":
+			flags.Verbose = true
+		case "--test", "-t":
+			flags.Test = true
+		case "--timeout":
+			if len(args) <= i+1 {
+				return flags, errors.New("no timeout provided")
+			}
+			timeout, err := ParseTimeout(args[i+1])
+			if err != nil {
+				return flags, err
+			}
+			flags.Timeout = timeout
+		case "--profile":
+			if len(args) <= i+1 {
+				return flags, errors.New("no profile provided")
+			}
+			flags.Profile = args[i+1]
+		case "--dry-run":
+			flags.DryRun = true
+		case "--hermetic":
+			flags.Hermetic = true
+		case "--help", "-h":
+			flags.Help = true
+		}
+	}
+
+	return flags, nil
+}
+
+// IsBuilderCommand reports whether the build was started by the plain builder command
+func (bc *BuildContext) IsBuilderCommand() bool {
+	return bc.Command == "builder"
+}
+
+// Context is done when the build is cancelled or runs past its timeout
+func (bc *BuildContext) Context() context.Context {
+	if bc.ctx == nil {
+		return context.Background()
+	}
+
+	return bc.ctx
+}
+
+// StartTimeout limits how long the build can run, counting from its start time.  The --timeout
+// flag takes precedence over the builder.yaml timeout.
+func (bc *BuildContext) StartTimeout() error {
+	timeout := bc.Flags.Timeout
+	if timeout == 0 {
+		var err error
+		if timeout, err = ParseTimeout(bc.Config.Timeout); err != nil {
+			return err
+		}
+	}
+
+	if timeout == 0 || bc.Timeout != 0 {
+		return nil
+	}
+
+	bc.Timeout = timeout
+	ctx, cancelTimeout := context.WithDeadline(bc.Context(), bc.StartTime.Add(timeout))
+	cancel := bc.cancel
+	bc.ctx = ctx
+	bc.cancel = func() {
+		cancelTimeout()
+		if cancel != nil {
+			cancel()
+		}
+	}
+
+	return nil
+}
+
+// Cancel kills the running build command and keeps any more from starting
+func (bc *BuildContext) Cancel() {
+	if bc.cancel != nil {
+		bc.cancel()
+	}
+}
+
+// Cancelled reports whether the build was cancelled
+func (bc *BuildContext) Cancelled() bool {
+	return bc.Context().Err() == context.Canceled
+}
+
+// OpenLogger creates the build log file inside the logs dir, with the secrets redacted
+func (bc *BuildContext) OpenLogger() error {
+	logger, closeLogger, err := log.NewLogger("logs", bc.LogsDir, bc.Flags.Verbose, bc.Flags.Debug)
+	if err != nil {
+		return err
+	}
+	bc.Logger, bc.closeLogger = bc.RedactLogger(logger), closeLogger
+
+	return nil
+}
+
+// CloseLogger closes the build log file.  It must be closed before the parent dir is renamed.
+func (bc *BuildContext) CloseLogger() {
+	if bc.closeLogger != nil {
+		bc.closeLogger()
+		bc.closeLogger = nil
+	}
+}
+
+// ArtifactNameList returns the artifact names as a comma seperated list
+func (bc *BuildContext) ArtifactNameList() string {
+	return strings.Join(bc.ArtifactNames, ",")
+}
+
+// SetArtifactInfo records what's known about the artifact that's collected from path
+func (bc *BuildContext) SetArtifactInfo(path string, info ArtifactInfo) {
+	if bc.ArtifactInfo == nil {
+		bc.ArtifactInfo = map[string]ArtifactInfo{}
+	}
+
+	bc.ArtifactInfo[filepath.Base(path)] = info
+}
@@ -0,0 +1,189 @@
+package utils
+
+import (
+	"Builder/history"
+	"bufio"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+// Build statuses recorded in the build history
+const (
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+)
+
+// number of build log lines kept in the record of a failed build
+const logTailLines = 20
+
+// NewBuildID returns a short id that is unique to a build started at startTime
+func NewBuildID(startTime time.Time) string {
+	hostName, _ := os.Hostname()
+	seed := hostName + "-" + strconv.Itoa(os.Getpid()) + "-" + strconv.FormatInt(startTime.UnixNano(), 10)
+
+	sum := sha256.Sum256([]byte(seed))
+
+	// Only return first 9 char of sum
+	return fmt.Sprintf("%x", sum)[0:9]
+}
+
+// OpenBuildRecord adds the build to the build history with a running status.  It is called as soon
+// as the build starts so builds that never finish still leave a trace.
+func OpenBuildRecord(bc *BuildContext) error {
+	return storeBuildRecord(buildRecord(bc, StatusRunning))
+}
+
+// FinishBuildRecord updates the build's entry in the build history with its final status.  A
+// succeeded build also gets the contents of its metadata.json, a failed or cancelled build
+// gets the step it stopped in, the exit code and the tail of the build log.
+func FinishBuildRecord(bc *BuildContext, status string, buildErr error) error {
+	if bc.EndTime.IsZero() {
+		bc.EndTime = time.Now()
+	}
+
+	record := buildRecord(bc, status)
+
+	if status == StatusSucceeded {
+		metadataJSON, err := os.ReadFile(bc.ArtifactDir + "/metadata.json")
+		if err != nil {
+			return fmt.Errorf("cannot find metadata.json file: %w", err)
+		}
+
+		var metadata map[string]interface{}
+		if err := json.Unmarshal(metadataJSON, &metadata); err != nil {
+			return fmt.Errorf("could not parse metadata.json file: %w", err)
+		}
+
+		for key, value := range metadata {
+			record[key] = value
+		}
+		bc.RedactValue(&record)
+
+		return storeBuildRecord(record)
+	}
+
+	record["Step"] = bc.Step
+	record["ExitCode"] = ExitCode(buildErr)
+	if status == StatusCancelled {
+		// exit code of a process stopped by SIGINT
+		record["ExitCode"] = 130
+	}
+	record["LogTail"] = logTail(bc)
+	if len(bc.Steps) > 0 {
+		record["Steps"] = bc.Steps
+	}
+	if bc.Tests != nil {
+		record["Tests"] = bc.Tests
+	}
+	if buildErr != nil {
+		record["Error"] = buildErr.Error()
+	}
+	bc.RedactValue(&record)
+
+	return storeBuildRecord(record)
+}
+
+// ExitCode returns the exit code of the build command that caused err, or 1 if err
+// didn't come from a build command.  A build command that was killed because it ran too long
+// gives 124 and one killed because the build was cancelled gives 130, like timeout(1) and a
+// shell would.
+func ExitCode(err error) int {
+	if errors.Is(err, ErrTimedOut) {
+		return 124
+	}
+	if errors.Is(err, ErrCancelled) {
+		return 130
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 {
+		return exitErr.ExitCode()
+	}
+
+	return 1
+}
+
+// buildRecord returns the fields every build history entry has
+func buildRecord(bc *BuildContext, status string) map[string]interface{} {
+	caser := cases.Title(language.English)
+
+	record := map[string]interface{}{
+		"BuildID":         bc.BuildID,
+		"Status":          status,
+		"ProjectName":     GetName(bc),
+		"ProjectType":     caser.String(bc.Config.ProjectType),
+		"GitURL":          bc.RepoURL,
+		"BranchName":      bc.BranchName,
+		"StartTime":       bc.StartTime.Format(time.RFC850),
+		history.StartedAt: bc.StartTime.UTC().Format(time.RFC3339),
+		"EndTime":         "",
+		"LogsLocation":    "",
+	}
+
+	if !bc.EndTime.IsZero() {
+		record["EndTime"] = bc.EndTime.Format(time.RFC850)
+	}
+
+	if bc.LogsDir != "" {
+		record["LogsLocation"] = bc.LogsDir + "/logs.json"
+	}
+
+	if userData, err := GetUserData(); err == nil {
+		record["UserName"] = UserName(userData)
+		record["HomeDir"] = userData.HomeDir
+	}
+
+	return record
+}
+
+// logTail returns the messages of the last lines of the build log
+func logTail(bc *BuildContext) []string {
+	tail := []string{}
+	if bc.LogsDir == "" {
+		return tail
+	}
+
+	logsFile, err := os.Open(bc.LogsDir + "/logs.json")
+	if err != nil {
+		return tail
+	}
+	defer logsFile.Close()
+
+	scanner := bufio.NewScanner(logsFile)
+	for scanner.Scan() {
+		var entry struct {
+			Msg string `json:"msg"`
+		}
+		if json.Unmarshal(scanner.Bytes(), &entry) != nil {
+			continue
+		}
+
+		tail = append(tail, entry.Msg)
+		if len(tail) > logTailLines {
+			tail = tail[1:]
+		}
+	}
+
+	return tail
+}
+
+// storeBuildRecord adds record to the build history, replacing the entry with the same BuildID
+func storeBuildRecord(record map[string]interface{}) error {
+	store, err := history.OpenDefault()
+	if err != nil {
+		return err
+	}
+
+	return store.Insert(record)
+}
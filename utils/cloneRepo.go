@@ -2,6 +2,7 @@ package utils
 
 import (
 	"Builder/spinner"
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -9,88 +10,96 @@ import (
 )
 
 // CloneRepo grabs url and clones the repo
-func CloneRepo(to string) {
+func CloneRepo(bc *BuildContext, to string) error {
 	// Get absolute path if a relative path was given
 	toPath, _ := filepath.Abs(to)
 
-	repo := GetRepoURL()
+	repo := GetRepoURL(bc)
+
+	// A clone left by an earlier init or config is updated instead of cloned again
+	if reused, err := updateClone(bc, repo, toPath); reused || err != nil {
+		return err
+	}
 
 	// Stat path, if it doesn't exist, create it
-	_, err := os.Stat(toPath)
-	if err != nil {
+	if _, err := os.Stat(toPath); err != nil {
 		errDir := os.MkdirAll(toPath, 0755)
 		if errDir != nil {
-			spinner.LogMessage("Could not create new repo directory: "+errDir.Error(), "fatal")
+			return fmt.Errorf("could not create new repo directory: %w", errDir)
 		}
+	}
 
-		cmd := exec.Command("git", "clone", repo, toPath)
-		cmd.Run()
+	// Branch given by -b flag or by repobranch in builder.yaml
+	branchName := bc.Config.RepoBranch
 
-		// Get branch name
-		os.Setenv("REPO_BRANCH_NAME", GetBranchName(toPath))
+	if branchName != "" {
+		cmd := exec.Command("git", "clone", "-b", branchName, "--single-branch", repo, toPath)
+		spinner.LogMessage("git clone -b "+branchName+" --single-branch "+repo, "info")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("git clone of %s (branch %s) failed: %w: %s", repo, branchName, err, strings.TrimSpace(string(out)))
+		}
+
+		bc.BranchName = branchName
 	} else {
-		bFlagExists, branchExists, branchName := bFlagAndBranchExists()
-
-		if bFlagExists {
-			if branchExists {
-				cmd := exec.Command("git", "clone", "-b", branchName, "--single-branch", repo, toPath)
-				cmd.Run()
-				spinner.LogMessage("git clone -b "+branchName+" --single-branch "+repo, "info")
-
-				// Get branch name
-				os.Setenv("REPO_BRANCH_NAME", branchName)
-			} else {
-				spinner.LogMessage("Branch does not exists", "fatal")
-			}
-		} else if branchExists { // Repo branch given in builder.yaml not by -b flag
-			cmd := exec.Command("git", "clone", "-b", branchName, "--single-branch", repo, toPath)
-			cmd.Run()
-			spinner.LogMessage("git clone -b "+branchName+" --single-branch "+repo, "info")
-
-			// Get branch name
-			os.Setenv("REPO_BRANCH_NAME", branchName)
-		} else {
-			cmd := exec.Command("git", "clone", repo, toPath)
-			cmd.Run()
-			spinner.LogMessage("git clone "+repo, "info")
-
-			// Get branch name
-			os.Setenv("REPO_BRANCH_NAME", GetBranchName(toPath))
+		cmd := exec.Command("git", "clone", repo, toPath)
+		spinner.LogMessage("git clone "+repo, "info")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("git clone of %s failed: %w: %s", repo, err, strings.TrimSpace(string(out)))
 		}
+
+		// Get branch name
+		bc.BranchName = GetBranchName(toPath)
 	}
+
+	return nil
 }
 
-func bFlagAndBranchExists() (bool, bool, string) {
-	var bFlagExists, branchExists = false, false
-	args := os.Args[1:]
+// updateClone fetches repo into the clone of it at toPath and checks out the branch being built
+// (the clone's current branch if none is given).  It returns false if there's nothing at toPath
+// or only an empty dir, so repo gets cloned, and an error if toPath holds anything but a clone
+// of repo.
+func updateClone(bc *BuildContext, repo, toPath string) (bool, error) {
+	entries, err := os.ReadDir(toPath)
+	if err != nil || len(entries) == 0 {
+		return false, nil
+	}
 
-	branchName := os.Getenv("REPO_BRANCH")
+	remoteCmd := exec.Command("git", "remote", "get-url", "origin")
+	remoteCmd.Dir = toPath
+	remote, err := remoteCmd.Output()
+	if err != nil || strings.TrimSpace(string(remote)) != repo {
+		return false, fmt.Errorf("could not clone %s: %s already exists and isn't a clone of it, remove it or build with a different name", repo, toPath)
+	}
 
-	for i, v := range args {
-		if v == "-b" || v == "--branch" {
-			if len(args) <= i+1 {
-				spinner.LogMessage("No Branch Name Provided", "fatal")
+	branchName := bc.Config.RepoBranch
+	if branchName == "" {
+		branchName = GetBranchName(toPath)
+	}
+	if branchName == "" {
+		return false, fmt.Errorf("could not update the clone of %s in %s: it has no branch checked out", repo, toPath)
+	}
 
-			} else {
-				branchName = args[i+1]
-				bFlagExists = true
-			}
+	spinner.LogMessage(toPath+" already exists, fetching "+branchName+" from "+repo, "info")
+	for _, args := range [][]string{
+		{"fetch", "origin", branchName},
+		{"checkout", "-B", branchName, "FETCH_HEAD"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = toPath
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return false, fmt.Errorf("could not update the clone of %s in %s: git %s: %w: %s", repo, toPath, args[0], err, strings.TrimSpace(string(out)))
 		}
 	}
 
-	if branchName != "" {
-		branchExists = true
-	}
+	bc.BranchName = branchName
 
-	return bFlagExists, branchExists, branchName
+	return true, nil
 }
 
 func GetBranchName(path string) string {
-	branchCmd := exec.Command("git", "branch")
+	branchCmd := exec.Command("git", "branch", "--show-current")
 	branchCmd.Dir = path
 	branch, _ := branchCmd.Output()
 
-	formatName := strings.TrimSuffix(string(branch), "\n")
-
-	return formatName[2:]
+	return strings.TrimSuffix(string(branch), "\n")
 }
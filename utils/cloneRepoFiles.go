@@ -1,40 +1,35 @@
 package utils
 
 import (
-	"Builder/spinner"
+	"fmt"
 	"os"
 	"path/filepath"
 
 	cp "github.com/otiai10/copy"
 )
 
-// CloneRepo grabs url and clones the repo/copies current dir
-func CloneRepoFiles(from string, to string) {
+// CloneRepoFiles copies the repo files into the hidden dir
+func CloneRepoFiles(bc *BuildContext, from string, to string) error {
 	//Get absolute paths in case relative paths are given
 	fromPath, _ := filepath.Abs(from)
 	toPath, _ := filepath.Abs(to)
 
+	// Builds are stored in default named 'builder' folder unless a different folder is provided
+	buildsDir := "builder"
+	if bc.Config.BuildsDir != "" {
+		buildsDir = bc.Config.BuildsDir
+	}
+
 	// Copy all but Builder created dir
-	if os.Getenv("BUILDER_BUILDS_DIR") != "" { // A different folder to store builds is provided
-		opt := cp.Options{
-			Skip: func(info os.FileInfo, src, dest string) (bool, error) {
-				return info.Name() == os.Getenv("BUILDER_BUILDS_DIR"), nil
-			},
-		}
-		err := cp.Copy(fromPath, toPath, opt)
-		if err != nil {
-			spinner.LogMessage(err.Error(), "fatal")
-		}
-	} else { // Builds are stored in default named 'builder' folder
-		// copy files to given path
-		opt := cp.Options{
-			Skip: func(info os.FileInfo, src, dest string) (bool, error) {
-				return info.Name() == "builder", nil
-			},
-		}
-		err := cp.Copy(fromPath, toPath, opt)
-		if err != nil {
-			spinner.LogMessage(err.Error(), "fatal")
-		}
+	opt := cp.Options{
+		Skip: func(info os.FileInfo, src, dest string) (bool, error) {
+			return info.Name() == buildsDir, nil
+		},
 	}
+	err := cp.Copy(fromPath, toPath, opt)
+	if err != nil {
+		return fmt.Errorf("could not copy repo files into %s: %w", toPath, err)
+	}
+
+	return nil
 }
@@ -0,0 +1,219 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// matches a leading NAME=value word of a command
+var envAssignment = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*=`)
+
+// ErrNeedsShell is returned by ParseCommand for commands that only a shell can run
+var ErrNeedsShell = errors.New("command uses shell syntax (pipes, &&, ;, redirects, subshells).  Set shell: true in the builder.yaml to run it through the shell")
+
+// ErrUnsetVar is returned by ParseCommand for a $VAR that isn't set in the command's environment
+var ErrUnsetVar = errors.New("isn't set in the environment of the command, declare it in env")
+
+// CommandArgs returns the env assignments and args of a builder.yaml command, with $VAR read
+// from the environment the command is run with (CommandEnv of env), so a hermetic build only
+// expands the allowlisted variables
+func (bc *BuildContext) CommandArgs(command string, env []string) ([]string, []string, error) {
+	assignments, args, err := CommandArgs(command, bc.Config.Shell, bc.CommandEnv(env))
+	if errors.Is(err, ErrUnsetVar) && bc.Hermetic() {
+		return nil, nil, fmt.Errorf("%w, hermetic builds only pass on the allowlisted variables", err)
+	}
+
+	return assignments, args, err
+}
+
+// CommandArgs turns a builder.yaml command into the env assignments and args to run, $VAR is
+// read from env (NAME=value pairs, later ones win).  In shell mode the command is handed to
+// the system shell as is.
+func CommandArgs(command string, shell bool, env []string) ([]string, []string, error) {
+	if shell {
+		if runtime.GOOS == "windows" {
+			return nil, []string{"cmd", "/C", command}, nil
+		}
+		return nil, []string{"/bin/sh", "-c", command}, nil
+	}
+
+	words, err := ParseCommand(command, env)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// leading NAME=value words are set in the command's environment, like a shell does
+	var assignments []string
+	for len(words) > 0 && envAssignment.MatchString(words[0]) {
+		assignments = append(assignments, words[0])
+		words = words[1:]
+	}
+
+	if len(words) == 0 {
+		return nil, nil, fmt.Errorf("no command to run in %q", command)
+	}
+
+	return assignments, words, nil
+}
+
+// ParseCommand splits command into words following POSIX shell quoting rules.  Single quotes
+// keep everything literally, double quotes keep everything but $VAR and \ escapes of $ ` " \,
+// and an unquoted \ escapes the next character.  $VAR and ${VAR} are expanded from env
+// (NAME=value pairs, later ones win), a name env doesn't set returns ErrUnsetVar.  Pipes,
+// lists, redirects and command substitution return ErrNeedsShell.
+func ParseCommand(command string, env []string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+
+	runes := []rune(command)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+
+		switch {
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		case r == '\'':
+			inWord = true
+			end := indexRune(runes, i+1, '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated ' in %q", command)
+			}
+			word.WriteString(string(runes[i+1 : end]))
+			i = end
+		case r == '"':
+			inWord = true
+			closed := false
+			for i++; i < len(runes); i++ {
+				r = runes[i]
+				if r == '"' {
+					closed = true
+					break
+				}
+				if r == '\\' && i+1 < len(runes) && strings.ContainsRune("$`\"\\\n", runes[i+1]) {
+					i++
+					word.WriteRune(runes[i])
+					continue
+				}
+				if r == '`' {
+					return nil, ErrNeedsShell
+				}
+				if r == '$' {
+					value, next, err := expandVar(runes, i, env)
+					if err != nil {
+						return nil, err
+					}
+					word.WriteString(value)
+					i = next
+					continue
+				}
+				word.WriteRune(r)
+			}
+			if !closed {
+				return nil, fmt.Errorf("unterminated \" in %q", command)
+			}
+		case r == '\\':
+			inWord = true
+			if i+1 < len(runes) {
+				i++
+				word.WriteRune(runes[i])
+			}
+		case strings.ContainsRune("|&;<>()`", r):
+			return nil, ErrNeedsShell
+		case r == '$':
+			inWord = true
+			value, next, err := expandVar(runes, i, env)
+			if err != nil {
+				return nil, err
+			}
+			word.WriteString(value)
+			i = next
+		default:
+			inWord = true
+			word.WriteRune(r)
+		}
+	}
+
+	if inWord {
+		words = append(words, word.String())
+	}
+
+	return words, nil
+}
+
+// QuoteCommand joins args into a command that ParseCommand splits back into args
+func QuoteCommand(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg != "" && !strings.ContainsAny(arg, " \t\n'\"\\$`|&;<>()*?[]#~") {
+			quoted[i] = arg
+		} else {
+			quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+	}
+
+	return strings.Join(quoted, " ")
+}
+
+// expandVar expands the $NAME or ${NAME} starting at runes[i] from env.  It returns the value
+// and the index of the last rune of the reference.  A $ that doesn't start a name is kept as is.
+func expandVar(runes []rune, i int, env []string) (string, int, error) {
+	if i+1 >= len(runes) {
+		return "$", i, nil
+	}
+
+	if runes[i+1] == '(' {
+		return "", i, ErrNeedsShell
+	}
+
+	if runes[i+1] == '{' {
+		end := indexRune(runes, i+2, '}')
+		if end < 0 {
+			return "", i, fmt.Errorf("unterminated ${ in %q", string(runes))
+		}
+		value, err := lookupVar(env, string(runes[i+2:end]))
+		return value, end, err
+	}
+
+	end := i + 1
+	for end < len(runes) && (runes[end] == '_' || (runes[end] >= 'A' && runes[end] <= 'Z') ||
+		(runes[end] >= 'a' && runes[end] <= 'z') || (end > i+1 && runes[end] >= '0' && runes[end] <= '9')) {
+		end++
+	}
+	if end == i+1 {
+		return "$", i, nil
+	}
+
+	value, err := lookupVar(env, string(runes[i+1:end]))
+	return value, end - 1, err
+}
+
+// lookupVar returns the value of the last name=value pair in env, variable names aren't case
+// sensitive on windows
+func lookupVar(env []string, name string) (string, error) {
+	for i := len(env) - 1; i >= 0; i-- {
+		pairName, value := splitEnv(env[i])
+		if pairName == name || (runtime.GOOS == "windows" && strings.EqualFold(pairName, name)) {
+			return value, nil
+		}
+	}
+
+	return "", fmt.Errorf("$%s %w", name, ErrUnsetVar)
+}
+
+func indexRune(runes []rune, from int, r rune) int {
+	for i := from; i < len(runes); i++ {
+		if runes[i] == r {
+			return i
+		}
+	}
+
+	return -1
+}
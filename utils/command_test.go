@@ -0,0 +1,120 @@
+package utils
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseCommand(t *testing.T) {
+	env := []string{"NAME=world", "EMPTY=", "NAME=builder", "SPACED=a b"}
+
+	tests := []struct {
+		command string
+		want    []string
+		err     error
+	}{
+		{"go build -o app", []string{"go", "build", "-o", "app"}, nil},
+		{"  go   vet\t./...\n", []string{"go", "vet", "./..."}, nil},
+		{`echo 'a  b' "c d"`, []string{"echo", "a  b", "c d"}, nil},
+		{`echo 'it''s'`, []string{"echo", "its"}, nil},
+		{`echo 'don'\''t'`, []string{"echo", "don't"}, nil},
+		{`echo '$NAME \n'`, []string{"echo", `$NAME \n`}, nil},
+		{`echo "say \"hi\" \$NAME \\ \n"`, []string{"echo", `say "hi" $NAME \ \n`}, nil},
+		{`echo a\ b \'c\'`, []string{"echo", "a b", "'c'"}, nil},
+		{`echo '' ""`, []string{"echo", "", ""}, nil},
+		{`echo a""b`, []string{"echo", "ab"}, nil},
+		{"echo $NAME ${NAME}! \"$SPACED\" $SPACED", []string{"echo", "builder", "builder!", "a b", "a b"}, nil},
+		{"echo x$EMPTY", []string{"echo", "x"}, nil},
+		{"echo $ $1 cost$", []string{"echo", "$", "$1", "cost$"}, nil},
+		{"GOOS=linux CGO_ENABLED=0 go build", []string{"GOOS=linux", "CGO_ENABLED=0", "go", "build"}, nil},
+		{"echo $MISSING", nil, ErrUnsetVar},
+		{"echo ${MISSING}", nil, ErrUnsetVar},
+		{"go test | tee out", nil, ErrNeedsShell},
+		{"make && make install", nil, ErrNeedsShell},
+		{"echo $(date)", nil, ErrNeedsShell},
+		{"echo `date`", nil, ErrNeedsShell},
+		{"cat < in", nil, ErrNeedsShell},
+	}
+
+	for _, test := range tests {
+		got, err := ParseCommand(test.command, env)
+		if test.err != nil {
+			if !errors.Is(err, test.err) {
+				t.Errorf("ParseCommand(%q) error = %v, want %v", test.command, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseCommand(%q) error = %v", test.command, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ParseCommand(%q) = %q, want %q", test.command, got, test.want)
+		}
+	}
+}
+
+func TestParseCommandUnterminated(t *testing.T) {
+	for _, command := range []string{`echo 'a`, `echo "a`, `echo ${A`} {
+		if _, err := ParseCommand(command, nil); err == nil {
+			t.Errorf("ParseCommand(%q) didn't fail", command)
+		}
+	}
+}
+
+func TestCommandArgs(t *testing.T) {
+	tests := []struct {
+		command string
+		env     []string
+		args    []string
+	}{
+		{"go build", nil, []string{"go", "build"}},
+		{"GOOS=linux GOARCH=arm64 go build -o app", []string{"GOOS=linux", "GOARCH=arm64"}, []string{"go", "build", "-o", "app"}},
+		{"go build GOOS=linux", nil, []string{"go", "build", "GOOS=linux"}},
+	}
+
+	for _, test := range tests {
+		env, args, err := CommandArgs(test.command, false, nil)
+		if err != nil {
+			t.Errorf("CommandArgs(%q) error = %v", test.command, err)
+			continue
+		}
+		if !reflect.DeepEqual(env, test.env) || !reflect.DeepEqual(args, test.args) {
+			t.Errorf("CommandArgs(%q) = %q, %q, want %q, %q", test.command, env, args, test.env, test.args)
+		}
+	}
+
+	if _, _, err := CommandArgs("A=b", false, nil); err == nil {
+		t.Errorf("CommandArgs with only assignments didn't fail")
+	}
+}
+
+func TestQuoteCommandRoundTrip(t *testing.T) {
+	tests := [][]string{
+		{"go", "build", "-o", "app"},
+		{"echo", "a b", "tab\there", "new\nline"},
+		{"echo", "", "''", `""`},
+		{"echo", "it's", `say "hi"`, `back\slash`, `trailing\`},
+		{"echo", "$HOME", "${X}", "`date`", "$(date)"},
+		{"echo", "a|b", "a&&b", "a;b", "<in", ">out", "(x)"},
+		{"echo", "*.go", "?", "[ab]", "#comment", "~"},
+		{"go", "build", "-ldflags", "-X 'main.version=1.2 3'"},
+	}
+
+	for _, args := range tests {
+		command := QuoteCommand(args)
+		got, err := ParseCommand(command, nil)
+		if err != nil {
+			t.Errorf("ParseCommand(QuoteCommand(%q)) = %q: error %v", args, command, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, args) {
+			t.Errorf("ParseCommand(QuoteCommand(%q)) = %q via %q", args, got, command)
+		}
+	}
+
+	if got := QuoteCommand([]string{"go", "build", "./..."}); got != "go build ./..." {
+		t.Errorf("QuoteCommand quoted plain words: %q", got)
+	}
+}
@@ -4,16 +4,17 @@
 package utils
 
 import (
-	"os"
+	"fmt"
 	"os/exec"
+	"strings"
 )
 
-//CopyDir creates exe from file passed in as arg
-func CopyDir() {
+// CopyDir copies the contents of the hidden dir into the workspace dir
+func CopyDir(bc *BuildContext) error {
+	out, err := exec.Command("cp", "-a", bc.HiddenDir+"/.", bc.WorkspaceDir).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("could not copy hidden dir into workspace: %w: %s", err, strings.TrimSpace(string(out)))
+	}
 
-	//copies contents of .hidden to workspace
-	hiddenDir := os.Getenv("BUILDER_HIDDEN_DIR")
-	workspaceDir := os.Getenv("BUILDER_WORKSPACE_DIR")
-	exec.Command("cp", "-a", hiddenDir+"/.", workspaceDir).Run()
+	return nil
 }
-
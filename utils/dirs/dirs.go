@@ -0,0 +1,30 @@
+// Package dirs resolves the dirs Builder keeps its own files in.  It imports nothing from
+// Builder so every package can use it.
+package dirs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// BuilderDir returns the application builder dir that holds the build history, secrets and
+// user config: ~/.builder, or %LOCALAPPDATA%\Builder on windows
+func BuilderDir() (string, error) {
+	if runtime.GOOS == "windows" {
+		appDataDir := os.Getenv("LOCALAPPDATA")
+		if appDataDir == "" {
+			appDataDir = os.Getenv("APPDATA")
+		}
+
+		return filepath.Join(appDataDir, "Builder"), nil
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("could not find home dir: %w", err)
+	}
+
+	return filepath.Join(homeDir, ".builder"), nil
+}
@@ -2,76 +2,94 @@ package utils
 
 import (
 	"Builder/spinner"
+	"Builder/yaml"
 	"bytes"
 	"fmt"
-	"os"
 	"os/exec"
+	"strconv"
 	"strings"
 )
 
 // Docker creates image from dockerfile and pushes to dockerhub
-func Docker() {
-	dockerFlag := CheckDockerFlag()
-
+func Docker(bc *BuildContext) error {
 	//if -D flag exists, build image
-	if dockerFlag {
-		//DETERMINE CMD
-		var cmd *exec.Cmd
-		dockerCmd := os.Getenv("BUILDER_DOCKER_CMD")
-		//if dockerCmd doesn't exist use default
-		if dockerCmd == "" {
-			name := GetName()
-			imageName := fmt.Sprintf("builder/%s", name)
-			unixTime := os.Getenv("BUILDER_TIMESTAMP")
-			cmd = exec.Command("docker", "build", ".", "-t", imageName+"-"+unixTime)
-		} else {
-			//else use defined dockerCmd
-			dockerCmdArray := strings.Fields(dockerCmd)
-			cmd = exec.Command(dockerCmdArray[0], dockerCmdArray[1:]...)
-		}
+	if bc.Flags.Docker {
+		spinner.LogMessage("Building docker image 🐳", "info")
 
-		//DETERMINE PATH
-		//determine projectType to top level Dockerfile path
-		compType := []string{"go", "rust", "c#", "java"}
-		nonCompType := []string{"node", "npm", "python", "ruby"}
-		workspaceDir := os.Getenv("BUILDER_WORKSPACE_DIR")
-		projectType := os.Getenv("BUILDER_PROJECT_TYPE")
-		if contains(compType, projectType) {
-			cmd.Dir = workspaceDir
-		} else if contains(nonCompType, projectType) {
-			cmd.Dir = workspaceDir + "/temp/"
-		} else {
-			spinner.LogMessage("Please define your projectType in builder.yaml", "fatal")
+		dockerCmds, dir, err := DockerCommands(bc)
+		if err != nil {
+			return err
 		}
 
 		//RUN DOCKER BUILD
-		spinner.LogMessage("running command: "+cmd.String(), "info")
-		err := cmd.Run()
-		if err != nil {
+		for _, dockerCmd := range dockerCmds {
+			env, args, err := bc.CommandArgs(dockerCmd, nil)
+			if err != nil {
+				return fmt.Errorf("dockercmd %q: %w", dockerCmd, err)
+			}
+			env = bc.CommandEnv(env)
+			path, err := LookPath(args[0], dir, env)
+			if err != nil {
+				return fmt.Errorf("dockercmd %q: %w", dockerCmd, err)
+			}
+			cmd := exec.Command(path, args[1:]...)
+			cmd.Env = env
+			cmd.Dir = dir
+
+			spinner.LogMessage("running command: "+bc.Redact(cmd.String()), "info")
 			var outb, errb bytes.Buffer
 			cmd.Stdout = &outb
 			cmd.Stderr = &errb
-			fmt.Println("out:", outb.String(), "err:", errb.String())
-			spinner.LogMessage("docker build failed: "+err.Error(), "fatal")
+			wait, err := StartProcess(bc.Context(), cmd)
+			if err == nil {
+				err = wait()
+			}
+			if err != nil {
+				output := strings.TrimSpace(errb.String())
+				if output == "" {
+					output = strings.TrimSpace(outb.String())
+				}
+				if output != "" {
+					return fmt.Errorf("docker build failed: %w: %s", err, output)
+				}
+				return fmt.Errorf("docker build failed: %w", err)
+			}
 		}
 
 		//RUN DOCKER PUSH
 	}
+
+	return nil
 }
 
-// CheckDockerFlag for docker flag
-func CheckDockerFlag() bool {
-	var exists bool
-	cArgs := os.Args[1:]
-	for _, v := range cArgs {
-		if v == "--docker" || v == "-D" {
-			spinner.LogMessage("Building docker image 🐳", "info")
-			exists = true
-		} else {
-			exists = false
-		}
+// DockerCommands returns the commands Docker runs (the dockercmd from the builder.yaml, or a
+// docker build of the project) and the dir they run in
+func DockerCommands(bc *BuildContext) (yaml.Commands, string, error) {
+	//DETERMINE CMD(S)
+	dockerCmds := bc.Config.DockerCmd
+	//if dockerCmd doesn't exist use default
+	if len(dockerCmds) == 0 {
+		name := GetName(bc)
+		imageName := fmt.Sprintf("builder/%s", name)
+		unixTime := strconv.FormatInt(bc.StartTime.Unix(), 10)
+		dockerCmds = yaml.Commands{QuoteCommand([]string{"docker", "build", ".", "-t", imageName + "-" + unixTime})}
 	}
-	return exists
+
+	//DETERMINE PATH
+	//determine projectType to top level Dockerfile path
+	compType := []string{"go", "rust", "c#", "java"}
+	nonCompType := []string{"node", "npm", "python", "ruby"}
+	projectType := bc.Config.ProjectType
+	var dir string
+	if contains(compType, projectType) {
+		dir = bc.WorkspaceDir
+	} else if contains(nonCompType, projectType) {
+		dir = bc.WorkspaceDir + "/temp/"
+	} else {
+		return nil, "", fmt.Errorf("docker build needs a projecttype in the builder.yaml, got %q", projectType)
+	}
+
+	return dockerCmds, dir, nil
 }
 
 func contains(s []string, str string) bool {
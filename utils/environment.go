@@ -0,0 +1,152 @@
+package utils
+
+import (
+	"Builder/utils/log"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// hermeticEnv are the variables of Builder's environment build commands get in hermetic mode
+var hermeticEnv = []string{
+	// system
+	"PATH", "HOME", "USER", "TMPDIR", "TZ", "LANG", "LANGUAGE",
+	"SSL_CERT_FILE", "SSL_CERT_DIR", "HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY",
+	// windows
+	"SYSTEMROOT", "WINDIR", "COMSPEC", "PATHEXT", "TEMP", "TMP", "USERPROFILE", "USERNAME",
+	"APPDATA", "LOCALAPPDATA", "PROGRAMDATA", "PROGRAMFILES", "PROGRAMFILES(X86)",
+	// toolchains
+	"GOROOT", "GOPATH", "GOCACHE", "GOMODCACHE", "GOPROXY", "GOPRIVATE", "GONOSUMDB", "GOTOOLCHAIN",
+	"CARGO_HOME", "RUSTUP_HOME", "RUSTUP_TOOLCHAIN",
+	"JAVA_HOME", "MAVEN_HOME", "M2_HOME", "GRADLE_HOME", "GRADLE_USER_HOME",
+	"NVM_DIR", "NPM_CONFIG_CACHE", "NPM_CONFIG_PREFIX",
+	"PYENV_ROOT", "VIRTUAL_ENV", "PIP_CACHE_DIR",
+	"GEM_HOME", "GEM_PATH", "RBENV_ROOT", "BUNDLE_PATH",
+	"DOTNET_ROOT", "NUGET_PACKAGES",
+}
+
+// hermeticEnvPrefixes are the prefixes of the variables build commands also get in hermetic mode
+var hermeticEnvPrefixes = []string{"LC_"}
+
+// Hermetic reports whether the build commands only get an allowlisted environment, set by
+// --hermetic or hermetic in the builder.yaml
+func (bc *BuildContext) Hermetic() bool {
+	return bc.Flags.Hermetic || bc.Config.Hermetic
+}
+
+// CommandEnv returns the environment of a build command with env (NAME=value) added, to be
+// used as exec.Cmd.Env.  It's Builder's environment, or in hermetic mode only its
+// allowlisted variables, plus the builder.yaml env and secrets, the stamp variables, the
+// variables of the target being built and then env.
+func (bc *BuildContext) CommandEnv(env []string) []string {
+	env = append(append(append(bc.BuildEnv(), bc.StampEnv()...), bc.TargetEnv...), env...)
+	if bc.Hermetic() {
+		return append(allowedEnv(os.Environ()), env...)
+	}
+
+	return append(os.Environ(), env...)
+}
+
+// EffectiveEnv returns the environment the build commands get before a step's or command's
+// own variables are added, sorted and with the values of the secrets replaced with ***
+func (bc *BuildContext) EffectiveEnv() []string {
+	env := bc.CommandEnv(nil)
+
+	// later entries win, like they do for exec
+	values := map[string]string{}
+	for _, pair := range env {
+		name, value := splitEnv(pair)
+		values[name] = value
+	}
+
+	var effective []string
+	for name, value := range values {
+		if _, secret := bc.secrets[name]; secret {
+			value = log.Redacted
+		}
+		effective = append(effective, name+"="+bc.Redact(value))
+	}
+	sort.Strings(effective)
+
+	return effective
+}
+
+// allowedEnv returns the variables of env that are in the hermetic allowlist
+func allowedEnv(env []string) []string {
+	var allowed []string
+	for _, pair := range env {
+		name, _ := splitEnv(pair)
+		if isHermeticEnv(name) {
+			allowed = append(allowed, pair)
+		}
+	}
+
+	return allowed
+}
+
+func isHermeticEnv(name string) bool {
+	// windows variable names aren't case sensitive, the proxy variables are often lowercase
+	upper := strings.ToUpper(name)
+	if upper != name && runtime.GOOS != "windows" && !strings.HasSuffix(upper, "_PROXY") {
+		return false
+	}
+
+	for _, allowed := range hermeticEnv {
+		if upper == allowed {
+			return true
+		}
+	}
+	for _, prefix := range hermeticEnvPrefixes {
+		if strings.HasPrefix(upper, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// LookPath finds the executable name the way exec.LookPath does, but in the PATH of env (a
+// command environment, later entries win) rather than Builder's own, so a PATH set in the
+// builder.yaml env or cut down by hermetic mode picks the executable.  A name with a path
+// separator is taken relative to dir.  Relative PATH entries are skipped, like exec.LookPath
+// does.
+func LookPath(name, dir string, env []string) (string, error) {
+	if strings.ContainsRune(name, '/') || strings.ContainsRune(name, filepath.Separator) {
+		if !filepath.IsAbs(name) {
+			name = filepath.Join(dir, name)
+		}
+		return exec.LookPath(name)
+	}
+
+	pathEnv, _ := lookupVar(env, "PATH")
+	for _, pathDir := range filepath.SplitList(pathEnv) {
+		if !filepath.IsAbs(pathDir) {
+			continue
+		}
+		// with a path exec.LookPath only checks the file is executable, and adds the PATHEXT
+		// extensions on windows
+		if path, err := exec.LookPath(filepath.Join(pathDir, name)); err == nil {
+			return path, nil
+		}
+	}
+
+	return "", fmt.Errorf("%s: %w in the PATH of the build commands", name, exec.ErrNotFound)
+}
+
+// splitEnv splits a NAME=value pair
+func splitEnv(pair string) (string, string) {
+	// windows has variables like =C:=C:\ that start with =
+	if i := strings.Index(pair, "="); i == 0 {
+		if j := strings.Index(pair[1:], "="); j >= 0 {
+			return pair[:j+1], pair[j+2:]
+		}
+	} else if i > 0 {
+		return pair[:i], pair[i+1:]
+	}
+
+	return pair, ""
+}
@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestLookPath(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("executables need an extension on windows")
+	}
+
+	first, second, buildDir := t.TempDir(), t.TempDir(), t.TempDir()
+	for _, path := range []string{filepath.Join(first, "tool"), filepath.Join(second, "tool"), filepath.Join(buildDir, "gradlew")} {
+		if err := os.WriteFile(path, []byte("#!/bin/sh\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(first, "plain"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		env  []string
+		want string
+	}{
+		{"tool", []string{"PATH=" + first + ":" + second}, filepath.Join(first, "tool")},
+		// the last PATH wins, like it does for the command
+		{"tool", []string{"PATH=" + first, "PATH=relative:" + second}, filepath.Join(second, "tool")},
+		{"./gradlew", nil, filepath.Join(buildDir, "gradlew")},
+		{"plain", []string{"PATH=" + first}, ""},
+		{"tool", nil, ""},
+	}
+
+	for _, test := range tests {
+		got, err := LookPath(test.name, buildDir, test.env)
+		if test.want == "" {
+			if err == nil {
+				t.Errorf("LookPath(%q, %q) = %q, want an error", test.name, test.env, got)
+			}
+			continue
+		}
+		if err != nil || got != test.want {
+			t.Errorf("LookPath(%q, %q) = %q, %v, want %q", test.name, test.env, got, err, test.want)
+		}
+	}
+}
@@ -2,44 +2,35 @@ package utils
 
 import (
 	"Builder/spinner"
-	"os"
 	"os/exec"
+	"strings"
 )
 
-func GetRepoURL() string {
-	args := os.Args[1:]
-
-	//grab URL first
-	var repo string
-	for i, v := range args {
-		if v == "init" || v == "config" {
-			if len(args) <= i+1 {
-				// logger.ErrorLogger.Println("No Repo Url Provided")
-				spinner.LogMessage("No Repo Url Provided", "fatal")
+// GetRepoURL returns the repo given to init/config, otherwise the origin url of the
+// current dir, otherwise the giturl from the builder.yaml
+func GetRepoURL(bc *BuildContext) string {
+	if bc.RepoURL != "" {
+		return bc.RepoURL
+	}
 
-			} else {
-				repo = args[i+1]
-			}
-		}
+	// init and config need the repo passed in, CheckArgs reports it missing
+	if !bc.IsBuilderCommand() {
+		return ""
 	}
-	if repo == "" {
-		// Get repo name from git config file
-		out, err := exec.Command("git", "config", "--get", "remote.origin.url").Output()
-		if err != nil {
+
+	// Get repo name from git config file
+	out, err := exec.Command("git", "config", "--get", "remote.origin.url").Output()
+	repo := strings.TrimSuffix(string(out), "\n")
+	if err != nil || repo == "" {
+		if bc.Config.GitURL != "" {
+			repo = bc.Config.GitURL
+		} else {
 			spinner.LogMessage("Can't find git URL.  Please provide it in the builder.yaml", "info")
 			return ""
 		}
+	}
 
-		repo = string(out[:len(out)-1]) // Get rid of last char because it is a newline char
-
-		if repo == "" {
-			if os.Getenv("GIT_URL") != "" {
-				repo = os.Getenv("GIT_URL")
-			} else {
-				spinner.LogMessage("Can't find git URL.  Please provide it in the builder.yaml", "info")
-			}
-		}
+	bc.RepoURL = repo
 
-	}
 	return repo
 }
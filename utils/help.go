@@ -5,20 +5,16 @@ import (
 	"os"
 )
 
-// Help is application info
+// Help prints application info if the help flag is given
 func Help() {
-	cArgs := os.Args[1:]
-	var helpExists bool
-
-	for _, v := range cArgs {
-		if v == "--help" || v == "-h" {
-			helpExists = true
-		}
+	if flags, _ := ParseFlags(os.Args[1:]); flags.Help {
+		PrintHelp()
 	}
+}
 
-	//check for help flag or builder cmd to print info
-	if (os.Getenv("BUILDER_COMMAND") == "true") || helpExists {
-		fmt.Println(`
+// PrintHelp prints application info and exits
+func PrintHelp() {
+	fmt.Println(`
 		   🔨 BUILDER 🔨
 													
 	       #%&&&%  ,&&            
@@ -42,7 +38,21 @@ func Help() {
 	- ex: builder config <repo> <flags>
 * builder: build project w/ builder.yaml while in the projects directory (no repo needed) 
 	- ex: builder <flags> 
+* builder plan: show what a build would do without doing it: dirs, commands, artifacts and outputs (repo optional)
+	- ex: builder plan <repo> <flags> --json
+* builder validate: check a builder.yaml for unknown keys, wrong types and keys that don't work together
+	- ex: builder validate <builder.yaml | dir> --json (--schema prints the JSON Schema)
+* builder config list|get|set|unset: show and edit the global config (~/.builder/config.yaml, /etc/builder/config.yaml with --system)
+	- ex: builder config set outputpath /srv/artifacts
+* builder secret: store the secrets builder.yaml secrets read with store: in the encrypted ~/.builder/secrets.enc
+	- ex: builder secret set <name> [<value>] | builder secret list | builder secret rm <name>
 * builder gui: display the Builder GUI (requires Chrome for use)
+* builder history: list past builds
+	- ex: builder history --project <name> --branch <branch> --user <user> --status failed --since 2024-01-01 --until 2024-01-31 --json
+* builder show: show everything recorded about a build
+	- ex: builder show <buildID> --json
+* builder verify: recompute the checksums of a build's artifacts and compare them to its metadata
+	- ex: builder verify <artifact dir | archive | buildID> --json
 
 			Flags
 
@@ -53,6 +63,11 @@ func Help() {
 * '--debug' or '-d': show Builder log output
 * '--verbose' or '-v': show log output for project being built
 * '--docker' or '-D': build Docker image
+* '--test' or '-t': run the project's tests after building it
+* '--timeout': how long the build may run before it is stopped ("90s", "30m")
+* '--profile': build with a profile of the builder.yaml ("--profile prod")
+* '--hermetic': give the build commands only PATH, HOME, the locale, toolchain variables and the builder.yaml env/secrets
+* '--dry-run': print the build plan (same as builder plan) instead of building, add '--json' for JSON
 
 
 		builder.yaml params
@@ -62,11 +77,22 @@ func Help() {
 * projectpath: provide path for project to be built
   - ("/Users/Name/Projects", etc)
 * projecttype: provide language/framework being used
-  - ("Node", "Java", "Go", "Rust", "Python", "C#", "Ruby")
+  - ("Node", "Java", "Gradle", "Kotlin", "Go", "Rust", "Python", "C#", "Ruby")
 * buildtool: provide tool used to install dependencies/build project
   - ("maven", "npm", "bundler", "pipenv", etc)
 * buildfile: provide file name needed to install dep/build project
   - Can be any user specified file. ("myCoolProject.go", "package.json" etc)
+* gradletask: for Gradle projects only. Gradle task(s) to run instead of build
+  - ("clean bootJar")
+* gopackages: for Go modules only. Comma separated main packages to build instead of every package main
+  - ("./cmd/api,./cmd/worker", "example.com/tool/cmd/tool")
+* targets: for Go and Rust projects only. Platforms to cross-compile for, one artifact each named with the platform
+  - ([linux/amd64, windows/amd64], [aarch64-apple-darwin])
+* stamp: put the git hash, branch, build ID and timestamp into the artifacts (-ldflags -X for Go,
+  BUILDER_* variables for Rust/C, build-info.properties for Java, builder-info.json for Node/Python/Ruby)
+  - (true, defaults to false)
+* stampvars: for Go projects only. Variables githash, branch, buildid and timestamp are set in
+  - ({githash: main.commit, timestamp: main.date})
 * prebuildcmd: for C/C++ projects only. Provide command to run before configcmd and buildcmd 
   - ("autoreconf -vfi", "./autogen.sh", etc)
 * configcmd: for C/C++ projects only. provide full command to configure C/C++ project before running buildcmd
@@ -81,7 +107,27 @@ func Help() {
   - ("docker build -t my-project:1.3 .")
 * repobranch: specify repo branch name
   - (“feature/“new-branch”)
+* shell: run the builder.yaml commands through the system shell (/bin/sh -c), needed for pipes, && and redirects
+  - (true)
+* timeout: how long the build may run before it is stopped
+  - ("30m", "600")
+* test: run the tests after the build with the default test command ("go test -json ./...", "mvn test", "npm test", etc)
+  - (true)
+* testcmd: provide command to run the tests
+  - ("go test -json ./...", "npm run test:ci", etc)
+* testresults: provide comma seperated list of JUnit XML reports to read the test results from
+  - ("target/surefire-reports/TEST-*.xml")
+* env: environment variables added for the build commands
+  - ("env: {CGO_ENABLED: \"0\", GOFLAGS: -mod=vendor}")
+* secrets: environment variables for the build commands read from Builder's env, a file or builder secret, redacted from logs and metadata
+  - ("secrets: {NPM_TOKEN: {env: CI_NPM_TOKEN}, DB_PASSWORD: {file: /run/secrets/db}, API_KEY: {store: api-key}}")
+* hermetic: same as --hermetic, the environment the build commands get is recorded in the metadata
+  - (true)
+* profiles: named sets of builder.yaml keys that overlay the rest when selected with --profile or profile
+  - ("profiles: {prod: {outputpath: /srv/prod}}")
+* buildcmd, env, outputpath, dockercmd and artifactlist can use ${git.sha}, ${build.id}, ${build.timestamp} and ${VAR} (env)
+* prebuildcmd, configcmd, buildcmd and dockercmd also take a list of commands that are run in order
+  - ("[go vet ./..., go build -o app]")
 			`)
-		os.Exit(0)
-	}
+	os.Exit(0)
 }
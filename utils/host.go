@@ -0,0 +1,190 @@
+package utils
+
+import (
+	"bufio"
+	"net"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Host identifies the machine a build ran on.  It is collected from the local machine only,
+// fields that can't be read are left empty.
+type Host struct {
+	Hostname    string   `json:"hostname" yaml:"hostname"`
+	Addresses   []string `json:"addresses" yaml:"addresses"`
+	MachineID   string   `json:"machineId" yaml:"machineId"`
+	OS          string   `json:"os" yaml:"os"`
+	Kernel      string   `json:"kernel" yaml:"kernel"`
+	Arch        string   `json:"arch" yaml:"arch"`
+	CPUs        int      `json:"cpus" yaml:"cpus"`
+	MemoryBytes uint64   `json:"memoryBytes" yaml:"memoryBytes"`
+}
+
+// GetHost collects the identity of the machine Builder is running on
+func GetHost() Host {
+	hostName, _ := os.Hostname()
+
+	return Host{
+		Hostname:    hostName,
+		Addresses:   hostAddresses(),
+		MachineID:   machineID(),
+		OS:          runtime.GOOS,
+		Kernel:      kernelVersion(),
+		Arch:        runtime.GOARCH,
+		CPUs:        runtime.NumCPU(),
+		MemoryBytes: totalMemory(),
+	}
+}
+
+// PrimaryIP returns the host's first IPv4 address, or its first address if it has no IPv4 one
+func (h Host) PrimaryIP() string {
+	for _, address := range h.Addresses {
+		if ip := net.ParseIP(address); ip != nil && ip.To4() != nil {
+			return address
+		}
+	}
+
+	if len(h.Addresses) > 0 {
+		return h.Addresses[0]
+	}
+
+	return ""
+}
+
+// hostAddresses returns the addresses of every interface that is up, except loopback ones
+func hostAddresses() []string {
+	addresses := []string{}
+
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return addresses
+	}
+
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+
+		for _, addr := range addrs {
+			var ip net.IP
+			switch v := addr.(type) {
+			case *net.IPNet:
+				ip = v.IP
+			case *net.IPAddr:
+				ip = v.IP
+			}
+
+			if ip != nil && !ip.IsLoopback() {
+				addresses = append(addresses, ip.String())
+			}
+		}
+	}
+
+	return addresses
+}
+
+func machineID() string {
+	switch runtime.GOOS {
+	case "windows":
+		out, err := exec.Command("reg", "query", `HKLM\SOFTWARE\Microsoft\Cryptography`, "/v", "MachineGuid").Output()
+		if err != nil {
+			return ""
+		}
+		fields := strings.Fields(string(out))
+		for i, field := range fields {
+			if field == "REG_SZ" && i+1 < len(fields) {
+				return fields[i+1]
+			}
+		}
+	case "darwin":
+		out, err := exec.Command("ioreg", "-rd1", "-c", "IOPlatformExpertDevice").Output()
+		if err != nil {
+			return ""
+		}
+		for _, line := range strings.Split(string(out), "\n") {
+			if strings.Contains(line, "IOPlatformUUID") {
+				parts := strings.Split(line, "\"")
+				if len(parts) >= 4 {
+					return parts[3]
+				}
+			}
+		}
+	default:
+		for _, path := range []string{"/etc/machine-id", "/var/lib/dbus/machine-id"} {
+			if id, err := os.ReadFile(path); err == nil {
+				return strings.TrimSpace(string(id))
+			}
+		}
+	}
+
+	return ""
+}
+
+func kernelVersion() string {
+	switch runtime.GOOS {
+	case "windows":
+		out, err := exec.Command("cmd", "/c", "ver").Output()
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(string(out))
+	case "linux":
+		if release, err := os.ReadFile("/proc/sys/kernel/osrelease"); err == nil {
+			return strings.TrimSpace(string(release))
+		}
+	}
+
+	out, err := exec.Command("uname", "-r").Output()
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(out))
+}
+
+// totalMemory returns the host's physical memory in bytes
+func totalMemory() uint64 {
+	switch runtime.GOOS {
+	case "linux":
+		meminfo, err := os.Open("/proc/meminfo")
+		if err != nil {
+			return 0
+		}
+		defer meminfo.Close()
+
+		scanner := bufio.NewScanner(meminfo)
+		for scanner.Scan() {
+			// MemTotal:       16307452 kB
+			fields := strings.Fields(scanner.Text())
+			if len(fields) >= 2 && fields[0] == "MemTotal:" {
+				kb, _ := strconv.ParseUint(fields[1], 10, 64)
+				return kb * 1024
+			}
+		}
+	case "darwin", "freebsd":
+		out, err := exec.Command("sysctl", "-n", "hw.memsize").Output()
+		if err != nil {
+			out, err = exec.Command("sysctl", "-n", "hw.physmem").Output()
+		}
+		if err == nil {
+			memory, _ := strconv.ParseUint(strings.TrimSpace(string(out)), 10, 64)
+			return memory
+		}
+	case "windows":
+		out, err := exec.Command("powershell", "-NoProfile", "-Command", "(Get-CimInstance Win32_ComputerSystem).TotalPhysicalMemory").Output()
+		if err == nil {
+			memory, _ := strconv.ParseUint(strings.TrimSpace(string(out)), 10, 64)
+			return memory
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,255 @@
+package utils
+
+import (
+	"Builder/spinner"
+	"Builder/utils/log"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// InterpolateConfig fills in the ${...} variables of the builder.yaml buildcmd, steps, env,
+// outputpath, dockercmd and artifactlist.  srcDir is the repo ${git.sha} is read from.  The
+// env values read Builder's environment, so they can pass a variable through to a hermetic
+// build, everything else reads the environment of the build commands.  A variable that isn't
+// set is replaced with nothing and logged as a warning.
+func InterpolateConfig(bc *BuildContext, srcDir string) error {
+	vars := builderVars(bc, srcDir)
+	// commands are split by ParseCommand unless they're run through the shell
+	inCommand := parsedCommand
+	if bc.Config.Shell {
+		inCommand = shellCommand
+	}
+
+	undefined := map[string]bool{}
+	var env []string
+	fill := func(s string, mode interpolation) (string, error) {
+		value, names, err := interpolate(s, vars, env, mode)
+		for _, name := range names {
+			undefined[name] = true
+		}
+		return value, err
+	}
+
+	var err error
+	if len(bc.Config.Env) > 0 {
+		env = append(os.Environ(), bc.secretEnv()...)
+		values := map[string]string{}
+		for name, value := range bc.Config.Env {
+			if values[name], err = fill(value, plainText); err != nil {
+				return fmt.Errorf("env %s: %w", name, err)
+			}
+		}
+		bc.Config.Env = values
+	}
+
+	env = bc.CommandEnv(nil)
+	if bc.secrets == nil {
+		env = append(env, bc.secretEnv()...)
+	}
+	if bc.Config.OutputPath, err = fill(bc.Config.OutputPath, plainText); err != nil {
+		return fmt.Errorf("outputpath: %w", err)
+	}
+	if bc.Config.ArtifactList, err = fill(bc.Config.ArtifactList, plainText); err != nil {
+		return fmt.Errorf("artifactlist: %w", err)
+	}
+
+	for i := range bc.Config.BuildCmd {
+		if bc.Config.BuildCmd[i], err = fill(bc.Config.BuildCmd[i], inCommand); err != nil {
+			return fmt.Errorf("buildcmd: %w", err)
+		}
+	}
+	for i := range bc.Config.DockerCmd {
+		if bc.Config.DockerCmd[i], err = fill(bc.Config.DockerCmd[i], inCommand); err != nil {
+			return fmt.Errorf("dockercmd: %w", err)
+		}
+	}
+	for i := range bc.Config.Steps {
+		for j := range bc.Config.Steps[i].Command {
+			if bc.Config.Steps[i].Command[j], err = fill(bc.Config.Steps[i].Command[j], inCommand); err != nil {
+				return fmt.Errorf("step %d: %w", i+1, err)
+			}
+		}
+	}
+
+	names := make([]string, 0, len(undefined))
+	for name := range undefined {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		msg := "${" + name + "} isn't set in the environment of the build commands, it's replaced with nothing"
+		if bc.Hermetic() {
+			msg += ", hermetic builds only pass through the variables declared in env"
+		}
+		spinner.LogMessage(msg, "warn")
+	}
+
+	return nil
+}
+
+// secretEnv returns the secrets as NAME=value pairs.  Before LoadSecrets has read them, as in
+// builder plan, they're *** so the secrets count as set.
+func (bc *BuildContext) secretEnv() []string {
+	var env []string
+	for name := range bc.Config.Secrets {
+		if bc.secrets == nil {
+			env = append(env, name+"="+log.Redacted)
+		} else if value, ok := bc.secrets[name]; ok {
+			env = append(env, name+"="+value)
+		}
+	}
+	sort.Strings(env)
+
+	return env
+}
+
+// builderVars are the variables Builder provides, looked up when they're first used
+func builderVars(bc *BuildContext, srcDir string) map[string]func() (string, error) {
+	var sha string
+
+	return map[string]func() (string, error){
+		"git.sha": func() (string, error) {
+			if sha == "" {
+				cmd := exec.Command("git", "rev-parse", "HEAD")
+				cmd.Dir = srcDir
+				out, err := cmd.Output()
+				if err != nil {
+					return "", fmt.Errorf("could not read the git hash of %s: %w", srcDir, err)
+				}
+				sha = strings.TrimSpace(string(out))
+			}
+			return sha, nil
+		},
+		"build.id": func() (string, error) {
+			return bc.BuildID, nil
+		},
+		"build.timestamp": func() (string, error) {
+			return strconv.FormatInt(bc.StartTime.Unix(), 10), nil
+		},
+	}
+}
+
+// interpolation is what a string with ${...} variables is used as, it decides how the values
+// and the $${ escape are written into it
+type interpolation int
+
+const (
+	// plainText is used as it is, a path or an env value
+	plainText interpolation = iota
+	// parsedCommand is split into words by ParseCommand
+	parsedCommand
+	// shellCommand is run through the shell
+	shellCommand
+)
+
+// interpolate replaces the ${name} variables in s with the Builder variable of that name, or
+// else the variable of env.  A variable that isn't set is replaced with nothing, like a shell
+// does, and its name returned, an unknown git. or build. variable is an error.  In a parsed
+// command the values are quoted so ParseCommand keeps them as they are.  $${ is a literal ${,
+// in a command it's written as \${ outside of single quotes so neither ParseCommand nor the
+// shell expands it.
+func interpolate(s string, vars map[string]func() (string, error), env []string, mode interpolation) (string, []string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil, nil
+	}
+
+	var out strings.Builder
+	var undefined []string
+	i := 0
+	for {
+		start := strings.Index(s[i:], "${")
+		if start < 0 {
+			out.WriteString(s[i:])
+			return out.String(), undefined, nil
+		}
+		start += i
+
+		// $${ escapes the variable, cmd.exe doesn't expand ${ so it needs no escaping
+		if start > 0 && s[start-1] == '$' {
+			out.WriteString(s[i : start-1])
+			escape := mode == parsedCommand || (mode == shellCommand && runtime.GOOS != "windows")
+			if escape && openQuote(s[:start-1]) != '\'' {
+				out.WriteString(`\`)
+			}
+			out.WriteString("${")
+			i = start + 2
+			continue
+		}
+
+		end := strings.IndexByte(s[start:], '}')
+		if end < 0 {
+			return "", nil, fmt.Errorf("unterminated ${ in %q", s)
+		}
+		name := s[start+2 : start+end]
+
+		var value string
+		if lookup, ok := vars[name]; ok {
+			var err error
+			if value, err = lookup(); err != nil {
+				return "", nil, err
+			}
+		} else if strings.HasPrefix(name, "git.") || strings.HasPrefix(name, "build.") {
+			return "", nil, fmt.Errorf("unknown variable ${%s}", name)
+		} else {
+			var err error
+			if value, err = lookupVar(env, name); err != nil {
+				undefined = append(undefined, name)
+			}
+		}
+
+		out.WriteString(s[i:start])
+		if mode == parsedCommand {
+			value = quoteValue(value, openQuote(s[:start]))
+		}
+		out.WriteString(value)
+		i = start + end + 1
+	}
+}
+
+// quoteValue quotes value so ParseCommand reads it back as it is, as part of the current
+// word.  quote is the quote value is written inside of, see openQuote.
+func quoteValue(value string, quote rune) string {
+	switch quote {
+	case '\'':
+		return strings.ReplaceAll(value, "'", `'\''`)
+	case '"':
+		return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`").Replace(value)
+	}
+
+	// an empty value outside of quotes adds no word, like a shell
+	if value == "" {
+		return ""
+	}
+	return QuoteCommand([]string{value})
+}
+
+// openQuote returns the quote (' or ") ParseCommand would be inside of at the end of s, or 0
+func openQuote(s string) rune {
+	var quote rune
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			}
+		case r == '\\':
+			escaped = true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		}
+	}
+
+	return quote
+}
@@ -0,0 +1,159 @@
+package utils
+
+import (
+	"Builder/yaml"
+	"errors"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func testVars() map[string]func() (string, error) {
+	return map[string]func() (string, error){
+		"git.sha":  func() (string, error) { return "abc123", nil },
+		"build.id": func() (string, error) { return "42", nil },
+		"git.fail": func() (string, error) { return "", errors.New("no repo") },
+	}
+}
+
+func TestInterpolate(t *testing.T) {
+	// cmd.exe doesn't expand ${, so the escape isn't escaped for it
+	shellEscape := `\`
+	if runtime.GOOS == "windows" {
+		shellEscape = ""
+	}
+	env := []string{"SET=set", "EMPTY=", "SPACES=a b", `QUOTES=it's "x"`, "DOLLAR=$HOME", "SET=later"}
+
+	tests := []struct {
+		in        string
+		mode      interpolation
+		want      string
+		undefined []string
+		err       bool
+	}{
+		{"no variables", plainText, "no variables", nil, false},
+		{"app-${git.sha}-${build.id}", plainText, "app-abc123-42", nil, false},
+		{"${SET}/${EMPTY}x", plainText, "later/x", nil, false},
+		{"a${UNSET}b", plainText, "ab", []string{"UNSET"}, false},
+		{"${SPACES}", plainText, "a b", nil, false},
+		{"$${HOME}", plainText, "${HOME}", nil, false},
+		{"echo ${SPACES} ${EMPTY} x", parsedCommand, "echo 'a b'  x", nil, false},
+		{"echo --v=${DOLLAR}", parsedCommand, "echo --v='$HOME'", nil, false},
+		{`echo "${QUOTES}"`, parsedCommand, `echo "it's \"x\""`, nil, false},
+		{`echo '${QUOTES}'`, parsedCommand, `echo 'it'\''s "x"'`, nil, false},
+		{"echo ${SPACES}", shellCommand, "echo a b", nil, false},
+		{"for f in *; do echo $${f}; done", shellCommand, "for f in *; do echo " + shellEscape + "${f}; done", nil, false},
+		{"echo $${HOME}", parsedCommand, `echo \${HOME}`, nil, false},
+		{`echo "$${HOME}"`, parsedCommand, `echo "\${HOME}"`, nil, false},
+		{`echo '$${HOME}'`, parsedCommand, `echo '${HOME}'`, nil, false},
+		{`echo 'it''s' $${A} "'" $${B}`, parsedCommand, `echo 'it''s' \${A} "'" \${B}`, nil, false},
+		{"${build.nope}", plainText, "", nil, true},
+		{"${git.fail}", plainText, "", nil, true},
+		{"${unterminated", plainText, "", nil, true},
+	}
+	for _, test := range tests {
+		got, undefined, err := interpolate(test.in, testVars(), env, test.mode)
+		if (err != nil) != test.err {
+			t.Errorf("interpolate(%q) error = %v, want error %v", test.in, err, test.err)
+			continue
+		}
+		if test.err {
+			continue
+		}
+		if got != test.want || !reflect.DeepEqual(undefined, test.undefined) {
+			t.Errorf("interpolate(%q, %v) = %q, %q, want %q, %q", test.in, test.mode, got, undefined, test.want, test.undefined)
+		}
+	}
+}
+
+// env passes Builder's variables through, commands only see the ones hermetic builds get
+func TestInterpolateConfig(t *testing.T) {
+	t.Setenv("BUILDER_TEST_PASS", "from host")
+	t.Setenv("BUILDER_TEST_HIDDEN", "hidden")
+
+	tests := []struct {
+		hermetic bool
+		want     string
+	}{
+		{false, "tool 'from host' hidden '***'"},
+		{true, "tool 'from host'  '***'"},
+	}
+
+	for _, test := range tests {
+		bc := &BuildContext{}
+		bc.Config.Hermetic = test.hermetic
+		bc.Config.Env = map[string]string{"PASS": "${BUILDER_TEST_PASS}"}
+		bc.Config.Secrets = map[string]yaml.Secret{"TOKEN": {Env: "BUILDER_TEST_TOKEN"}}
+		bc.Config.BuildCmd = yaml.Commands{"tool ${PASS} ${BUILDER_TEST_HIDDEN} ${TOKEN}"}
+
+		if err := InterpolateConfig(bc, "."); err != nil {
+			t.Fatalf("InterpolateConfig() error = %v", err)
+		}
+		if bc.Config.Env["PASS"] != "from host" {
+			t.Errorf("hermetic %v: env PASS = %q, want %q", test.hermetic, bc.Config.Env["PASS"], "from host")
+		}
+		if got := bc.Config.BuildCmd[0]; got != test.want {
+			t.Errorf("hermetic %v: buildcmd = %q, want %q", test.hermetic, got, test.want)
+		}
+	}
+}
+
+// the values and the escape have to survive ParseCommand as they are
+func TestInterpolateSurvivesParseCommand(t *testing.T) {
+	env := []string{"X=expanded", "ARGS=a b", `TRICKY=it's "$X" \ ` + "`x`"}
+
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"tool --template $${X}", []string{"tool", "--template", "${X}"}},
+		{`tool "--template=$${X}"`, []string{"tool", "--template=${X}"}},
+		{`tool '--template=$${X}'`, []string{"tool", "--template=${X}"}},
+		{"tool $X $${X}", []string{"tool", "expanded", "${X}"}},
+		{"tool ${ARGS}", []string{"tool", "a b"}},
+		{"tool --flag=${ARGS}x", []string{"tool", "--flag=a bx"}},
+		{"tool ${TRICKY}", []string{"tool", `it's "$X" \ ` + "`x`"}},
+		{`tool "${TRICKY}"`, []string{"tool", `it's "$X" \ ` + "`x`"}},
+		{`tool '${TRICKY}'`, []string{"tool", `it's "$X" \ ` + "`x`"}},
+		{"tool ${UNSET} x", []string{"tool", "x"}},
+	}
+
+	for _, test := range tests {
+		command, _, err := interpolate(test.in, testVars(), env, parsedCommand)
+		if err != nil {
+			t.Errorf("interpolate(%q) error = %v", test.in, err)
+			continue
+		}
+		got, err := ParseCommand(command, env)
+		if err != nil {
+			t.Errorf("ParseCommand(%q) error = %v", command, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q parsed as %q, want %q", test.in, got, test.want)
+		}
+	}
+}
+
+func TestOpenQuote(t *testing.T) {
+	tests := []struct {
+		in   string
+		want rune
+	}{
+		{"echo", 0},
+		{"echo '", '\''},
+		{`echo "`, '"'},
+		{`echo "it's`, '"'},
+		{`echo 'say "hi`, '\''},
+		{`echo \'`, 0},
+		{`echo "\"`, '"'},
+		{`echo 'a\`, '\''},
+		{`echo 'a' "b"`, 0},
+	}
+
+	for _, test := range tests {
+		if got := openQuote(test.in); got != test.want {
+			t.Errorf("openQuote(%q) = %q, want %q", test.in, got, test.want)
+		}
+	}
+}
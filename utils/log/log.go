@@ -9,9 +9,9 @@ import (
 	"go.uber.org/zap/zapcore"
 )
 
-var logger *zap.Logger
-
-func NewLogger(logFileName string, path string) (*zap.Logger, func()) {
+// NewLogger creates a zap logger writing to path/logFileName.json.  If verbose is set the
+// output is also printed to the console, if debug is set the caller is added to each entry.
+func NewLogger(logFileName string, path string, verbose bool, debug bool) (*zap.Logger, func(), error) {
 	defaultLogLevel := zapcore.Level(logLevel)
 
 	config := zap.NewProductionEncoderConfig()
@@ -22,26 +22,14 @@ func NewLogger(logFileName string, path string) (*zap.Logger, func()) {
 
 	writer, closeFile, err := zap.Open(filepath.Join(path, logFileName+".json"))
 	if err != nil {
-		fmt.Println("logger err")
-	}
-
-	// If debug flag given display caller in log and print build logs to console
-	args := os.Args[1:]
-	verboseFlag := false
-	debugFlag := false
-	for i := 0; i < len(args); i++ {
-		if args[i] == "-v" || args[i] == "--verbose" {
-			verboseFlag = true
-		}
-		if args[i] == "-d" || args[i] == "--debug" {
-			debugFlag = true
-		}
+		return nil, nil, fmt.Errorf("could not open log file %s: %w", logFileName, err)
 	}
 
 	var core zapcore.Core
+	var logger *zap.Logger
 
 	// If verbose flag given display build logs to console as well as to file
-	if verboseFlag {
+	if verbose {
 		fileEncoder := zapcore.NewJSONEncoder(config)
 		consoleEncoder := zapcore.NewConsoleEncoder(config)
 		core = zapcore.NewTee(
@@ -56,13 +44,13 @@ func NewLogger(logFileName string, path string) (*zap.Logger, func()) {
 	}
 
 	// If debug flag given add Builder caller to build logs
-	if debugFlag {
+	if debug {
 		logger = zap.New(core, zap.AddCaller(), zap.AddStacktrace(zapcore.ErrorLevel))
 	} else {
 		logger = zap.New(core, zap.AddStacktrace(zapcore.ErrorLevel))
 	}
 
-	return logger, closeFile
+	return logger, closeFile, nil
 }
 
 func init() {
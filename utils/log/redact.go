@@ -0,0 +1,101 @@
+package log
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Redacted replaces a secret in the logs
+const Redacted = "***"
+
+// MinRedactLength is the length a secret needs to be redacted, shorter values would turn
+// every occurrence of a common string in the logs into ***
+const MinRedactLength = 4
+
+// Redactor replaces the values of secrets with *** in the log entries it's wrapped around.
+// Values can be added after the logger is created, they're redacted from then on.
+type Redactor struct {
+	mu     sync.RWMutex
+	values []string
+}
+
+// Add adds values to the secrets that are redacted, values shorter than MinRedactLength are
+// left out
+func (r *Redactor) Add(values ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, value := range values {
+		if len(value) >= MinRedactLength {
+			r.values = append(r.values, value)
+		}
+	}
+
+	// longest first so a secret containing another is redacted whole
+	sort.Slice(r.values, func(i, j int) bool { return len(r.values[i]) > len(r.values[j]) })
+}
+
+// Redact returns s with the secrets replaced with ***
+func (r *Redactor) Redact(s string) string {
+	if r == nil {
+		return s
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, value := range r.values {
+		s = strings.ReplaceAll(s, value, Redacted)
+	}
+
+	return s
+}
+
+// Wrap returns logger with the secrets redacted from its messages and string fields
+func (r *Redactor) Wrap(logger *zap.Logger) *zap.Logger {
+	return logger.WithOptions(zap.WrapCore(r.Core))
+}
+
+// Core returns core with the secrets redacted from its messages and string fields
+func (r *Redactor) Core(core zapcore.Core) zapcore.Core {
+	return &redactCore{Core: core, redactor: r}
+}
+
+type redactCore struct {
+	zapcore.Core
+	redactor *Redactor
+}
+
+func (c *redactCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactCore{Core: c.Core.With(c.redactFields(fields)), redactor: c.redactor}
+}
+
+func (c *redactCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+
+	return checked
+}
+
+func (c *redactCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	entry.Message = c.redactor.Redact(entry.Message)
+
+	return c.Core.Write(entry, c.redactFields(fields))
+}
+
+func (c *redactCore) redactFields(fields []zapcore.Field) []zapcore.Field {
+	redacted := make([]zapcore.Field, len(fields))
+	for i, field := range fields {
+		if field.Type == zapcore.StringType {
+			field.String = c.redactor.Redact(field.String)
+		}
+		redacted[i] = field
+	}
+
+	return redacted
+}
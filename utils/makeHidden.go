@@ -1,13 +1,12 @@
 package utils
 
 import (
-	"os"
 	"os/exec"
 )
 
-func MakeHidden() {
-	hiddenDir := os.Getenv("BUILDER_HIDDEN_DIR")
-	if os.Getenv("HIDDEN_DIR_ENABLED") == "true" {
+func MakeHidden(bc *BuildContext) {
+	hiddenDir := bc.HiddenDir
+	if bc.Flags.Hidden {
 		//make hiddenDir hidden
 		exec.Command("attrib", hiddenDir, "-h").Run()
 		//make contents read-only
@@ -1,184 +1,191 @@
 package utils
 
 import (
-	"Builder/spinner"
+	"Builder/testresult"
+	"Builder/yaml"
 	"crypto/sha256"
+	"crypto/sha512"
 	"fmt"
+	"io"
+	"mime"
+	"path/filepath"
 	"runtime"
 
 	"encoding/json"
 	"io/ioutil"
-	"net"
 	"os"
 	"os/exec"
 	"os/user"
-	"path/filepath"
 	"strings"
+	"time"
 
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
-	"gopkg.in/yaml.v2"
 )
 
-func Metadata(path string) {
+// Metadata writes metadata.json and metadata.yaml for the build into path
+func Metadata(bc *BuildContext, path string) error {
 	//Metedata
-	projectName := GetName()
+	projectName := GetName(bc)
 
 	caser := cases.Title(language.English)
-	projectType := caser.String(os.Getenv("BUILDER_PROJECT_TYPE"))
+	projectType := caser.String(bc.Config.ProjectType)
 
-	artifactName := os.Getenv("BUILDER_ARTIFACT_NAMES")
-	artifactChecksums := GetArtifactChecksum()
-	builderPath, _ := os.Getwd()
-	artifactPath := os.Getenv("BUILDER_ARTIFACT_DIR")
-	var artifactLocation string
-	if os.Getenv("BUILDER_OUTPUT_PATH") != "" {
-		artifactLocation = os.Getenv("BUILDER_OUTPUT_PATH")
-	} else {
-		if os.Getenv("BUILDER_DIR_PATH") != "" {
-			if artifactPath[0:1] == "." {
-				artifactPath = artifactPath[1:]
-				artifactLocation = builderPath + artifactPath
-			} else {
-				artifactLocation = artifactPath
-			}
-		} else {
-			artifactPath = artifactPath[1:]
-			artifactLocation = builderPath + artifactPath
-		}
+	artifactName := bc.ArtifactNameList()
+	artifactChecksums, err := GetArtifactChecksums(bc)
+	if err != nil {
+		return err
 	}
-
-	logsPath := os.Getenv("BUILDER_LOGS_DIR")
-	var logsLocation string
-	if strings.HasPrefix(logsPath, "./") {
-		logsLocation = builderPath + "/" + logsPath[2:] + "/logs.json"
+	var artifactLocation string
+	if bc.Config.OutputPath != "" {
+		artifactLocation = bc.Config.OutputPath
 	} else {
-		logsLocation = logsPath + "/logs.json"
+		artifactLocation = bc.ArtifactDir
 	}
 
-	ip := GetIPAdress().String()
+	logsLocation := bc.LogsDir + "/logs.json"
 
-	userName := GetUserData().Username
+	host := GetHost()
 
-	// If on Windows, remove computer ID prefix from returned username
-	if runtime.GOOS == "windows" {
-		splitString := strings.Split(userName, "\\")
-		userName = splitString[1]
+	userData, err := GetUserData()
+	if err != nil {
+		return err
 	}
+	userName := UserName(userData)
 
-	homeDir := GetUserData().HomeDir
-	startTime := os.Getenv("BUILD_START_TIME")
-	endTime := os.Getenv("BUILD_END_TIME")
-
-	var gitURL = GetRepoURL()
-	_, masterGitHash := GitMasterNameAndHash()
-
-	var branchName string
-	if os.Getenv("BUILDER_COMMAND") == "true" {
-		if os.Getenv("REPO_BRANCH_NAME") != "" {
-			branchName = os.Getenv("REPO_BRANCH_NAME")
-		} else {
-			out, err := exec.Command("git", "symbolic-ref", "--short", "HEAD").Output()
-			if err != nil {
-				spinner.LogMessage("Can't get current branch name.  Please provide it in the builder.yaml.", "info")
-				branchName = ""
-			} else {
-				// remove \n at end of returned branch name before returning
-				branchName = strings.TrimSuffix(string(out), "\n")
-			}
-		}
-	} else {
-		branchName = os.Getenv("REPO_BRANCH_NAME")
-	}
+	homeDir := userData.HomeDir
+	endTime := bc.EndTime.Format(time.RFC850)
+
+	var gitURL = GetRepoURL(bc)
+	_, masterGitHash := GitMasterNameAndHash(bc)
+	//the same values are stamped into the artifacts
+	info := bc.BuildInfo()
 
 	//Contains a collection of files with user's metadata
 	userMetaData := AllMetaData{
+		SchemaVersion:     MetadataSchemaVersion,
+		BuildID:           info.BuildID,
 		ProjectName:       projectName,
 		ProjectType:       projectType,
+		Profile:           bc.Config.Profile,
 		ArtifactName:      artifactName,
 		ArtifactChecksums: artifactChecksums,
 		ArtifactLocation:  artifactLocation,
 		LogsLocation:      logsLocation,
 		UserName:          userName,
 		HomeDir:           homeDir,
-		IP:                ip,
-		StartTime:         startTime,
+		IP:                host.PrimaryIP(),
+		Host:              host,
+		Toolchain:         bc.Toolchain,
+		Steps:             bc.Steps,
+		Tests:             bc.Tests,
+		StartTime:         info.Timestamp,
 		EndTime:           endTime,
 		GitURL:            gitURL,
 		MasterGitHash:     masterGitHash,
-		BranchName:        branchName}
+		GitHash:           info.GitHash,
+		BranchName:        info.Branch,
+		Stamped:           bc.Config.Stamp}
 
-	OutputMetadata(path, &userMetaData)
+	if bc.Hermetic() {
+		userMetaData.Hermetic = true
+		userMetaData.Environment = bc.EffectiveEnv()
+	}
+
+	//the secrets can end up in step commands and test output
+	bc.RedactValue(&userMetaData)
+
+	return OutputMetadata(path, &userMetaData)
 }
 
+// MetadataSchemaVersion is the version of the metadata.json/metadata.yaml layout.  It is
+// bumped whenever a field is removed or changes type.
+//
+//	1: ArtifactChecksums was a string
+//	2: ArtifactChecksums is a list of ArtifactChecksum
+const MetadataSchemaVersion = 2
+
 // AllMetaData holds the stuct of all the arguments
 type AllMetaData struct {
+	SchemaVersion     int
+	BuildID           string
 	ProjectName       string
 	ProjectType       string
+	Profile           string `json:",omitempty" yaml:",omitempty"`
 	ArtifactName      string
-	ArtifactChecksums string
+	ArtifactChecksums []ArtifactChecksum
 	ArtifactLocation  string
 	LogsLocation      string
 	UserName          string
 	HomeDir           string
 	IP                string
+	Host              Host
+	Toolchain         []Tool
+	Hermetic          bool                `json:",omitempty" yaml:",omitempty"`
+	Stamped           bool                `json:",omitempty" yaml:",omitempty"`
+	Environment       []string            `json:",omitempty" yaml:",omitempty"`
+	Steps             []StepResult        `json:",omitempty" yaml:",omitempty"`
+	Tests             *testresult.Summary `json:",omitempty" yaml:",omitempty"`
 	StartTime         string
 	EndTime           string
 	GitURL            string
 	MasterGitHash     string
+	GitHash           string
 	BranchName        string
 }
 
 // GetUserData return username and userdir
-func GetUserData() *user.User {
+func GetUserData() (*user.User, error) {
 	user, err := user.Current()
 	if err != nil {
-		panic(err)
-
+		return nil, fmt.Errorf("could not get current user: %w", err)
 	}
 
-	return user
-
+	return user, nil
 }
 
-// GetIPAdress Get preferred outbound ip of this machine
-func GetIPAdress() net.IP {
-	conn, err := net.Dial("udp", "8.8.8.8:80")
-	if err != nil {
-		spinner.LogMessage("could not connect to outbound ip: "+err.Error(), "fatal")
+// UserName returns the name of user without the computer or domain prefix Windows adds
+func UserName(user *user.User) string {
+	userName := user.Username
+	if runtime.GOOS == "windows" {
+		userName = userName[strings.LastIndex(userName, "\\")+1:]
 	}
-	defer conn.Close()
-
-	localAddr := conn.LocalAddr().(*net.UDPAddr)
 
-	return localAddr.IP
+	return userName
 }
 
 // OutputJSONall  outputs allMetaData struct in JSON format
-func OutputMetadata(path string, allData *AllMetaData) {
-	yamlData, _ := yaml.Marshal(allData)
-	jsonData, _ := json.Marshal(allData)
+func OutputMetadata(path string, allData *AllMetaData) error {
+	yamlData, err := yaml.Marshal(allData)
+	if err != nil {
+		return fmt.Errorf("YAML metadata creation unsuccessful: %w", err)
+	}
+	jsonData, err := json.Marshal(allData)
+	if err != nil {
+		return fmt.Errorf("JSON metadata creation unsuccessful: %w", err)
+	}
 
-	err := ioutil.WriteFile(path+"/metadata.json", jsonData, 0666)
+	err = ioutil.WriteFile(path+"/metadata.json", jsonData, 0666)
 	err2 := ioutil.WriteFile(path+"/metadata.yaml", yamlData, 0666)
 
 	if err != nil {
-		spinner.LogMessage("JSON Metadata creation unsuccessful.", "fatal")
+		return fmt.Errorf("JSON metadata creation unsuccessful: %w", err)
 	}
 
 	if err2 != nil {
-		spinner.LogMessage("YAML Metadata creation unsuccessful.", "fatal")
+		return fmt.Errorf("YAML metadata creation unsuccessful: %w", err2)
 	}
+
+	return nil
 }
 
 // Gets the name of the repo's master branch and its hash
-func GitMasterNameAndHash() (string, string) {
-	hiddenDir := os.Getenv("BUILDER_HIDDEN_DIR")
-	dirToRunIn, _ := filepath.Abs(hiddenDir)
+func GitMasterNameAndHash(bc *BuildContext) (string, string) {
+	dirToRunIn := bc.HiddenDir
 
 	//outputs the name of the master branch
 	cmd := exec.Command("git", "symbolic-ref", "refs/remotes/origin/HEAD", "--short")
-	if os.Getenv("BUILDER_COMMAND") != "true" {
+	if !bc.IsBuilderCommand() {
 		cmd.Dir = dirToRunIn
 	}
 	output, err := cmd.Output()
@@ -191,7 +198,7 @@ func GitMasterNameAndHash() (string, string) {
 
 	//outputs the hash of the provided branch
 	cmd = exec.Command("git", "rev-parse", masterBranchName)
-	if os.Getenv("BUILDER_COMMAND") != "true" {
+	if !bc.IsBuilderCommand() {
 		cmd.Dir = dirToRunIn
 	}
 	hashOutput, hashErr := cmd.Output()
@@ -203,105 +210,130 @@ func GitMasterNameAndHash() (string, string) {
 	return masterBranchName, masterBranchHash
 }
 
-type Artifacts struct {
-	name     string
-	checksum string
+// ArtifactChecksum describes one file of the build's artifacts.  Path is relative to the
+// artifact dir, which is also its path inside the compressed artifact dir.
+type ArtifactChecksum struct {
+	Name      string `json:"name" yaml:"name"`
+	Path      string `json:"path" yaml:"path"`
+	Size      int64  `json:"size" yaml:"size"`
+	SHA256    string `json:"sha256" yaml:"sha256"`
+	SHA512    string `json:"sha512" yaml:"sha512"`
+	MediaType string `json:"mediaType" yaml:"mediaType"`
+	// ArtifactInfo is set on the artifacts the compiler knows more about, and on the files in them
+	ArtifactInfo `yaml:",inline"`
 }
 
-func GetArtifactChecksum() string {
-	artifactDir := os.Getenv("BUILDER_ARTIFACT_DIR")
-
-	files, err := os.ReadDir(artifactDir)
-	if err != nil {
-		spinner.LogMessage(err.Error(), "fatal")
-	}
+// ArtifactInfo is what the compiler knows about one of the artifacts it collected
+type ArtifactInfo struct {
+	// Module is the groupId:artifactId:version of the maven module that built the artifact
+	Module string `json:"module,omitempty" yaml:"module,omitempty"`
+	// ImportPath is the import path of the go main package the binary was built from
+	ImportPath string `json:"importPath,omitempty" yaml:"importPath,omitempty"`
+	// Target is the builder.yaml target the artifact was cross-compiled for
+	Target string `json:"target,omitempty" yaml:"target,omitempty"`
+}
 
-	var checksumsArray []Artifacts
-	var checksum string
-	for _, file := range files {
-		if file.Name() != "metadata.json" && file.Name() != "metadata.yaml" {
-			// Get checksum of artifact
-			artifact, err := os.ReadFile(artifactDir + "/" + file.Name())
-			if err != nil {
-				spinner.LogMessage(err.Error(), "fatal")
-			}
+// media types of artifact extensions that aren't in every system's mime database
+var artifactMediaTypes = map[string]string{
+	".jar":   "application/java-archive",
+	".war":   "application/java-archive",
+	".ear":   "application/java-archive",
+	".zip":   "application/zip",
+	".gz":    "application/gzip",
+	".tgz":   "application/gzip",
+	".rpm":   "application/x-rpm",
+	".deb":   "application/vnd.debian.binary-package",
+	".exe":   "application/vnd.microsoft.portable-executable",
+	".dll":   "application/vnd.microsoft.portable-executable",
+	".lib":   "application/octet-stream",
+	".nupkg": "application/zip",
+	".gem":   "application/x-tar",
+	".whl":   "application/zip",
+	".crate": "application/gzip",
+	".tar":   "application/x-tar",
+	".so":    "application/octet-stream",
+	".a":     "application/octet-stream",
+	".wasm":  "application/wasm",
+}
 
-			sum := sha256.Sum256(artifact)
-			checksum = fmt.Sprintf("%x", sum)
+// GetArtifactChecksums returns the size, checksums and media type of every file in the artifact dir
+func GetArtifactChecksums(bc *BuildContext) ([]ArtifactChecksum, error) {
+	artifactDir := bc.ArtifactDir
 
-			var artifactObj Artifacts
-			artifactObj.name = file.Name()
-			artifactObj.checksum = checksum
+	checksums := []ArtifactChecksum{}
+	err := filepath.Walk(artifactDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || (filepath.Dir(path) == artifactDir && (info.Name() == "metadata.json" || info.Name() == "metadata.yaml")) {
+			return nil
+		}
 
-			checksumsArray = append(checksumsArray, artifactObj)
+		relPath, err := filepath.Rel(artifactDir, path)
+		if err != nil {
+			return err
 		}
-	}
-	checksums := fmt.Sprintf("%+v", checksumsArray)
 
-	return checksums
-}
+		checksum, err := FileChecksum(path)
+		if err != nil {
+			return err
+		}
+		checksum.Path = filepath.ToSlash(relPath)
+		checksum.ArtifactInfo = bc.ArtifactInfo[strings.SplitN(checksum.Path, "/", 2)[0]]
 
-func GetBuildID() string {
-	artifactDir := os.Getenv("BUILDER_ARTIFACT_DIR")
-	var checksum string
+		checksums = append(checksums, checksum)
 
-	// Get checksum of metadata.json
-	metadata, err := os.ReadFile(artifactDir + "/metadata.json")
+		return nil
+	})
 	if err != nil {
-		spinner.LogMessage(err.Error(), "fatal")
+		return nil, fmt.Errorf("could not get artifact checksums: %w", err)
 	}
 
-	sum := sha256.Sum256(metadata)
-	checksum = fmt.Sprintf("%x", sum)
-
-	// Only return first 10 char of sum
-	return checksum[0:9]
+	return checksums, nil
 }
 
-func StoreBuildMetadataLocally() {
-	// Read in build JSON data from build artifact directory
-	artifactDir := os.Getenv("BUILDER_ARTIFACT_DIR")
-
-	metadataJSON, err := os.ReadFile(artifactDir + "/metadata.json")
+// FileChecksum returns the size, checksums and media type of the file at path.  Path is set
+// to the name of the file.
+func FileChecksum(path string) (ArtifactChecksum, error) {
+	artifactFile, err := os.Open(path)
 	if err != nil {
-		spinner.LogMessage("Cannot find metadata.json file: "+err.Error(), "fatal")
+		return ArtifactChecksum{}, err
 	}
+	defer artifactFile.Close()
 
-	// Unmarshal json data so we can add buildID
-	var metadataFormat map[string]interface{}
-	json.Unmarshal(metadataJSON, &metadataFormat)
-	metadataFormat["BuildID"] = GetBuildID()
-
-	updatedMetadataJSON, err := json.Marshal(metadataFormat)
-
-	// Check if builds.json exists and append to it, if not, create it
-	textToAppend := string(updatedMetadataJSON) + ",\n"
-
-	var pathToBuildsJSON string
-
-	if runtime.GOOS == "windows" {
-		appDataDir := os.Getenv("LOCALAPPDATA")
-		if appDataDir == "" {
-			appDataDir = os.Getenv("APPDATA")
-		}
+	return ReaderChecksum(filepath.Base(path), artifactFile)
+}
 
-		pathToBuildsJSON = appDataDir + "/Builder/builds.json"
-	} else {
-		user, _ := user.Current()
-		homeDir := user.HomeDir
+// ReaderChecksum returns the size, checksums and media type of the artifact named name read
+// from r.  Path is set to name.
+func ReaderChecksum(name string, r io.Reader) (ArtifactChecksum, error) {
+	sha256Hash := sha256.New()
+	sha512Hash := sha512.New()
 
-		pathToBuildsJSON = homeDir + "/.builder/builds.json"
+	size, err := io.Copy(io.MultiWriter(sha256Hash, sha512Hash), r)
+	if err != nil {
+		return ArtifactChecksum{}, fmt.Errorf("could not read artifact %s: %w", name, err)
 	}
 
-	buildsFile, err := os.OpenFile(pathToBuildsJSON, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	return ArtifactChecksum{
+		Name:      filepath.Base(name),
+		Path:      name,
+		Size:      size,
+		SHA256:    fmt.Sprintf("%x", sha256Hash.Sum(nil)),
+		SHA512:    fmt.Sprintf("%x", sha512Hash.Sum(nil)),
+		MediaType: artifactMediaType(name),
+	}, nil
+}
 
-	if err != nil {
-		spinner.LogMessage("Could not create builds.json file: "+err.Error(), "fatal")
+func artifactMediaType(name string) string {
+	ext := strings.ToLower(filepath.Ext(name))
+	if mediaType, ok := artifactMediaTypes[ext]; ok {
+		return mediaType
 	}
-	if _, err := buildsFile.Write([]byte(textToAppend)); err != nil {
-		spinner.LogMessage("Could not write to builds.json file: "+err.Error(), "fatal")
-	}
-	if err := buildsFile.Close(); err != nil {
-		spinner.LogMessage("Could not close builds.json file: "+err.Error(), "fatal")
+
+	if mediaType := mime.TypeByExtension(ext); ext != "" && mediaType != "" {
+		return mediaType
 	}
+
+	return "application/octet-stream"
 }
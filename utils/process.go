@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrTimedOut is returned when a command is killed because its step or the build ran too long
+	ErrTimedOut = errors.New("timed out")
+	// ErrCancelled is returned when a command is killed because the build was cancelled
+	ErrCancelled = errors.New("build cancelled")
+)
+
+// StartProcess starts cmd in its own process group and kills the whole group (cmd and every
+// process it started) if ctx is done before cmd exits.  The returned func waits for cmd to
+// exit; if it was killed the error wraps ErrTimedOut or ErrCancelled.
+func StartProcess(ctx context.Context, cmd *exec.Cmd) (func() error, error) {
+	setProcessGroup(cmd)
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	exited := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			killProcessGroup(cmd)
+		case <-exited:
+		}
+	}()
+
+	wait := func() error {
+		err := cmd.Wait()
+		close(exited)
+
+		switch ctx.Err() {
+		case context.DeadlineExceeded:
+			return fmt.Errorf("%w (%v)", ErrTimedOut, err)
+		case context.Canceled:
+			return fmt.Errorf("%w (%v)", ErrCancelled, err)
+		}
+		return err
+	}
+
+	return wait, nil
+}
+
+// ParseTimeout parses a builder.yaml or --timeout timeout, given as a duration ("90s", "5m")
+// or a number of seconds
+func ParseTimeout(timeout string) (time.Duration, error) {
+	if timeout == "" {
+		return 0, nil
+	}
+
+	if seconds, err := strconv.Atoi(timeout); err == nil {
+		if seconds < 0 {
+			return 0, fmt.Errorf("invalid timeout %q, it can't be negative", timeout)
+		}
+		return time.Duration(seconds) * time.Second, nil
+	}
+
+	d, err := time.ParseDuration(timeout)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid timeout %q, use a duration like 90s or 5m", timeout)
+	}
+
+	return d, nil
+}
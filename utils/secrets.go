@@ -0,0 +1,143 @@
+package utils
+
+import (
+	"Builder/secrets"
+	"Builder/spinner"
+	"Builder/utils/log"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// LoadSecrets reads the values of the builder.yaml secrets from Builder's environment, their
+// files or the encrypted secrets file.  From then on the values are added to the build
+// commands' environment and redacted from the build logs, metadata and build history.
+func LoadSecrets(bc *BuildContext) error {
+	var store *secrets.Store
+
+	names := make([]string, 0, len(bc.Config.Secrets))
+	for name := range bc.Config.Secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	bc.secrets = map[string]string{}
+	for _, name := range names {
+		secret := bc.Config.Secrets[name]
+
+		var value string
+		switch {
+		case secret.Env != "":
+			var ok bool
+			if value, ok = os.LookupEnv(secret.Env); !ok {
+				return fmt.Errorf("secret %s: environment variable %s is not set", name, secret.Env)
+			}
+		case secret.File != "":
+			contents, err := os.ReadFile(secret.File)
+			if err != nil {
+				return fmt.Errorf("secret %s: %w", name, err)
+			}
+			value = strings.TrimRight(string(contents), "\r\n")
+		case secret.Store != "":
+			if store == nil {
+				var err error
+				if store, err = secrets.Open(); err != nil {
+					return fmt.Errorf("secret %s: %w", name, err)
+				}
+			}
+			var ok bool
+			if value, ok = store.Get(secret.Store); !ok {
+				return fmt.Errorf("secret %s: %s is not in %s, add it with builder secret set %s", name, secret.Store, store.Path(), secret.Store)
+			}
+		default:
+			return fmt.Errorf("secret %s needs one of env, file or store", name)
+		}
+
+		if len(value) < log.MinRedactLength {
+			spinner.LogMessage(fmt.Sprintf("secret %s is shorter than %d characters, it isn't redacted from the logs", name, log.MinRedactLength), "warn")
+		}
+		bc.secrets[name] = value
+		bc.redactor.Add(value)
+	}
+
+	return nil
+}
+
+// BuildEnv returns the builder.yaml env and secrets as sorted NAME=value pairs, to be added
+// to the environment of the build commands.  A secret wins over an env entry of the same name.
+func (bc *BuildContext) BuildEnv() []string {
+	values := map[string]string{}
+	for name, value := range bc.Config.Env {
+		values[name] = value
+	}
+	for name, value := range bc.secrets {
+		values[name] = value
+	}
+
+	var env []string
+	for name, value := range values {
+		env = append(env, name+"="+value)
+	}
+	sort.Strings(env)
+
+	return env
+}
+
+// Redact returns s with the values of the build's secrets replaced with ***
+func (bc *BuildContext) Redact(s string) string {
+	return bc.redactor.Redact(s)
+}
+
+// RedactValue replaces the values of the build's secrets in every string reachable from
+// ptr, a pointer to a struct, slice or map, with ***
+func (bc *BuildContext) RedactValue(ptr interface{}) {
+	redactValue(bc.redactor, reflect.ValueOf(ptr))
+}
+
+func redactValue(redactor *log.Redactor, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.String:
+		if v.CanSet() {
+			v.SetString(redactor.Redact(v.String()))
+		}
+	case reflect.Ptr:
+		if !v.IsNil() {
+			redactValue(redactor, v.Elem())
+		}
+	case reflect.Interface:
+		// the value in an interface can't be changed, it's replaced with a redacted copy
+		if !v.IsNil() && v.CanSet() {
+			value := reflect.New(v.Elem().Type()).Elem()
+			value.Set(v.Elem())
+			redactValue(redactor, value)
+			v.Set(value)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			redactValue(redactor, v.Field(i))
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			redactValue(redactor, v.Index(i))
+		}
+	case reflect.Map:
+		if !v.CanSet() {
+			return
+		}
+		for _, key := range v.MapKeys() {
+			value := reflect.New(v.Type().Elem()).Elem()
+			value.Set(v.MapIndex(key))
+			redactValue(redactor, value)
+			v.SetMapIndex(key, value)
+		}
+	}
+}
+
+// RedactLogger returns logger with the values of the build's secrets redacted from it
+func (bc *BuildContext) RedactLogger(logger *zap.Logger) *zap.Logger {
+	return bc.redactor.Wrap(logger)
+}
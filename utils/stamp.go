@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"Builder/spinner"
+	"encoding/json"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// BuildInfo is the build metadata stamp: true puts into the artifacts.  The values are the
+// ones Metadata writes as GitHash, BranchName, BuildID and StartTime.
+type BuildInfo struct {
+	GitHash   string `json:"gitHash"`
+	Branch    string `json:"branch"`
+	BuildID   string `json:"buildId"`
+	Timestamp string `json:"timestamp"`
+}
+
+// BuildInfo returns the build's git hash, branch, build ID and timestamp, read once so the
+// stamped artifacts and the metadata get the same values
+func (bc *BuildContext) BuildInfo() BuildInfo {
+	if bc.buildInfo != nil {
+		return *bc.buildInfo
+	}
+
+	branchName := bc.BranchName
+	if branchName == "" && bc.IsBuilderCommand() {
+		out, err := exec.Command("git", "symbolic-ref", "--short", "HEAD").Output()
+		if err != nil {
+			spinner.LogMessage("Can't get current branch name.  Please provide it in the builder.yaml.", "info")
+		} else {
+			// remove \n at end of returned branch name before returning
+			branchName = strings.TrimSuffix(string(out), "\n")
+		}
+	}
+
+	bc.buildInfo = &BuildInfo{
+		GitHash:   HeadGitHash(bc),
+		Branch:    branchName,
+		BuildID:   bc.BuildID,
+		Timestamp: bc.StartTime.Format(time.RFC850),
+	}
+
+	return *bc.buildInfo
+}
+
+// HeadGitHash returns the hash of the commit that's built, the HEAD of the hidden dir (the
+// current dir for the builder command), or "undefined" if it can't be read
+func HeadGitHash(bc *BuildContext) string {
+	cmd := exec.Command("git", "rev-parse", "HEAD")
+	if !bc.IsBuilderCommand() {
+		cmd.Dir = bc.HiddenDir
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return "undefined"
+	}
+
+	return strings.TrimSpace(string(out))
+}
+
+// StampEnv returns the build info as the BUILDER_GIT_HASH, BUILDER_BRANCH, BUILDER_BUILD_ID
+// and BUILDER_TIMESTAMP variables the build commands get with stamp: true, nil without it
+func (bc *BuildContext) StampEnv() []string {
+	if !bc.Config.Stamp {
+		return nil
+	}
+
+	info := bc.BuildInfo()
+	return []string{
+		"BUILDER_GIT_HASH=" + info.GitHash,
+		"BUILDER_BRANCH=" + info.Branch,
+		"BUILDER_BUILD_ID=" + info.BuildID,
+		"BUILDER_TIMESTAMP=" + info.Timestamp,
+	}
+}
+
+// Values returns the build info by the names stampvars uses (githash, branch, buildid, timestamp)
+func (info BuildInfo) Values() map[string]string {
+	return map[string]string{
+		"githash":   info.GitHash,
+		"branch":    info.Branch,
+		"buildid":   info.BuildID,
+		"timestamp": info.Timestamp,
+	}
+}
+
+// Properties returns the build info as a java .properties file
+func (info BuildInfo) Properties() []byte {
+	escape := strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\r", `\r`)
+
+	var out strings.Builder
+	out.WriteString("# written by Builder\n")
+	for _, entry := range [][2]string{
+		{"gitHash", info.GitHash},
+		{"branch", info.Branch},
+		{"buildId", info.BuildID},
+		{"timestamp", info.Timestamp},
+	} {
+		out.WriteString(entry[0] + "=" + escape.Replace(entry[1]) + "\n")
+	}
+
+	return []byte(out.String())
+}
+
+// JSON returns the build info as indented JSON
+func (info BuildInfo) JSON() ([]byte, error) {
+	out, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(out, '\n'), nil
+}
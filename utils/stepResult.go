@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"time"
+)
+
+// Step statuses recorded in the metadata
+const (
+	StepSucceeded = "succeeded"
+	StepFailed    = "failed"
+)
+
+// StepResult is the outcome of one builder.yaml step
+type StepResult struct {
+	Name     string `json:"name" yaml:"name"`
+	Command  string `json:"command" yaml:"command"`
+	Status   string `json:"status" yaml:"status"`
+	ExitCode int    `json:"exitCode" yaml:"exitCode"`
+	Error    string `json:"error,omitempty" yaml:"error,omitempty"`
+	// Log is the step's log file name inside the logs dir
+	Log             string  `json:"log" yaml:"log"`
+	StartTime       string  `json:"startTime" yaml:"startTime"`
+	EndTime         string  `json:"endTime" yaml:"endTime"`
+	DurationSeconds float64 `json:"durationSeconds" yaml:"durationSeconds"`
+}
+
+// NewStepResult returns the result of a step that ran from start to end and returned err
+func NewStepResult(name, command, log string, start, end time.Time, err error) StepResult {
+	result := StepResult{
+		Name:            name,
+		Command:         command,
+		Status:          StepSucceeded,
+		Log:             log,
+		StartTime:       start.Format(time.RFC3339),
+		EndTime:         end.Format(time.RFC3339),
+		DurationSeconds: end.Sub(start).Round(time.Millisecond).Seconds(),
+	}
+
+	if err != nil {
+		result.Status = StepFailed
+		result.ExitCode = ExitCode(err)
+		result.Error = err.Error()
+	}
+
+	return result
+}
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// toolVersionTimeout limits how long a version command can run, a gradle wrapper downloads
+// gradle the first time it's run
+const toolVersionTimeout = 2 * time.Minute
+
+// Tool is one toolchain executable used by a build
+type Tool struct {
+	Name    string `json:"name" yaml:"name"`
+	Version string `json:"version" yaml:"version"`
+	Path    string `json:"path" yaml:"path"`
+}
+
+// GetTool runs a version command (e.g. "go version", "/path/to/gradlew --version") and returns
+// the first line of its output along with the resolved path of the executable.  Version and
+// Path are left empty if the tool can't be found or run.  The command is killed if the build
+// is cancelled or runs out of time, or after toolVersionTimeout.  It's found in the PATH of
+// the build commands and run with their environment in the build dir, so the version
+// recorded is the one the build uses, also for tools that pick a version per dir.
+func GetTool(bc *BuildContext, versionCmd []string) Tool {
+	tool := Tool{Name: strings.TrimSuffix(filepath.Base(versionCmd[0]), ".bat")}
+
+	env := bc.CommandEnv(nil)
+	path, err := LookPath(versionCmd[0], bc.BuildDir, env)
+	if err != nil {
+		return tool
+	}
+	tool.Path = path
+
+	ctx, cancel := context.WithTimeout(bc.Context(), toolVersionTimeout)
+	defer cancel()
+
+	var out bytes.Buffer
+	cmd := exec.Command(path, versionCmd[1:]...)
+	cmd.Dir = bc.BuildDir
+	cmd.Env = env
+	cmd.Stdout, cmd.Stderr = &out, &out
+	wait, err := StartProcess(ctx, cmd)
+	if err == nil {
+		err = wait()
+	}
+	if err != nil {
+		return tool
+	}
+
+	// gradle starts with a line of dashes
+	for _, line := range strings.Split(out.String(), "\n") {
+		if line = strings.TrimSpace(line); strings.Trim(line, "-") != "" {
+			tool.Version = line
+			break
+		}
+	}
+
+	return tool
+}
@@ -0,0 +1,56 @@
+package yaml
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Commands is a command field of the builder.yaml (buildcmd, prebuildcmd, configcmd,
+// dockercmd).  It is written either as a single command or as a list of commands that are
+// run in order:
+//
+//	buildcmd: go build -ldflags "-X main.version=1.0"
+//	buildcmd: [go vet ./..., go build ./...]
+type Commands []string
+
+// MarshalYAML writes a single command as a plain string so generated builder.yamls keep
+// their old format
+func (c Commands) MarshalYAML() (interface{}, error) {
+	switch len(c) {
+	case 0:
+		return "", nil
+	case 1:
+		return c[0], nil
+	default:
+		return []string(c), nil
+	}
+}
+
+// String returns the commands joined the way a shell would run them in sequence
+func (c Commands) String() string {
+	return strings.Join(c, " && ")
+}
+
+// UnmarshalYAML reads a single command or a list of commands, empty commands are dropped
+func (c *Commands) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if node.Value != "" {
+			*c = Commands{node.Value}
+		}
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				return typeError(item, "expected a command")
+			}
+			if item.ShortTag() != nullTag && item.Value != "" {
+				*c = append(*c, item.Value)
+			}
+		}
+	default:
+		return typeError(node, "expected a command or a list of commands")
+	}
+
+	return nil
+}
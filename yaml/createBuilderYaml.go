@@ -2,74 +2,109 @@ package yaml
 
 import (
 	"Builder/spinner"
+	"bytes"
+	"fmt"
 	"os"
 
-	"gopkg.in/yaml.v2"
+	"gopkg.in/yaml.v3"
 )
 
+// BuilderYaml holds the values of a builder.yaml
 type BuilderYaml struct {
-	ProjectName   string
-	ProjectPath   string
-	ProjectType   string
-	BuildTool     string
-	BuildFile     string
-	PreBuildCmd   string
-	ConfigCmd     string
-	BuildCmd      string
-	ArtifactList  string
-	OutputPath    string
-	GlobalLogs    string
-	DockerCmd     string
-	RepoBranch    string
+	ProjectName string
+	ProjectPath string
+	ProjectType string
+	BuildsDir   string
+	BuildTool   string
+	BuildFile   string
+	// GradleTask is the gradle task(s) the gradle compiler runs, "build" by default
+	GradleTask string `yaml:",omitempty"`
+	// GoPackages are the main packages the go compiler builds in a module, comma separated,
+	// every package main in the module by default
+	GoPackages string `yaml:",omitempty"`
+	// Targets are the platforms the go (GOOS/GOARCH) and rust (target triple) compilers
+	// cross-compile for, one artifact each
+	Targets     []string `yaml:",omitempty"`
+	PreBuildCmd Commands
+	ConfigCmd   Commands
+	BuildCmd    Commands
+	// Steps are run in order in place of buildcmd
+	Steps []Step `yaml:",omitempty"`
+	// Test runs the test stage with the default test command
+	Test         bool     `yaml:",omitempty"`
+	TestCmd      Commands `yaml:",omitempty"`
+	TestResults  string   `yaml:",omitempty"`
+	ArtifactList string
+	OutputPath   string
+	GlobalLogs   string
+	DockerCmd    Commands
+	RepoBranch   string
+	// Timeout is how long the whole build may run, a duration ("30m") or a number of seconds
+	Timeout       string `yaml:",omitempty"`
+	GitURL        string
 	BypassPrompts string
+	// Shell runs the builder.yaml commands through the system shell (/bin/sh -c) instead of
+	// splitting them into words, so pipes, && and redirects can be used
+	Shell bool
+	// Env holds environment variables added for the build commands
+	Env map[string]string `yaml:",omitempty"`
+	// Secrets are environment variables added for the build commands whose values are read at
+	// build time and redacted from the logs and metadata
+	Secrets map[string]Secret `yaml:",omitempty"`
+	// Hermetic gives the build commands only an allowlisted part of Builder's environment
+	// (PATH, HOME, locale, toolchain variables) plus env and secrets
+	Hermetic bool `yaml:",omitempty"`
+	// Stamp puts the build's git hash, branch, build ID and timestamp into the artifacts, see
+	// StampVars for go
+	Stamp bool `yaml:",omitempty"`
+	// StampVars are the go variables (package path.name) the build info is set in with
+	// -ldflags -X, by githash, branch, buildid and timestamp
+	StampVars map[string]string `yaml:",omitempty"`
+	// Profile is the profile the build uses, selected by --profile or the builder.yaml
+	Profile string `yaml:",omitempty"`
+	// Profiles are named sets of values that overlay the rest of the builder.yaml
+	Profiles map[string]BuilderYaml `yaml:",omitempty"`
 }
 
-func CreateBuilderYaml(fullPath string) {
-
-	projectName := os.Getenv("BUILDER_DIR_NAME")
-	projectPath := os.Getenv("BUILDER_DIR_PATH")
-	projectType := os.Getenv("BUILDER_PROJECT_TYPE")
-	buildTool := os.Getenv("BUILDER_BUILD_TOOL")
-	buildFile := os.Getenv("BUILDER_BUILD_FILE")
-	preBuildCmd := os.Getenv("BUILDER_PREBUILD_COMMAND")
-	configCmd := os.Getenv("BUILDER_CONFIG_COMMAND")
-	buildCmd := os.Getenv("BUILDER_BUILD_COMMAND")
-	artifactList := os.Getenv("BUILDER_ARTIFACT_LIST")
-	outputPath := os.Getenv("BUILDER_OUTPUT_PATH")
-	globalLogs := os.Getenv("GLOBAL_LOGS_PATH")
-	dockerCmd := os.Getenv("BUILDER_DOCKER_CMD")
-	repoBranch := os.Getenv("REPO_BRANCH")
-	bypassPrompts := os.Getenv("BYPASS_PROMPTS")
-
-	builderData := BuilderYaml{
-		ProjectName:   projectName,
-		ProjectPath:   projectPath,
-		ProjectType:   projectType,
-		BuildTool:     buildTool,
-		BuildFile:     buildFile,
-		PreBuildCmd:   preBuildCmd,
-		ConfigCmd:     configCmd,
-		BuildCmd:      buildCmd,
-		ArtifactList:  artifactList,
-		OutputPath:    outputPath,
-		GlobalLogs:    globalLogs,
-		DockerCmd:     dockerCmd,
-		RepoBranch:    repoBranch,
-		BypassPrompts: bypassPrompts,
-	}
-
+// CreateBuilderYaml writes cfg to fullPath/builder.yaml if one doesn't exist yet
+func CreateBuilderYaml(fullPath string, cfg *BuilderYaml) error {
 	_, err := os.Stat(fullPath + "/builder.yaml")
 	if err != nil {
-		OutputData(fullPath, &builderData)
+		if err := OutputData(fullPath, cfg); err != nil {
+			return err
+		}
 		spinner.LogMessage("builder.yaml created ✅", "info")
 	}
+
+	return nil
 }
 
-func OutputData(fullPath string, allData *BuilderYaml) {
-	yamlData, _ := yaml.Marshal(allData)
-	err := os.WriteFile(fullPath+"/builder.yaml", yamlData, 0644)
+func OutputData(fullPath string, allData *BuilderYaml) error {
+	yamlData, err := Marshal(allData)
+	if err != nil {
+		return fmt.Errorf("builder.yaml creation failed: %w", err)
+	}
 
+	err = os.WriteFile(fullPath+"/builder.yaml", yamlData, 0644)
 	if err != nil {
-		spinner.LogMessage("builder.yaml creation failed ⛔️", "fatal")
+		return fmt.Errorf("builder.yaml creation failed: %w", err)
 	}
+
+	return nil
+}
+
+// Marshal encodes v as yaml with the library the builder.yaml is read with, indented by two
+// spaces like the builder.yamls and metadata.yamls written so far
+func Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
 }
@@ -0,0 +1,278 @@
+package yaml
+
+import (
+	"Builder/spinner"
+	"Builder/utils/dirs"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// names of the global config files
+const (
+	UserConfig   = "user"
+	SystemConfig = "system"
+)
+
+// ErrUnknownKey is returned for a key that isn't a builder.yaml key
+var ErrUnknownKey = errors.New("unknown key")
+
+// globalConfig is one of the global config files
+type globalConfig struct {
+	name string
+	path string
+	// file is nil if the file doesn't exist
+	file *configFile
+}
+
+// ConfigSetting is the value of one key of the global config, and the file it comes from
+type ConfigSetting struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	// Source is user, system or default
+	Source string `json:"source"`
+	Path   string `json:"path,omitempty"`
+}
+
+// ConfigPath returns the path of the user (~/.builder/config.yaml) or system
+// (/etc/builder/config.yaml) config file
+func ConfigPath(name string) (string, error) {
+	if name == SystemConfig {
+		if runtime.GOOS == "windows" {
+			return filepath.Join(os.Getenv("ProgramData"), "Builder", "config.yaml"), nil
+		}
+		return "/etc/builder/config.yaml", nil
+	}
+
+	dir, err := dirs.BuilderDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "config.yaml"), nil
+}
+
+// globalConfigs reads the global config files, from highest to lowest precedence
+func globalConfigs() ([]globalConfig, error) {
+	var configs []globalConfig
+	for _, name := range []string{UserConfig, SystemConfig} {
+		path, err := ConfigPath(name)
+		if err != nil {
+			return nil, err
+		}
+
+		source, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			configs = append(configs, globalConfig{name: name, path: path})
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		file, err := readConfig(path, source)
+		if err != nil {
+			return nil, err
+		}
+
+		// profiles belong to a repo
+		if file.cfg.Profile != "" || len(file.cfg.Profiles) > 0 {
+			spinner.LogMessage(path+": profile and profiles are only read from a builder.yaml", "warn")
+			file.cfg.Profile = ""
+			file.cfg.Profiles = nil
+		}
+
+		configs = append(configs, globalConfig{name: name, path: path, file: file})
+	}
+
+	return configs, nil
+}
+
+// GlobalSettings returns every key set in the global config files or the defaults, with the
+// value that is used and where it comes from
+func GlobalSettings() ([]ConfigSetting, error) {
+	configs, err := globalConfigs()
+	if err != nil {
+		return nil, err
+	}
+
+	var settings []ConfigSetting
+	for _, key := range configKeys() {
+		if setting, ok := globalSetting(configs, key); ok {
+			settings = append(settings, setting)
+		}
+	}
+
+	return settings, nil
+}
+
+// GlobalSetting returns the value of key used from the global config files or the defaults,
+// ok is false if it isn't set
+func GlobalSetting(key string) (setting ConfigSetting, ok bool, err error) {
+	key = strings.ToLower(key)
+	if !isConfigKey(key) {
+		return setting, false, fmt.Errorf("%w %s", ErrUnknownKey, key)
+	}
+
+	configs, err := globalConfigs()
+	if err != nil {
+		return setting, false, err
+	}
+
+	setting, ok = globalSetting(configs, key)
+
+	return setting, ok, nil
+}
+
+func globalSetting(configs []globalConfig, key string) (ConfigSetting, bool) {
+	for _, config := range configs {
+		if config.file == nil {
+			continue
+		}
+		if _, set := config.file.lines[key]; set {
+			return ConfigSetting{Key: key, Value: formatValue(configValue(config.file.cfg, key)), Source: config.name, Path: config.path}, true
+		}
+	}
+
+	if value := configValue(Defaults, key); !value.IsZero() {
+		return ConfigSetting{Key: key, Value: formatValue(value), Source: "default"}, true
+	}
+
+	return ConfigSetting{}, false
+}
+
+// SetGlobalSetting sets key to value in the user or system config file, creating it if needed.
+// value is read as YAML, so lists ("[go vet ./..., go build]") and true/false can be given.
+// The file is checked with checks before it's written.  An empty value removes the key.
+func SetGlobalSetting(name, key, value string, checks Checks) (string, error) {
+	key = strings.ToLower(key)
+	if !isConfigKey(key) {
+		return "", fmt.Errorf("%w %s", ErrUnknownKey, key)
+	}
+	if key == "profile" || key == "profiles" {
+		return "", fmt.Errorf("%s is only read from a builder.yaml", key)
+	}
+
+	path, err := ConfigPath(name)
+	if err != nil {
+		return "", err
+	}
+
+	var doc yaml.Node
+	source, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err := yaml.Unmarshal(source, &doc); err != nil {
+		return "", fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].ShortTag() == nullTag {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return "", fmt.Errorf("%s must be a list of keys and values", path)
+	}
+
+	// drop the key, in whatever case it was written, then add the new value
+	var kept []*yaml.Node
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if strings.ToLower(root.Content[i].Value) != key {
+			kept = append(kept, root.Content[i], root.Content[i+1])
+		}
+	}
+	root.Content = kept
+
+	if value != "" {
+		valueNode := &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+		var parsed yaml.Node
+		if err := yaml.Unmarshal([]byte(value), &parsed); err == nil && len(parsed.Content) > 0 {
+			valueNode = parsed.Content[0]
+		}
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, valueNode)
+	}
+
+	out, err := yaml.Marshal(&doc)
+	if err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	var invalid []string
+	for _, problem := range Validate(out, checks) {
+		if problem.Key == key || strings.HasPrefix(problem.Key, key+"[") || problem.Kind == ProblemSyntax {
+			invalid = append(invalid, problem.Message)
+		}
+	}
+	if len(invalid) > 0 {
+		return "", fmt.Errorf("%s: %s", key, strings.Join(invalid, "; "))
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("could not create %s: %w", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, out, 0644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	return path, nil
+}
+
+// configKeys returns the builder.yaml keys in order
+func configKeys() []string {
+	var keys []string
+	for key := range yamlFields(reflect.TypeOf(BuilderYaml{})) {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func isConfigKey(key string) bool {
+	_, ok := yamlFields(reflect.TypeOf(BuilderYaml{}))[key]
+	return ok
+}
+
+// configValue returns the field of cfg for key
+func configValue(cfg BuilderYaml, key string) reflect.Value {
+	value := reflect.ValueOf(cfg)
+	for i := 0; i < value.NumField(); i++ {
+		if fieldKey(value.Type().Field(i)) == key {
+			return value.Field(i)
+		}
+	}
+
+	return reflect.Value{}
+}
+
+// formatValue writes a config value the way it's written in a config file
+func formatValue(value reflect.Value) string {
+	switch v := value.Interface().(type) {
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case Commands:
+		if len(v) == 1 {
+			return v[0]
+		}
+		return "[" + strings.Join(v, ", ") + "]"
+	case []string:
+		return "[" + strings.Join(v, ", ") + "]"
+	}
+
+	out, err := yaml.Marshal(value.Interface())
+	if err != nil {
+		return fmt.Sprintf("%v", value.Interface())
+	}
+
+	return strings.TrimSpace(string(out))
+}
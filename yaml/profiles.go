@@ -0,0 +1,60 @@
+package yaml
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// applyProfile overlays the keys set in cfg's profile (cfg.Profile) onto cfg, lines are the
+// keys set in the builder.yaml.  The profiles are dropped from cfg afterwards, so the config
+// only holds the values in use.
+func applyProfile(cfg *BuilderYaml, lines map[string]int) error {
+	profiles := cfg.Profiles
+	cfg.Profiles = nil
+
+	if cfg.Profile == "" {
+		return nil
+	}
+
+	profile, ok := profiles[cfg.Profile]
+	if !ok {
+		return fmt.Errorf("profile %s is not in the builder.yaml, the profiles are: %s", cfg.Profile, strings.Join(profileNames(profiles), ", "))
+	}
+
+	overlay(cfg, profile, lines, "profiles."+cfg.Profile+".")
+
+	return nil
+}
+
+// overlay copies the values of src onto cfg.  Empty values are skipped, so a builder.yaml with
+// every key written out keeps the values of the layers under it, except for true/false keys
+// which are copied when their key (prefix + key) is in lines.
+func overlay(cfg *BuilderYaml, src BuilderYaml, lines map[string]int, prefix string) {
+	dst := reflect.ValueOf(cfg).Elem()
+	srcValue := reflect.ValueOf(src)
+	for i := 0; i < dst.NumField(); i++ {
+		field := dst.Type().Field(i)
+		_, set := lines[prefix+fieldKey(field)]
+
+		if !srcValue.Field(i).IsZero() || (set && field.Type.Kind() == reflect.Bool) {
+			dst.Field(i).Set(srcValue.Field(i))
+		}
+	}
+}
+
+// profileNames returns the names of profiles in order
+func profileNames(profiles map[string]BuilderYaml) []string {
+	var names []string
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if len(names) == 0 {
+		return []string{"none"}
+	}
+
+	return names
+}
@@ -0,0 +1,33 @@
+package yaml
+
+import "sort"
+
+// Secret is where the value of one of the builder.yaml secrets is read from at build time.
+// Exactly one of Env, File and Store is set:
+//
+//	secrets:
+//	  NPM_TOKEN:
+//	    env: CI_NPM_TOKEN
+//	  DB_PASSWORD:
+//	    file: /run/secrets/db_password
+//	  SIGNING_KEY:
+//	    store: signing-key
+type Secret struct {
+	// Env is the environment variable of Builder the value is read from
+	Env string `yaml:"env,omitempty"`
+	// File is the path of a file holding the value, a trailing newline is dropped
+	File string `yaml:"file,omitempty"`
+	// Store is the name of the secret in the encrypted secrets file (builder secret set)
+	Store string `yaml:"store,omitempty"`
+}
+
+// secretNames returns the names of secrets in order
+func secretNames(secrets map[string]Secret) []string {
+	var names []string
+	for name := range secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,42 @@
+package yaml
+
+import (
+	"gopkg.in/yaml.v3"
+)
+
+// Step is one entry of the builder.yaml steps list.  Steps replace buildcmd and are run in
+// order in the build dir of every project type:
+//
+//	steps:
+//	  - name: vet
+//	    command: go vet ./...
+//	  - name: build
+//	    command: go build -o myapp
+//	    workingdir: cmd/myapp
+//	    env: {CGO_ENABLED: "0"}
+//	    timeout: 5m
+//	    continueonerror: false
+type Step struct {
+	Name    string
+	Command Commands
+	// WorkingDir is relative to the build dir
+	WorkingDir string            `yaml:"workingdir,omitempty"`
+	Env        map[string]string `yaml:"env,omitempty"`
+	// Timeout is a duration ("90s", "5m") or a number of seconds
+	Timeout         string `yaml:"timeout,omitempty"`
+	ContinueOnError bool   `yaml:"continueonerror,omitempty"`
+}
+
+// UnmarshalYAML reads a step.  A plain string is a step with only a command.
+func (s *Step) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return s.Command.UnmarshalYAML(node)
+	case yaml.MappingNode:
+		// step has Step's fields without its UnmarshalYAML
+		type step Step
+		return node.Decode((*step)(s))
+	default:
+		return typeError(node, "expected a step or a command")
+	}
+}
@@ -0,0 +1,517 @@
+package yaml
+
+import (
+	_ "embed"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Schema is the JSON Schema of the builder.yaml, printed by builder validate --schema
+//
+//go:embed builder.schema.json
+var Schema []byte
+
+// kinds of Problem
+const (
+	ProblemSyntax       = "syntax"
+	ProblemUnknownKey   = "unknown-key"
+	ProblemDuplicateKey = "duplicate-key"
+	ProblemType         = "type"
+	ProblemValue        = "value"
+	ProblemCombination  = "combination"
+)
+
+const (
+	nullTag = "!!null"
+	boolTag = "!!bool"
+)
+
+// Problem is something wrong with a builder.yaml
+type Problem struct {
+	Line int    `json:"line,omitempty"`
+	Key  string `json:"key,omitempty"`
+	// Kind is one of the Problem* constants
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+}
+
+func (p Problem) String() string {
+	msg := p.Message
+	if p.Key != "" {
+		msg = p.Key + ": " + msg
+	}
+	if p.Line > 0 {
+		msg = fmt.Sprintf("line %d: %s", p.Line, msg)
+	}
+
+	return msg
+}
+
+// Checks are the checks of values Validate can't do itself, since the project types and
+// timeout parsing live in packages that import this one.  Either may be nil.
+type Checks struct {
+	ProjectType func(projectType string) error
+	Timeout     func(timeout string) error
+}
+
+// Validate checks builder.yaml source for syntax errors, unknown keys, values of the wrong
+// type and keys that don't work together.  The problems are sorted by line.
+func Validate(source []byte, checks Checks) []Problem {
+	cfg, lines, problems, err := parse(source)
+	if err != nil {
+		return []Problem{syntaxProblem(err)}
+	}
+
+	problems = append(problems, checkConfig(cfg, lines, checks)...)
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].Line < problems[j].Line
+	})
+
+	return problems
+}
+
+// parse reads builder.yaml source into a BuilderYaml.  Keys are matched case-insensitively.
+// Keys and values that can't be used are left out and returned as problems, lines holds the
+// line of each key that was used ("buildcmd", "steps[2].timeout").  err is returned if
+// source isn't valid YAML.
+func parse(source []byte) (cfg BuilderYaml, lines map[string]int, problems []Problem, err error) {
+	lines = map[string]int{}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(source, &doc); err != nil {
+		return cfg, lines, nil, err
+	}
+	if len(doc.Content) == 0 {
+		// empty file
+		return cfg, lines, nil, nil
+	}
+
+	root := doc.Content[0]
+	if root.ShortTag() == nullTag {
+		return cfg, lines, nil, nil
+	}
+	if root.Kind != yaml.MappingNode {
+		return cfg, lines, []Problem{{Line: root.Line, Kind: ProblemType, Message: "builder.yaml must be a list of keys and values, got " + describe(root)}}, nil
+	}
+
+	problems = checkFields("", root, reflect.TypeOf(cfg), lines)
+
+	// anything checkFields missed is reported by the decoder
+	if err := root.Decode(&cfg); err != nil {
+		problems = append(problems, decodeProblems(err)...)
+	}
+
+	return cfg, lines, problems, nil
+}
+
+// checkFields checks the keys of mapping node against the fields of struct t.  Keys are
+// lowercased so they decode into t, unknown keys and bad values are removed from node.
+func checkFields(path string, node *yaml.Node, t reflect.Type, lines map[string]int) []Problem {
+	fields := yamlFields(t)
+
+	var problems []Problem
+	var kept []*yaml.Node
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode, valNode := node.Content[i], node.Content[i+1]
+		key := strings.ToLower(keyNode.Value)
+		name := path + keyNode.Value
+
+		field, ok := fields[key]
+		if !ok {
+			problems = append(problems, Problem{Line: keyNode.Line, Key: name, Kind: ProblemUnknownKey, Message: unknownKeyMessage(key, fields)})
+			continue
+		}
+		if line, ok := lines[path+key]; ok {
+			problems = append(problems, Problem{Line: keyNode.Line, Key: name, Kind: ProblemDuplicateKey, Message: fmt.Sprintf("already set on line %d, keys are not case sensitive", line)})
+			continue
+		}
+		lines[path+key] = keyNode.Line
+
+		valueProblems, ok := checkValue(path+key, valNode, field.Type, lines)
+		problems = append(problems, valueProblems...)
+		if !ok {
+			continue
+		}
+
+		keyNode.Value = key
+		kept = append(kept, keyNode, valNode)
+	}
+	node.Content = kept
+
+	return problems
+}
+
+// checkValue checks that node can be decoded into a value of type t.  The bad entries of a
+// list are removed from node, ok is false if the whole value can't be used.
+func checkValue(name string, node *yaml.Node, t reflect.Type, lines map[string]int) (problems []Problem, ok bool) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node.ShortTag() == nullTag {
+		return nil, true
+	}
+
+	wrongType := func(n *yaml.Node, expected string) ([]Problem, bool) {
+		return []Problem{{Line: n.Line, Key: name, Kind: ProblemType, Message: "expected " + expected + ", got " + describe(n)}}, false
+	}
+
+	switch {
+	case t == reflect.TypeOf(Commands{}):
+		switch node.Kind {
+		case yaml.ScalarNode:
+		case yaml.SequenceNode:
+			for _, item := range node.Content {
+				if item.Kind != yaml.ScalarNode {
+					return wrongType(item, "a command")
+				}
+			}
+		default:
+			return wrongType(node, "a command or a list of commands")
+		}
+	case t.Kind() == reflect.String:
+		if node.Kind != yaml.ScalarNode {
+			return wrongType(node, "a string")
+		}
+	case t.Kind() == reflect.Bool:
+		if node.Kind != yaml.ScalarNode || node.ShortTag() != boolTag {
+			return wrongType(node, "true or false")
+		}
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.String:
+		if node.Kind != yaml.SequenceNode {
+			return wrongType(node, "a list")
+		}
+		for i, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				return wrongType(item, "a string")
+			}
+			lines[fmt.Sprintf("%s[%d]", name, i+1)] = item.Line
+		}
+	case t.Kind() == reflect.Map && t.Elem().Kind() == reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			return wrongType(node, "a list of names and their keys")
+		}
+
+		// the names are kept as they are, the keys under them are checked like the ones above
+		var kept []*yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			nameNode, valNode := node.Content[i], node.Content[i+1]
+			itemName := name + "." + nameNode.Value
+			if valNode.Kind != yaml.MappingNode {
+				problems = append(problems, Problem{Line: valNode.Line, Key: itemName, Kind: ProblemType, Message: "expected a list of keys and values, got " + describe(valNode)})
+				continue
+			}
+
+			lines[itemName] = nameNode.Line
+			problems = append(problems, checkFields(itemName+".", valNode, t.Elem(), lines)...)
+			kept = append(kept, nameNode, valNode)
+		}
+		node.Content = kept
+	case t.Kind() == reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			return wrongType(node, "a list of names and values")
+		}
+		for i := 1; i < len(node.Content); i += 2 {
+			if node.Content[i].Kind != yaml.ScalarNode {
+				return wrongType(node.Content[i], "a value for "+node.Content[i-1].Value)
+			}
+			lines[name+"."+node.Content[i-1].Value] = node.Content[i-1].Line
+		}
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Struct:
+		if node.Kind != yaml.SequenceNode {
+			return wrongType(node, "a list")
+		}
+
+		var kept []*yaml.Node
+		for i, item := range node.Content {
+			switch item.Kind {
+			case yaml.ScalarNode, yaml.MappingNode:
+			default:
+				problems = append(problems, Problem{Line: item.Line, Key: fmt.Sprintf("%s[%d]", name, i+1), Kind: ProblemType, Message: "expected a command or a list of keys and values, got " + describe(item)})
+				continue
+			}
+
+			// the entries that are kept are named by their index in the decoded list
+			itemName := fmt.Sprintf("%s[%d]", name, len(kept)+1)
+			lines[itemName] = item.Line
+			if item.Kind == yaml.MappingNode {
+				problems = append(problems, checkFields(itemName+".", item, t.Elem(), lines)...)
+			}
+			kept = append(kept, item)
+		}
+		node.Content = kept
+	}
+
+	return problems, true
+}
+
+// checkConfig checks the values of cfg and the keys that don't work together
+func checkConfig(cfg BuilderYaml, lines map[string]int, checks Checks) []Problem {
+	problems := checkValues(cfg, lines, checks, "")
+
+	// each profile is checked overlaid on the rest of the builder.yaml, only the problems with
+	// the keys it sets are its own
+	for _, name := range profileNames(cfg.Profiles) {
+		profile, ok := cfg.Profiles[name]
+		if !ok {
+			continue
+		}
+		prefix := "profiles." + name + "."
+
+		if profile.Profile != "" || len(profile.Profiles) > 0 {
+			problems = append(problems, Problem{Line: lines["profiles."+name], Key: "profiles." + name, Kind: ProblemCombination, Message: "profiles can't set profile or profiles"})
+		}
+
+		merged := cfg
+		overlay(&merged, profile, lines, prefix)
+		problems = append(problems, checkValues(merged, lines, checks, prefix)...)
+	}
+
+	if _, ok := cfg.Profiles[cfg.Profile]; cfg.Profile != "" && !ok {
+		problems = append(problems, Problem{Line: lines["profile"], Key: "profile", Kind: ProblemValue, Message: "profile " + cfg.Profile + " is not in profiles"})
+	}
+
+	return problems
+}
+
+// checkValues checks the values of cfg and the keys that don't work together.  Keys are
+// looked up under prefix, a problem with a key that isn't set under a prefix is skipped.
+func checkValues(cfg BuilderYaml, lines map[string]int, checks Checks, prefix string) []Problem {
+	var problems []Problem
+	add := func(key, kind, msg string) {
+		line, ok := lines[prefix+key]
+		if !ok && prefix != "" {
+			return
+		}
+		problems = append(problems, Problem{Line: line, Key: prefix + key, Kind: kind, Message: msg})
+	}
+
+	if cfg.ProjectType != "" && checks.ProjectType != nil {
+		if err := checks.ProjectType(cfg.ProjectType); err != nil {
+			add("projecttype", ProblemValue, err.Error())
+		}
+	}
+
+	// buildtool and buildfile are only honored along with a projecttype
+	if cfg.ProjectType == "" {
+		if cfg.BuildTool != "" {
+			add("buildtool", ProblemCombination, "needs a projecttype")
+		}
+		if cfg.BuildFile != "" {
+			add("buildfile", ProblemCombination, "needs a projecttype, it's only searched for along with one")
+		}
+	}
+
+	// prebuildcmd and configcmd are only run by the C/C++ compiler
+	if projectType := strings.ToLower(cfg.ProjectType); projectType != "" && projectType != "c" && projectType != "c++" {
+		if len(cfg.PreBuildCmd) > 0 {
+			add("prebuildcmd", ProblemCombination, "is only run for C/C++ projects, not "+cfg.ProjectType)
+		}
+		if len(cfg.ConfigCmd) > 0 {
+			add("configcmd", ProblemCombination, "is only run for C/C++ projects, not "+cfg.ProjectType)
+		}
+	}
+
+	// gradletask is only run by the gradle compiler
+	if projectType := strings.ToLower(cfg.ProjectType); cfg.GradleTask != "" && projectType != "" && projectType != "gradle" && projectType != "kotlin" {
+		add("gradletask", ProblemCombination, "is only run for gradle projects, not "+cfg.ProjectType)
+	}
+
+	// gopackages is only built by the go compiler
+	if projectType := strings.ToLower(cfg.ProjectType); cfg.GoPackages != "" && projectType != "" && projectType != "go" {
+		add("gopackages", ProblemCombination, "is only built for go projects, not "+cfg.ProjectType)
+	}
+
+	// targets are only cross-compiled by the go and rust compilers
+	if projectType := strings.ToLower(cfg.ProjectType); len(cfg.Targets) > 0 && projectType != "" && projectType != "go" && projectType != "rust" {
+		add("targets", ProblemCombination, "are only built for go and rust projects, not "+cfg.ProjectType)
+	}
+	for i, target := range cfg.Targets {
+		if strings.TrimSpace(target) == "" {
+			add(fmt.Sprintf("targets[%d]", i+1), ProblemValue, "is empty")
+		}
+	}
+
+	// stampvars are only set by the go compiler, and only with stamp on
+	if len(cfg.StampVars) > 0 {
+		if projectType := strings.ToLower(cfg.ProjectType); projectType != "" && projectType != "go" {
+			add("stampvars", ProblemCombination, "are only set for go projects, not "+cfg.ProjectType)
+		}
+		if !cfg.Stamp {
+			add("stampvars", ProblemCombination, "are only set with stamp: true")
+		}
+	}
+	for name := range cfg.StampVars {
+		switch strings.ToLower(name) {
+		case "githash", "branch", "buildid", "timestamp":
+		default:
+			add("stampvars."+name, ProblemValue, "is not githash, branch, buildid or timestamp")
+		}
+	}
+
+	if len(cfg.Steps) > 0 && len(cfg.BuildCmd) > 0 {
+		add("steps", ProblemCombination, "can't be used along with buildcmd, use one or the other")
+	}
+
+	for i, step := range cfg.Steps {
+		name := fmt.Sprintf("steps[%d]", i+1)
+		if len(step.Command) == 0 {
+			add(name, ProblemValue, "has no command")
+		}
+		if step.Timeout != "" && checks.Timeout != nil {
+			if err := checks.Timeout(step.Timeout); err != nil {
+				add(name+".timeout", ProblemValue, err.Error())
+			}
+		}
+	}
+
+	for _, name := range secretNames(cfg.Secrets) {
+		secret := cfg.Secrets[name]
+		set := 0
+		for _, source := range []string{secret.Env, secret.File, secret.Store} {
+			if source != "" {
+				set++
+			}
+		}
+		if set != 1 {
+			add("secrets."+name, ProblemValue, "needs one of env, file or store")
+		}
+	}
+
+	if cfg.Timeout != "" && checks.Timeout != nil {
+		if err := checks.Timeout(cfg.Timeout); err != nil {
+			add("timeout", ProblemValue, err.Error())
+		}
+	}
+
+	return problems
+}
+
+// yamlFields maps the keys of struct t to its fields
+func yamlFields(t reflect.Type) map[string]reflect.StructField {
+	fields := map[string]reflect.StructField{}
+	for i := 0; i < t.NumField(); i++ {
+		fields[fieldKey(t.Field(i))] = t.Field(i)
+	}
+
+	return fields
+}
+
+// fieldKey returns the key of field, its yaml tag name or its lowercased name
+func fieldKey(field reflect.StructField) string {
+	if key := strings.Split(field.Tag.Get("yaml"), ",")[0]; key != "" {
+		return key
+	}
+
+	return strings.ToLower(field.Name)
+}
+
+// unknownKeyMessage suggests the known key closest to key, if any is close
+func unknownKeyMessage(key string, fields map[string]reflect.StructField) string {
+	// build_cmd, build-cmd
+	plain := strings.NewReplacer("_", "", "-", "").Replace(key)
+
+	best, bestDistance := "", 3
+	for known := range fields {
+		if d := editDistance(plain, known); d < bestDistance || (d == bestDistance && known < best) {
+			best, bestDistance = known, d
+		}
+	}
+
+	if best == "" {
+		return "unknown key"
+	}
+
+	return fmt.Sprintf("unknown key, did you mean %s?", best)
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+
+	return a
+}
+
+// describe names the kind of value node holds, for error messages
+func describe(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.SequenceNode:
+		return "a list"
+	case yaml.MappingNode:
+		return "a list of keys and values"
+	default:
+		return strconv.Quote(node.Value)
+	}
+}
+
+// typeError is returned by UnmarshalYAML methods so the decoder carries on and reports every
+// bad value
+func typeError(node *yaml.Node, msg string) error {
+	return &yaml.TypeError{Errors: []string{fmt.Sprintf("line %d: %s, got %s", node.Line, msg, describe(node))}}
+}
+
+var lineMessage = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// decodeProblems turns the errors of the yaml decoder into problems
+func decodeProblems(err error) []Problem {
+	typeErr, ok := err.(*yaml.TypeError)
+	if !ok {
+		return []Problem{{Kind: ProblemType, Message: err.Error()}}
+	}
+
+	var problems []Problem
+	for _, msg := range typeErr.Errors {
+		problem := Problem{Kind: ProblemType, Message: msg}
+		if match := lineMessage.FindStringSubmatch(msg); match != nil {
+			problem.Line, _ = strconv.Atoi(match[1])
+			problem.Message = match[2]
+		}
+		problems = append(problems, problem)
+	}
+
+	return problems
+}
+
+// syntaxProblem turns a yaml syntax error into a problem
+func syntaxProblem(err error) Problem {
+	problem := Problem{Kind: ProblemSyntax, Message: strings.TrimPrefix(err.Error(), "yaml: ")}
+	if match := lineMessage.FindStringSubmatch(err.Error()); match != nil {
+		problem.Line, _ = strconv.Atoi(match[1])
+		problem.Message = match[2]
+	}
+
+	return problem
+}
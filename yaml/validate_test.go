@@ -0,0 +1,123 @@
+package yaml
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   []Problem
+	}{
+		{
+			name:   "valid",
+			source: "projectname: hello\nbuildcmd: go build\n",
+		},
+		{
+			name:   "empty",
+			source: "",
+		},
+		{
+			name:   "unknown key with a suggestion",
+			source: "projectname: hello\nbuild_cmd: go build\n",
+			want: []Problem{
+				{Line: 2, Key: "build_cmd", Kind: ProblemUnknownKey, Message: "unknown key, did you mean buildcmd?"},
+			},
+		},
+		{
+			name:   "unknown key without a suggestion",
+			source: "projectname: hello\n\nsomethingelse: true\n",
+			want: []Problem{
+				{Line: 3, Key: "somethingelse", Kind: ProblemUnknownKey, Message: "unknown key"},
+			},
+		},
+		{
+			name:   "unknown key in a step",
+			source: "steps:\n  - go vet ./...\n  - command: go build\n    timout: 5m\n",
+			want: []Problem{
+				{Line: 4, Key: "steps[2].timout", Kind: ProblemUnknownKey, Message: "unknown key, did you mean timeout?"},
+			},
+		},
+		{
+			name:   "unknown key in a profile",
+			source: "profiles:\n  release:\n    buildcmds: make\n",
+			want: []Problem{
+				{Line: 3, Key: "profiles.release.buildcmds", Kind: ProblemUnknownKey, Message: "unknown key, did you mean buildcmd?"},
+			},
+		},
+		{
+			name:   "duplicate key in another case",
+			source: "projectname: hello\nProjectName: world\n",
+			want: []Problem{
+				{Line: 2, Key: "ProjectName", Kind: ProblemDuplicateKey, Message: "already set on line 1, keys are not case sensitive"},
+			},
+		},
+		{
+			name:   "value of the wrong type",
+			source: "projectname: hello\nstamp: maybe\n",
+			want: []Problem{
+				{Line: 2, Key: "stamp", Kind: ProblemType, Message: `expected true or false, got "maybe"`},
+			},
+		},
+		{
+			name:   "problems sorted by line",
+			source: "timeout: soon\nprojectname: hello\nbuildtool: make\n",
+			want: []Problem{
+				{Line: 1, Key: "timeout", Kind: ProblemValue, Message: "bad timeout"},
+				{Line: 3, Key: "buildtool", Kind: ProblemCombination, Message: "needs a projecttype"},
+			},
+		},
+		{
+			name:   "not a mapping",
+			source: "- projectname\n",
+			want: []Problem{
+				{Line: 1, Kind: ProblemType, Message: "builder.yaml must be a list of keys and values, got a list"},
+			},
+		},
+	}
+
+	checks := Checks{
+		Timeout: func(timeout string) error {
+			if timeout == "soon" {
+				return errors.New("bad timeout")
+			}
+			return nil
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Validate([]byte(tt.source), checks)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateSyntaxError(t *testing.T) {
+	got := Validate([]byte("projectname: hello\nbuildcmd: \"go build\n"), Checks{})
+	if len(got) != 1 || got[0].Kind != ProblemSyntax || got[0].Line != 2 {
+		t.Errorf("Validate() = %+v, want one syntax problem on line 2", got)
+	}
+}
+
+func TestProblemString(t *testing.T) {
+	tests := []struct {
+		problem Problem
+		want    string
+	}{
+		{Problem{Line: 4, Key: "steps[2].timout", Message: "unknown key"}, "line 4: steps[2].timout: unknown key"},
+		{Problem{Key: "timeout", Message: "bad timeout"}, "timeout: bad timeout"},
+		{Problem{Message: "did not find expected key"}, "did not find expected key"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.problem.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
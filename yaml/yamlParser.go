@@ -2,49 +2,121 @@ package yaml
 
 import (
 	"Builder/spinner"
+	"fmt"
 	"io/ioutil"
 	"os"
-
-	"gopkg.in/yaml.v3"
+	"strings"
 )
 
-func YamlParser(yamlPath string) {
-	// Initial declaration
-	m := map[string]interface{}{
-		"key": "value",
-	}
-	// Dynamically add a sub-map
-	m["sub"] = map[string]interface{}{
-		"deepKey": "deepValue",
-	}
-	// returns map
-	var f interface{}
+// Defaults are the values of the builder.yaml keys that are left out.  The buildtool and
+// buildfile defaults depend on the project type and are set by its compiler.
+var Defaults = BuilderYaml{BuildsDir: "builder"}
+
+// configFile is a parsed builder.yaml or config.yaml, lines holds the keys set in it
+type configFile struct {
+	cfg   BuilderYaml
+	lines map[string]int
+}
 
+// YamlParser reads the builder.yaml at yamlPath into cfg, layered over the global config
+// files (see LoadGlobalConfig).  Unknown keys and keys that don't work together are logged as
+// warnings, values of the wrong type fail the build.
+func YamlParser(yamlPath string, cfg *BuilderYaml) error {
 	//takes yaml path and read file
 	source, err := ioutil.ReadFile(yamlPath)
 	if err != nil {
 		removeTempDir()
-		spinner.LogMessage("failed to read builder yaml: "+err.Error(), "fatal")
+		return fmt.Errorf("failed to read builder yaml: %w", err)
+	}
+
+	//unpacks yaml file into the typed config
+	file, err := readConfig("builder.yaml", source)
+	if err != nil {
+		removeTempDir()
+		return err
+	}
+
+	if err := loadConfig(file, cfg); err != nil {
+		removeTempDir()
+		return err
+	}
+
+	return removeTempDir()
+}
+
+// LoadGlobalConfig fills in the values of cfg that aren't set yet from the global config
+// files and the defaults, for builds that don't read a builder.yaml
+func LoadGlobalConfig(cfg *BuilderYaml) error {
+	return loadConfig(nil, cfg)
+}
+
+// loadConfig layers the config files into cfg.  From lowest to highest precedence: the
+// defaults, the system config, the user config, the repo's builder.yaml (repo, nil if there
+// isn't one) and its selected profile.  Values already present in cfg (set by CLI flags) win
+// over all of them.
+func loadConfig(repo *configFile, cfg *BuilderYaml) error {
+	layers, err := globalConfigs()
+	if err != nil {
+		return err
+	}
+
+	merged := Defaults
+	for i := len(layers) - 1; i >= 0; i-- {
+		if layers[i].file != nil {
+			overlay(&merged, layers[i].file.cfg, layers[i].file.lines, "")
+		}
+	}
+
+	lines := map[string]int{}
+	if repo != nil {
+		overlay(&merged, repo.cfg, repo.lines, "")
+		lines = repo.lines
+	}
+
+	//the --profile flag wins over the builder.yaml profile
+	if cfg.Profile != "" {
+		merged.Profile = cfg.Profile
+	}
+	if err := applyProfile(&merged, lines); err != nil {
+		return err
 	}
 
-	//unpacks yaml file in a map int{}
-	err = yaml.Unmarshal([]byte(source), &f)
+	ConfigEnvs(merged, cfg)
+
+	return nil
+}
+
+// readConfig parses the source of the config file name.  Unknown keys and keys that don't work
+// together are logged as warnings, values of the wrong type are returned as an error.
+func readConfig(name string, source []byte) (*configFile, error) {
+	cfg, lines, problems, err := parse(source)
 	if err != nil {
-		spinner.LogMessage(err.Error(), "error")
+		return nil, fmt.Errorf("failed to parse %s: %w", name, err)
 	}
+	problems = append(problems, checkConfig(cfg, lines, Checks{})...)
 
-	//pass map int{} to callback that sets env vars
-	ConfigEnvs(f)
+	var invalid []string
+	for _, problem := range problems {
+		switch problem.Kind {
+		case ProblemUnknownKey, ProblemDuplicateKey, ProblemCombination:
+			spinner.LogMessage(name+" "+problem.String(), "warn")
+		default:
+			invalid = append(invalid, problem.String())
+		}
+	}
+	if len(invalid) > 0 {
+		return nil, fmt.Errorf("invalid %s (run builder validate for details): %s", name, strings.Join(invalid, "; "))
+	}
 
-	// if env var BUILDER_COMMAND != true
-	removeTempDir()
-	//else
+	return &configFile{cfg: cfg, lines: lines}, nil
 }
 
-func removeTempDir() {
+func removeTempDir() error {
 	//delete tempRepo dir
 	err := os.RemoveAll("./tempRepo")
 	if err != nil {
-		spinner.LogMessage("Failed to delete tempRepo directory: "+err.Error(), "fatal")
+		return fmt.Errorf("failed to delete tempRepo directory: %w", err)
 	}
+
+	return nil
 }